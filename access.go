@@ -0,0 +1,133 @@
+package gnap
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// AccessEntry is an element of an `access` array. It holds either a
+// ResourceAccess object, or a ResourceAccessReference, which is a
+// string that refers to access that is known to the authorization
+// server and the resource server.
+type AccessEntry struct {
+	object    *ResourceAccess
+	reference ResourceAccessReference
+}
+
+// NewAccessEntry creates an AccessEntry holding a ResourceAccess object
+func NewAccessEntry(v ResourceAccess) AccessEntry {
+	return AccessEntry{object: &v}
+}
+
+// NewAccessReference creates an AccessEntry holding a reference
+func NewAccessReference(v ResourceAccessReference) AccessEntry {
+	return AccessEntry{reference: v}
+}
+
+// IsReference returns true if the entry holds a reference. The zero
+// value holds neither an object nor a reference
+func (e AccessEntry) IsReference() bool {
+	return e.object == nil && e.reference != ""
+}
+
+// Object returns the ResourceAccess object held by the entry, or nil
+// if the entry holds a reference
+func (e AccessEntry) Object() *ResourceAccess {
+	return e.object
+}
+
+// Reference returns the reference held by the entry, or the empty
+// string if the entry holds a ResourceAccess object
+func (e AccessEntry) Reference() ResourceAccessReference {
+	return e.reference
+}
+
+func (e AccessEntry) MarshalJSON() ([]byte, error) {
+	if e.object != nil {
+		return json.Marshal(e.object)
+	}
+	if e.reference == "" {
+		return nil, errors.New(`access entry must hold an object or a non-empty reference`)
+	}
+	return json.Marshal(string(e.reference))
+}
+
+func (e *AccessEntry) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var ref string
+		if err := json.Unmarshal(data, &ref); err != nil {
+			return errors.Wrap(err, `failed to decode access reference`)
+		}
+		if ref == "" {
+			return errors.New(`access reference must not be empty`)
+		}
+		*e = NewAccessReference(ResourceAccessReference(ref))
+		return nil
+	}
+
+	var object ResourceAccess
+	if err := json.Unmarshal(data, &object); err != nil {
+		return errors.Wrap(err, `failed to decode access object`)
+	}
+	*e = NewAccessEntry(object)
+	return nil
+}
+
+// AccessObjects returns the ResourceAccess objects in `entries`,
+// skipping references
+func AccessObjects(entries []AccessEntry) []ResourceAccess {
+	var list []ResourceAccess
+	for _, e := range entries {
+		if e.object != nil {
+			list = append(list, *e.object)
+		}
+	}
+	return list
+}
+
+// AccessReferences returns the references in `entries`, skipping
+// ResourceAccess objects
+func AccessReferences(entries []AccessEntry) []ResourceAccessReference {
+	var list []ResourceAccessReference
+	for _, e := range entries {
+		if e.IsReference() {
+			list = append(list, e.reference)
+		}
+	}
+	return list
+}
+
+// AddAccessObjects adds ResourceAccess objects to the `access` field
+func (c *AccessTokenRequest) AddAccessObjects(v ...ResourceAccess) *AccessTokenRequest {
+	for _, ra := range v {
+		c.AddAccess(NewAccessEntry(ra))
+	}
+	return c
+}
+
+// AddAccessReferences adds references to the `access` field
+func (c *AccessTokenRequest) AddAccessReferences(v ...ResourceAccessReference) *AccessTokenRequest {
+	for _, ref := range v {
+		c.AddAccess(NewAccessReference(ref))
+	}
+	return c
+}
+
+// AddAccessObjects adds ResourceAccess objects to the `access` field
+func (c *AccessToken) AddAccessObjects(v ...ResourceAccess) *AccessToken {
+	for _, ra := range v {
+		c.AddAccess(NewAccessEntry(ra))
+	}
+	return c
+}
+
+// AddAccessReferences adds references to the `access` field
+func (c *AccessToken) AddAccessReferences(v ...ResourceAccessReference) *AccessToken {
+	for _, ref := range v {
+		c.AddAccess(NewAccessReference(ref))
+	}
+	return c
+}
@@ -0,0 +1,171 @@
+package gnap
+
+// legacyFlags are the boolean fields that older drafts used instead
+// of the `flags` array. `bound` is inverted: an unbound token is a
+// bearer token.
+//
+// `split` is not listed, as it is only a flag of the access token
+// request. It is kept as an extra field of the token (see Split)
+var legacyFlags = []struct {
+	name     string
+	flag     AccessTokenAttribute
+	inverted bool
+}{
+	{name: "bound", flag: Bearer, inverted: true},
+	{name: "durable", flag: Durable},
+}
+
+// HasFlag returns true if the token has the given flag
+func (c *AccessToken) HasFlag(flag AccessTokenAttribute) bool {
+	for _, v := range c.flags {
+		if v == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBearer returns true if the token is a bearer token, which is not
+// bound to any key
+func (c *AccessToken) IsBearer() bool {
+	return c.HasFlag(Bearer)
+}
+
+// IsDurable returns true if the token is durable, meaning that it is
+// not revoked when the grant is modified or the client is rotated
+func (c *AccessToken) IsDurable() bool {
+	return c.HasFlag(Durable)
+}
+
+// SetBound marks the token as bound to a key, or as a bearer token.
+// A nil value removes the legacy field, and leaves the token bound.
+//
+// Deprecated: use AddFlags with Bearer for bearer tokens
+func (c *AccessToken) SetBound(v *bool) {
+	c.legacyBound = v != nil && *v
+	c.setFlag(Bearer, v != nil && !*v)
+}
+
+// Bound returns false if the token is a bearer token, and true if the
+// token was decoded from, or set with, a `bound: true` field used by
+// older drafts. Otherwise it returns nil, in which case the token is
+// bound.
+//
+// Deprecated: use IsBearer
+func (c *AccessToken) Bound() *bool {
+	var v bool
+	switch {
+	case c.IsBearer():
+		v = false
+	case c.legacyBound:
+		v = true
+	default:
+		return nil
+	}
+	return &v
+}
+
+// SetDurable marks the token as durable. A nil value is the same as
+// false.
+//
+// Deprecated: use AddFlags with Durable
+func (c *AccessToken) SetDurable(v *bool) {
+	c.setFlag(Durable, v != nil && *v)
+}
+
+// Durable returns true if the token is durable, or nil if the
+// `durable` flag is not present.
+//
+// Deprecated: use IsDurable
+func (c *AccessToken) Durable() *bool {
+	if !c.IsDurable() {
+		return nil
+	}
+	v := true
+	return &v
+}
+
+// SetSplit sets the `split` field used by older drafts. It is not a
+// flag of the access token, and is encoded as a separate boolean field.
+// A nil value removes the field.
+//
+// Deprecated: split tokens are requested using the flags of
+// AccessTokenRequest
+func (c *AccessToken) SetSplit(v *bool) {
+	if v == nil {
+		delete(c.extraFields, "split")
+		return
+	}
+	if c.extraFields == nil {
+		c.extraFields = make(map[string]interface{})
+	}
+	c.extraFields["split"] = *v
+}
+
+// Split returns the `split` field used by older drafts, or nil if it
+// is not present.
+//
+// Deprecated: split tokens are requested using the flags of
+// AccessTokenRequest
+func (c *AccessToken) Split() *bool {
+	raw, ok := c.extraFields["split"]
+	if !ok {
+		return nil
+	}
+	v, ok := raw.(bool)
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+// setFlag adds or removes the given flag
+func (c *AccessToken) setFlag(flag AccessTokenAttribute, on bool) {
+	if c.HasFlag(flag) == on {
+		return
+	}
+
+	if on {
+		c.flags = append(c.flags, flag)
+		return
+	}
+
+	var flags []AccessTokenAttribute
+	for _, v := range c.flags {
+		if v != flag {
+			flags = append(flags, v)
+		}
+	}
+	c.flags = flags
+}
+
+// decodeLegacyFlags converts the boolean fields used by older drafts
+// into flags. The boolean fields are removed, so that only the
+// `flags` array is produced when the token is encoded again
+func (c *AccessToken) decodeLegacyFlags() {
+	c.legacyBound = false
+	for _, legacy := range legacyFlags {
+		raw, ok := c.extraFields[legacy.name]
+		if !ok {
+			continue
+		}
+
+		v, ok := raw.(bool)
+		if !ok {
+			continue
+		}
+		delete(c.extraFields, legacy.name)
+
+		if legacy.flag == Bearer && v {
+			c.legacyBound = true
+		}
+
+		if v != legacy.inverted && !c.HasFlag(legacy.flag) {
+			c.flags = append(c.flags, legacy.flag)
+		}
+	}
+
+	if len(c.extraFields) == 0 {
+		c.extraFields = nil
+	}
+}
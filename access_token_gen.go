@@ -13,21 +13,20 @@ import (
 )
 
 type AccessToken struct {
-	access      []ResourceAccess
-	bound       *bool
-	durable     *bool
+	access      []AccessEntry
 	expires_in  *int64
+	flags       []AccessTokenAttribute
 	key         jwk.Key
 	label       *string
 	manage      *string
-	split       *bool
 	value       *string
 	extraFields map[string]interface{}
+	legacyBound bool
 }
 
-func NewAccessToken(access ResourceAccess, value string) *AccessToken {
+func NewAccessToken(access AccessEntry, value string) *AccessToken {
 	return &AccessToken{
-		access: []ResourceAccess{access},
+		access: []AccessEntry{access},
 		value:  &value,
 	}
 }
@@ -39,6 +38,9 @@ func (c *AccessToken) Validate() error {
 	if c.value == nil {
 		return errors.Errorf(`field "value" is required`)
 	}
+	if c.HasFlag(Bearer) && c.key != nil {
+		return errors.Errorf(`"key" must not be specified for bearer tokens`)
+	}
 	return nil
 }
 
@@ -49,21 +51,16 @@ func (c *AccessToken) Get(key string) (interface{}, bool) {
 			return nil, false
 		}
 		return c.access, true
-	case "bound":
-		if c.bound == nil {
-			return nil, false
-		}
-		return c.bound, true
-	case "durable":
-		if c.durable == nil {
-			return nil, false
-		}
-		return c.durable, true
 	case "expires_in":
 		if c.expires_in == nil {
 			return nil, false
 		}
 		return c.expires_in, true
+	case "flags":
+		if len(c.flags) == 0 {
+			return nil, false
+		}
+		return c.flags, true
 	case "key":
 		if c.key == nil {
 			return nil, false
@@ -79,11 +76,6 @@ func (c *AccessToken) Get(key string) (interface{}, bool) {
 			return nil, false
 		}
 		return c.manage, true
-	case "split":
-		if c.split == nil {
-			return nil, false
-		}
-		return c.split, true
 	case "value":
 		if c.value == nil {
 			return nil, false
@@ -101,29 +93,23 @@ func (c *AccessToken) Get(key string) (interface{}, bool) {
 func (c *AccessToken) Set(key string, value interface{}) error {
 	switch key {
 	case "access":
-		if v, ok := value.([]ResourceAccess); ok {
+		if v, ok := value.([]AccessEntry); ok {
 			c.access = v
 		} else {
 			return errors.Errorf(`invalid type for "access" (%T)`, value)
 		}
-	case "bound":
-		if v, ok := value.(*bool); ok {
-			c.bound = v
-		} else {
-			return errors.Errorf(`invalid type for "bound" (%T)`, value)
-		}
-	case "durable":
-		if v, ok := value.(*bool); ok {
-			c.durable = v
-		} else {
-			return errors.Errorf(`invalid type for "durable" (%T)`, value)
-		}
 	case "expires_in":
 		if v, ok := value.(*int64); ok {
 			c.expires_in = v
 		} else {
 			return errors.Errorf(`invalid type for "expires_in" (%T)`, value)
 		}
+	case "flags":
+		if v, ok := value.([]AccessTokenAttribute); ok {
+			c.flags = v
+		} else {
+			return errors.Errorf(`invalid type for "flags" (%T)`, value)
+		}
 	case "key":
 		if v, ok := value.(jwk.Key); ok {
 			c.key = v
@@ -146,12 +132,6 @@ func (c *AccessToken) Set(key string, value interface{}) error {
 		} else {
 			return errors.Errorf(`invalid type for "manage" (%T)`, value)
 		}
-	case "split":
-		if v, ok := value.(*bool); ok {
-			c.split = v
-		} else {
-			return errors.Errorf(`invalid type for "split" (%T)`, value)
-		}
 	case "value":
 		if v, ok := value.(string); ok {
 			c.value = &v
@@ -169,31 +149,15 @@ func (c *AccessToken) Set(key string, value interface{}) error {
 	return nil
 }
 
-func (c *AccessToken) AddAccess(v ...ResourceAccess) *AccessToken {
+func (c *AccessToken) AddAccess(v ...AccessEntry) *AccessToken {
 	c.access = append(c.access, v...)
 	return c
 }
 
-func (c *AccessToken) Access() []ResourceAccess {
+func (c *AccessToken) Access() []AccessEntry {
 	return c.access
 }
 
-func (c *AccessToken) SetBound(v *bool) {
-	c.bound = v
-}
-
-func (c *AccessToken) Bound() *bool {
-	return c.bound
-}
-
-func (c *AccessToken) SetDurable(v *bool) {
-	c.durable = v
-}
-
-func (c *AccessToken) Durable() *bool {
-	return c.durable
-}
-
 func (c *AccessToken) SetExpiresIn(v *int64) {
 	c.expires_in = v
 }
@@ -202,6 +166,15 @@ func (c *AccessToken) ExpiresIn() *int64 {
 	return c.expires_in
 }
 
+func (c *AccessToken) AddFlags(v ...AccessTokenAttribute) *AccessToken {
+	c.flags = append(c.flags, v...)
+	return c
+}
+
+func (c *AccessToken) Flags() []AccessTokenAttribute {
+	return c.flags
+}
+
 func (c *AccessToken) SetKey(v jwk.Key) {
 	c.key = v
 }
@@ -232,14 +205,6 @@ func (c *AccessToken) Manage() string {
 	return *(c.manage)
 }
 
-func (c *AccessToken) SetSplit(v *bool) {
-	c.split = v
-}
-
-func (c *AccessToken) Split() *bool {
-	return c.split
-}
-
 func (c *AccessToken) SetValue(v string) {
 	c.value = &v
 }
@@ -276,13 +241,11 @@ func (c AccessToken) MarshalJSON() ([]byte, error) {
 
 func (c *AccessToken) UnmarshalJSON(data []byte) error {
 	c.access = nil
-	c.bound = nil
-	c.durable = nil
 	c.expires_in = nil
+	c.flags = nil
 	c.key = nil
 	c.label = nil
 	c.manage = nil
-	c.split = nil
 	c.value = nil
 	dec := json.NewDecoder(bytes.NewReader(data))
 	tok, err := dec.Token()
@@ -315,22 +278,24 @@ LOOP:
 				if err := dec.Decode(&(c.access)); err != nil {
 					return errors.Wrap(err, `error reading access`)
 				}
-			case "bound":
-				if err := dec.Decode(&(c.bound)); err != nil {
-					return errors.Wrap(err, `error reading bound`)
-				}
-			case "durable":
-				if err := dec.Decode(&(c.durable)); err != nil {
-					return errors.Wrap(err, `error reading durable`)
-				}
 			case "expires_in":
 				if err := dec.Decode(&(c.expires_in)); err != nil {
 					return errors.Wrap(err, `error reading expires_in`)
 				}
+			case "flags":
+				if err := dec.Decode(&(c.flags)); err != nil {
+					return errors.Wrap(err, `error reading flags`)
+				}
 			case "key":
-				if err := dec.Decode(&(c.key)); err != nil {
+				var tmp json.RawMessage
+				if err := dec.Decode(&tmp); err != nil {
 					return errors.Wrap(err, `error reading key`)
 				}
+				key, err := jwk.ParseKey(tmp)
+				if err != nil {
+					return errors.Wrap(err, `error parsing key`)
+				}
+				c.key = key
 			case "label":
 				var tmp string
 				if err := dec.Decode(&tmp); err != nil {
@@ -343,10 +308,6 @@ LOOP:
 					return errors.Wrap(err, `error reading manage`)
 				}
 				c.manage = &tmp
-			case "split":
-				if err := dec.Decode(&(c.split)); err != nil {
-					return errors.Wrap(err, `error reading split`)
-				}
 			case "value":
 				var tmp string
 				if err := dec.Decode(&tmp); err != nil {
@@ -365,6 +326,7 @@ LOOP:
 			}
 		}
 	}
+	c.decodeLegacyFlags()
 	return nil
 }
 
@@ -373,24 +335,21 @@ func (c *AccessToken) makePairs() []*mapiter.Pair {
 	if tmp := c.access; len(tmp) > 0 {
 		pairs = append(pairs, &mapiter.Pair{Key: "access", Value: tmp})
 	}
-	if tmp := c.bound; tmp != nil {
-		pairs = append(pairs, &mapiter.Pair{Key: "bound", Value: *tmp})
-	}
-	if tmp := c.durable; tmp != nil {
-		pairs = append(pairs, &mapiter.Pair{Key: "durable", Value: *tmp})
-	}
 	if tmp := c.expires_in; tmp != nil {
 		pairs = append(pairs, &mapiter.Pair{Key: "expires_in", Value: *tmp})
 	}
+	if tmp := c.flags; len(tmp) > 0 {
+		pairs = append(pairs, &mapiter.Pair{Key: "flags", Value: tmp})
+	}
+	if tmp := c.key; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "key", Value: publicJWK(tmp)})
+	}
 	if tmp := c.label; tmp != nil {
 		pairs = append(pairs, &mapiter.Pair{Key: "label", Value: *tmp})
 	}
 	if tmp := c.manage; tmp != nil {
 		pairs = append(pairs, &mapiter.Pair{Key: "manage", Value: *tmp})
 	}
-	if tmp := c.split; tmp != nil {
-		pairs = append(pairs, &mapiter.Pair{Key: "split", Value: *tmp})
-	}
 	if tmp := c.value; tmp != nil {
 		pairs = append(pairs, &mapiter.Pair{Key: "value", Value: *tmp})
 	}
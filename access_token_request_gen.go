@@ -12,7 +12,7 @@ import (
 )
 
 type AccessTokenRequest struct {
-	access      []*ResourceAccess
+	access      []AccessEntry
 	flags       []AccessTokenAttribute
 	label       *string
 	extraFields map[string]interface{}
@@ -55,7 +55,7 @@ func (c *AccessTokenRequest) Get(key string) (interface{}, bool) {
 func (c *AccessTokenRequest) Set(key string, value interface{}) error {
 	switch key {
 	case "access":
-		if v, ok := value.([]*ResourceAccess); ok {
+		if v, ok := value.([]AccessEntry); ok {
 			c.access = v
 		} else {
 			return errors.Errorf(`invalid type for "access" (%T)`, value)
@@ -83,12 +83,12 @@ func (c *AccessTokenRequest) Set(key string, value interface{}) error {
 	return nil
 }
 
-func (c *AccessTokenRequest) AddAccess(v ...*ResourceAccess) *AccessTokenRequest {
+func (c *AccessTokenRequest) AddAccess(v ...AccessEntry) *AccessTokenRequest {
 	c.access = append(c.access, v...)
 	return c
 }
 
-func (c *AccessTokenRequest) Access() []*ResourceAccess {
+func (c *AccessTokenRequest) Access() []AccessEntry {
 	return c.access
 }
 
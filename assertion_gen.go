@@ -0,0 +1,219 @@
+package gnap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/lestrrat-go/iter/mapiter"
+	"github.com/pkg/errors"
+)
+
+// Assertion is an identity assertion about the subject, such as an OpenID Connect ID Token
+type Assertion struct {
+	format      *AssertionFormat
+	value       *string
+	extraFields map[string]interface{}
+}
+
+func NewAssertion(format AssertionFormat, value string) *Assertion {
+	return &Assertion{
+		format: &format,
+		value:  &value,
+	}
+}
+
+func (c *Assertion) Validate() error {
+	if c.format == nil {
+		return errors.Errorf(`field "format" is required`)
+	}
+	if c.value == nil {
+		return errors.Errorf(`field "value" is required`)
+	}
+	return nil
+}
+
+func (c *Assertion) Get(key string) (interface{}, bool) {
+	switch key {
+	case "format":
+		if c.format == nil {
+			return nil, false
+		}
+		return c.format, true
+	case "value":
+		if c.value == nil {
+			return nil, false
+		}
+		return c.value, true
+	default:
+		if c.extraFields == nil {
+			return nil, false
+		}
+		v, ok := c.extraFields[key]
+		return v, ok
+	}
+}
+
+func (c *Assertion) Set(key string, value interface{}) error {
+	switch key {
+	case "format":
+		if v, ok := value.(*AssertionFormat); ok {
+			c.format = v
+		} else {
+			return errors.Errorf(`invalid type for "format" (%T)`, value)
+		}
+	case "value":
+		if v, ok := value.(string); ok {
+			c.value = &v
+		} else if value == nil {
+			c.value = nil
+		} else {
+			return errors.Errorf(`invalid type for "value" (%T)`, value)
+		}
+	default:
+		if c.extraFields == nil {
+			c.extraFields = make(map[string]interface{})
+		}
+		c.extraFields[key] = value
+	}
+	return nil
+}
+
+func (c *Assertion) SetFormat(v AssertionFormat) {
+	c.format = &v
+}
+
+func (c *Assertion) Format() AssertionFormat {
+	if c.format == nil {
+		return ""
+	}
+	return *(c.format)
+}
+
+func (c *Assertion) SetValue(v string) {
+	c.value = &v
+}
+
+func (c *Assertion) Value() string {
+	if c.value == nil {
+		return ""
+	}
+	return *(c.value)
+}
+
+func (c Assertion) MarshalJSON() ([]byte, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	buf.WriteByte('{')
+	var i int
+	for iter := c.Iterate(ctx); iter.Next(ctx); {
+		pair := iter.Pair()
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		i++
+		buf.WriteString(strconv.Quote(pair.Key.(string)))
+		buf.WriteByte(':')
+		if err := enc.Encode(pair.Value); err != nil {
+			return nil, errors.Wrapf(err, `failed to encode %s`, pair.Key.(string))
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (c *Assertion) UnmarshalJSON(data []byte) error {
+	c.format = nil
+	c.value = nil
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return errors.Wrap(err, `error reading token`)
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok != '{' {
+			return errors.Errorf(`expected '{', but got '%c'`, tok)
+		}
+	default:
+		return errors.Errorf(`expected '{', but got '%c'`, tok)
+	}
+LOOP:
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return errors.Wrap(err, `error reading token`)
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			if tok == '}' { // End of object
+				break LOOP
+			}
+			return errors.Errorf(`unexpected delimiter '%c'`, tok)
+		case string:
+			switch tok {
+			case "format":
+				if err := dec.Decode(&(c.format)); err != nil {
+					return errors.Wrap(err, `error reading format`)
+				}
+			case "value":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading value`)
+				}
+				c.value = &tmp
+			default:
+				var tmp interface{}
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrapf(err, `error reading %s`, tok)
+				}
+				if c.extraFields == nil {
+					c.extraFields = map[string]interface{}{}
+				}
+				c.extraFields[tok] = tmp
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Assertion) makePairs() []*mapiter.Pair {
+	var pairs []*mapiter.Pair
+	if tmp := c.format; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "format", Value: *tmp})
+	}
+	if tmp := c.value; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "value", Value: *tmp})
+	}
+	var extraKeys []string
+	for k := range c.extraFields {
+		extraKeys = append(extraKeys, k)
+	}
+	for _, k := range extraKeys {
+		pairs = append(pairs, &mapiter.Pair{Key: k, Value: c.extraFields[k]})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.(string) < pairs[j].Key.(string)
+	})
+	return pairs
+}
+
+func (c *Assertion) Iterate(ctx context.Context) mapiter.Iterator {
+	pairs := c.makePairs()
+	ch := make(chan *mapiter.Pair, len(pairs))
+	go func(ctx context.Context, ch chan *mapiter.Pair, pairs []*mapiter.Pair) {
+		defer close(ch)
+		for _, pair := range pairs {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- pair:
+			}
+		}
+	}(ctx, ch, pairs)
+	return mapiter.New(ch)
+}
@@ -1,23 +1,148 @@
 package client
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"io/ioutil"
 	"net/http"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/gnap/proof"
+	"github.com/pkg/errors"
 )
 
 type Client struct {
-	httpcl *http.Client
+	httpcl        *http.Client
+	grantEndpoint string
+	signer        proof.Signer
+
+	// err holds errors that occurred while configuring the client,
+	// and is reported when a request is sent
+	err error
 }
 
 func New(options ...ClientOption) *Client {
 	httpcl := http.DefaultClient
+	var grantEndpoint string
+	var signer proof.Signer
+	var mtlsKey *gnap.Key
 	for _, option := range options {
 		switch option.Ident() {
 		case identHTTPClient{}:
 			httpcl = option.Value().(*http.Client)
+		case identGrantEndpoint{}:
+			grantEndpoint = option.Value().(string)
+		case identSigner{}:
+			signer = option.Value().(proof.Signer)
+		case identMutualTLS{}:
+			mtlsKey = option.Value().(*gnap.Key)
+		}
+	}
+
+	client := &Client{
+		httpcl:        httpcl,
+		grantEndpoint: grantEndpoint,
+		signer:        signer,
+	}
+
+	if mtlsKey != nil {
+		httpcl, err := withClientCertificate(httpcl, mtlsKey)
+		if err != nil {
+			client.err = errors.Wrap(err, `failed to configure mutual TLS`)
+		} else {
+			client.httpcl = httpcl
+		}
+	}
+	return client
+}
+
+// withClientCertificate returns a copy of the HTTP client that
+// presents the certificate described by key
+func withClientCertificate(httpcl *http.Client, key *gnap.Key) (*http.Client, error) {
+	cert, err := proof.TLSCertificate(key)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create TLS certificate`)
+	}
+
+	var transport *http.Transport
+	switch rt := httpcl.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = rt.Clone()
+	default:
+		return nil, errors.Errorf(`unsupported transport type %T`, rt)
+	}
+
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+
+	cloned := *httpcl
+	cloned.Transport = transport
+	return &cloned, nil
+}
+
+// send encodes payload (if non-nil) as JSON, sends it to the specified
+// URI, and decodes the JSON response into dst. If token is non-empty,
+// it is sent in the Authorization header using the GNAP scheme.
+func (client *Client) send(ctx context.Context, method, uri, token string, payload, dst interface{}) error {
+	if client.err != nil {
+		return client.err
+	}
+
+	var body io.Reader
+	if payload != nil {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(payload); err != nil {
+			return errors.Wrap(err, `failed to encode payload`)
+		}
+		body = &buf
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, uri, body)
+	if err != nil {
+		return errors.Wrap(err, `failed to create HTTP request`)
+	}
+	if payload != nil {
+		req.Header.Set(`Content-Type`, `application/json`)
+	}
+	req.Header.Set(`Accept`, `application/json`)
+	if token != "" {
+		req.Header.Set(`Authorization`, `GNAP `+token)
+	}
+
+	if client.signer != nil {
+		if err := client.signer.Sign(req); err != nil {
+			return errors.Wrap(err, `failed to sign HTTP request`)
 		}
 	}
 
-	return &Client{
-		httpcl: httpcl,
+	res, err := client.httpcl.Do(req)
+	if err != nil {
+		return errors.Wrap(err, `failed to complete HTTP request`)
+	}
+	defer res.Body.Close()
+
+	buf, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.Wrap(err, `failed to read response body`)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return newStatusError(res, buf)
+	}
+
+	if dst == nil || len(buf) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(buf, dst); err != nil {
+		return errors.Wrap(err, `failed to decode response`)
 	}
+	return nil
 }
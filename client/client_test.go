@@ -1,20 +1,879 @@
 package client_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/lestrrat-go/gnap"
 	"github.com/lestrrat-go/gnap/client"
+	"github.com/lestrrat-go/gnap/proof"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/lestrrat-go/jwx/jwt/openid"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGrantRequest(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	t.Run("Success", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req gnap.GrantRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if len(req.Interact().Start()) != 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set(`Content-Type`, `application/json`)
+			w.Write([]byte(`{"continue":{"access_token":{"access":[{"type":"grant"}],"value":"80UPRY5NM33OMUKMKSKU"},"uri":"https://server.example.com/continue","wait":60},"interact":{"redirect":"https://server.example.com/interact/4CF492MLVMSW9MKMXKHQ"}}`))
+		}))
+		defer srv.Close()
+
+		cl := client.New(client.WithGrantEndpoint(srv.URL))
+		res, err := cl.NewGrantRequest().Interact(
+			gnap.NewInteractionRequest(gnap.StartRedirect).
+				AddFinish(gnap.NewInteractionFinish(gnap.FinishRedirect, `1234567890`, `https://localhost:8080/finish`)),
+		).Do(ctx)
+		if !assert.NoError(t, err, `Do should succeed`) {
+			return
+		}
+
+		if !assert.Equal(t, `https://server.example.com/interact/4CF492MLVMSW9MKMXKHQ`, res.Interact().Redirect(), `interact.redirect should match`) {
+			return
+		}
+		if !assert.Equal(t, `80UPRY5NM33OMUKMKSKU`, res.Continue().AccessToken().Value(), `continue.access_token.value should match`) {
+			return
+		}
+	})
+	t.Run("Error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(`Content-Type`, `application/json`)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_request"}`))
+		}))
+		defer srv.Close()
+
+		cl := client.New(client.WithGrantEndpoint(srv.URL))
+		_, err := cl.NewGrantRequest().Interact(
+			gnap.NewInteractionRequest(gnap.StartRedirect),
+		).Do(ctx)
+		if !assert.Error(t, err, `Do should fail`) {
+			return
+		}
+
+		var serr *client.StatusError
+		if !assert.True(t, errors.As(err, &serr), `error should be a *client.StatusError`) {
+			return
+		}
+		if !assert.Equal(t, http.StatusBadRequest, serr.StatusCode, `status code should match`) {
+			return
+		}
+		if !assert.Equal(t, `invalid_request`, serr.Response.Error().Code(), `error should match`) {
+			return
+		}
+	})
+	t.Run("Error Object", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(`Content-Type`, `application/json`)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":{"code":"user_denied","description":"The RO denied the request"}}`))
+		}))
+		defer srv.Close()
+
+		cl := client.New(client.WithGrantEndpoint(srv.URL))
+		_, err := cl.NewGrantRequest().Interact(
+			gnap.NewInteractionRequest(gnap.StartRedirect),
+		).Do(ctx)
+
+		var gerr *gnap.Error
+		if !assert.True(t, errors.As(err, &gerr), `error should be a *gnap.Error`) {
+			return
+		}
+		if !assert.Equal(t, gnap.UserDenied, gerr.Code(), `error code should match`) {
+			return
+		}
+		if !assert.Equal(t, `The RO denied the request`, gerr.Description(), `error description should match`) {
+			return
+		}
+		if !assert.Equal(t, gnap.UserDenied, gnap.ErrorCode(err), `gnap.ErrorCode should match`) {
+			return
+		}
+	})
+	t.Run("User", func(t *testing.T) {
+		var received *gnap.User
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req gnap.GrantRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			received = req.User()
+
+			w.Header().Set(`Content-Type`, `application/json`)
+			w.Write([]byte(`{"access_token":{"access":[{"type":"photo-api"}],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}}`))
+		}))
+		defer srv.Close()
+
+		cl := client.New(client.WithGrantEndpoint(srv.URL))
+		t.Run("Reference", func(t *testing.T) {
+			_, err := cl.NewGrantRequest().User(gnap.NewUserReference(`XUT2MFM1XBIKJKSDU8QM`)).Do(ctx)
+			if !assert.NoError(t, err, `Do should succeed`) {
+				return
+			}
+			if !assert.True(t, received.IsReference(), `user should be a reference`) {
+				return
+			}
+			if !assert.Equal(t, `XUT2MFM1XBIKJKSDU8QM`, received.Ref(), `user reference should match`) {
+				return
+			}
+		})
+		t.Run("Subject Identifiers", func(t *testing.T) {
+			var user gnap.User
+			user.AddSubIDs(*gnap.NewEmailSubjectID(`user@example.com`))
+			_, err := cl.NewGrantRequest().User(&user).Do(ctx)
+			if !assert.NoError(t, err, `Do should succeed`) {
+				return
+			}
+			if !assert.False(t, received.IsReference(), `user should not be a reference`) {
+				return
+			}
+			if !assert.Equal(t, `user@example.com`, received.SubIDs()[0].Email(), `email should match`) {
+				return
+			}
+		})
+	})
+	t.Run("No Endpoint", func(t *testing.T) {
+		_, err := client.New().NewGrantRequest().Do(ctx)
+		if !assert.Error(t, err, `Do should fail`) {
+			return
+		}
+	})
+}
+
+func TestContinuation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var lastMethod string
+	var lastBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(`Authorization`) != `GNAP 80UPRY5NM33OMUKMKSKU` {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		lastMethod = r.Method
+		lastBody = nil
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&lastBody); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set(`Content-Type`, `application/json`)
+		w.Write([]byte(`{"access_token":{"access":[{"type":"photo-api"}],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}}`))
+	}))
+	defer srv.Close()
+
+	var at gnap.AccessToken
+	at.SetValue(`80UPRY5NM33OMUKMKSKU`)
+	var cont gnap.RequestContinuation
+	cont.SetURI(srv.URL + `/continue`)
+	cont.SetAccessToken(&at)
+
 	cl := client.New()
-	cl.NewGrantRequest().Interact(
-		gnap.NewInteraction(gnap.StartRedirect).
-			AddFinish(gnap.NewInteractionFinish(gnap.FinishRedirect, `1234567890`, `https://localhost:8080/finish`)),
-	).Do(ctx)
+	t.Run("Poll", func(t *testing.T) {
+		res, err := cl.NewContinueRequest(&cont).Do(ctx)
+		if !assert.NoError(t, err, `Do should succeed`) {
+			return
+		}
+		if !assert.Equal(t, http.MethodPost, lastMethod, `method should be POST`) {
+			return
+		}
+		if !assert.Nil(t, lastBody, `body should be empty`) {
+			return
+		}
+		if !assert.Equal(t, `OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0`, res.AccessToken().Value(), `access token should match`) {
+			return
+		}
+	})
+	t.Run("InteractRef", func(t *testing.T) {
+		_, err := cl.NewContinueRequest(&cont).InteractRef(`4IFWWIKYBC2PQ6U56NL1`).Do(ctx)
+		if !assert.NoError(t, err, `Do should succeed`) {
+			return
+		}
+		if !assert.Equal(t, map[string]interface{}{"interact_ref": "4IFWWIKYBC2PQ6U56NL1"}, lastBody, `body should match`) {
+			return
+		}
+	})
+	t.Run("Modify", func(t *testing.T) {
+		var ra gnap.ResourceAccess
+		ra.SetType(`photo-api`)
+		_, err := cl.NewModifyGrant(&cont).AddAccessTokens(gnap.NewAccessTokenRequest().AddAccessObjects(ra)).Do(ctx)
+		if !assert.NoError(t, err, `Do should succeed`) {
+			return
+		}
+		if !assert.Equal(t, http.MethodPatch, lastMethod, `method should be PATCH`) {
+			return
+		}
+		if !assert.Contains(t, lastBody, `access_token`, `body should contain access_token`) {
+			return
+		}
+	})
+	t.Run("Cancel", func(t *testing.T) {
+		if !assert.NoError(t, cl.NewCancelGrant(&cont).Do(ctx), `Do should succeed`) {
+			return
+		}
+		if !assert.Equal(t, http.MethodDelete, lastMethod, `method should be DELETE`) {
+			return
+		}
+	})
+}
+
+func TestPollGrant(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set(`Content-Type`, `application/json`)
+		switch attempts {
+		case 1:
+			w.Write([]byte(`{"continue":{"access_token":{"access":[{"type":"grant"}],"value":"33OMUKMKSKU80UPRY5NM"},"uri":"` + `http://` + r.Host + `/continue"}}`))
+		case 2:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"too_fast"}`))
+		default:
+			if r.Header.Get(`Authorization`) != `GNAP 33OMUKMKSKU80UPRY5NM` {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"access_token":{"access":[{"type":"photo-api"}],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	var at gnap.AccessToken
+	at.SetValue(`80UPRY5NM33OMUKMKSKU`)
+	var cont gnap.RequestContinuation
+	cont.SetURI(srv.URL + `/continue`)
+	cont.SetAccessToken(&at)
+
+	cl := client.New()
+	t.Run("Approved", func(t *testing.T) {
+		res, err := cl.PollGrant(ctx, &cont, client.WithPollInterval(10*time.Millisecond), client.WithTooFastIncrement(10*time.Millisecond))
+		if !assert.NoError(t, err, `PollGrant should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 3, attempts, `server should be polled 3 times`) {
+			return
+		}
+		if !assert.Equal(t, `OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0`, res.AccessToken().Value(), `access token should match`) {
+			return
+		}
+	})
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		_, err := cl.PollGrant(ctx, &cont, client.WithPollInterval(time.Hour))
+		if !assert.True(t, errors.Is(err, context.DeadlineExceeded), `error should be context.DeadlineExceeded`) {
+			return
+		}
+	})
+	t.Run("Too Fast With Zero Interval", func(t *testing.T) {
+		var attempts int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.Header().Set(`Content-Type`, `application/json`)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"too_fast"}`))
+		}))
+		defer srv.Close()
+
+		var cont gnap.RequestContinuation
+		cont.SetURI(srv.URL + `/continue`)
+		cont.SetAccessToken(&at)
+
+		ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+
+		_, err := cl.PollGrant(ctx, &cont, client.WithPollInterval(0))
+		if !assert.True(t, errors.Is(err, context.DeadlineExceeded), `error should be context.DeadlineExceeded`) {
+			return
+		}
+		if !assert.Equal(t, 1, attempts, `client should back off after too_fast`) {
+			return
+		}
+	})
+	t.Run("Error In Successful Response", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(`Content-Type`, `application/json`)
+			w.Write([]byte(`{"error":"user_denied"}`))
+		}))
+		defer srv.Close()
+
+		var cont gnap.RequestContinuation
+		cont.SetURI(srv.URL + `/continue`)
+		cont.SetAccessToken(&at)
+
+		_, err := cl.PollGrant(ctx, &cont, client.WithPollInterval(0))
+		var gerr *gnap.Error
+		if !assert.True(t, errors.As(err, &gerr), `error should be a *gnap.Error`) {
+			return
+		}
+		if !assert.Equal(t, gnap.UserDenied, gerr.Code(), `error should match`) {
+			return
+		}
+	})
+	t.Run("Nil Continuation", func(t *testing.T) {
+		if _, err := cl.PollGrant(ctx, nil); !assert.Error(t, err, `PollGrant should fail`) {
+			return
+		}
+	})
+}
+
+func TestSigner(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+	jwkey, err := jwk.New(raw)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+
+	form := gnap.HTTPSig
+	var key gnap.Key
+	key.SetProof(&form)
+	key.SetJWK(jwkey)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Keep the body around, so that the verifier can check its digest
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		var req gnap.GrantRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := req.Client().Key().JWK().(jwk.ECDSAPublicKey); !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := proof.Verify(r, req.Client().Key()); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set(`Content-Type`, `application/json`)
+		w.Write([]byte(`{"access_token":{"access":[{"type":"photo-api"}],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}}`))
+	}))
+	defer srv.Close()
+
+	signer, err := proof.NewHTTPSigSigner(&key)
+	if !assert.NoError(t, err, `proof.NewHTTPSigSigner should succeed`) {
+		return
+	}
+
+	cl := client.New(client.WithGrantEndpoint(srv.URL), client.WithSigner(signer))
+	res, err := cl.NewGrantRequest().Client(gnap.NewClient(key)).Do(ctx)
+	if !assert.NoError(t, err, `Do should succeed`) {
+		return
+	}
+	if !assert.Equal(t, `OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0`, res.AccessToken().Value(), `access token should match`) {
+		return
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: `gnap-client`},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &raw.PublicKey, raw)
+	if !assert.NoError(t, err, `x509.CreateCertificate should succeed`) {
+		return
+	}
+	cert, err := x509.ParseCertificate(der)
+	if !assert.NoError(t, err, `x509.ParseCertificate should succeed`) {
+		return
+	}
+	jwkey, err := jwk.New(raw)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+
+	form := gnap.MutualTLS
+	var key gnap.Key
+	key.SetProof(&form)
+	key.SetJWK(jwkey)
+	key.SetCert(base64.StdEncoding.EncodeToString(der))
+
+	// The AS only knows the thumbprint of the certificate
+	var registered gnap.Key
+	registered.SetProof(&form)
+	registered.SetCertS256(proof.CertificateThumbprint(cert))
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := proof.Verify(r, &registered); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set(`Content-Type`, `application/json`)
+		w.Write([]byte(`{"access_token":{"access":[{"type":"photo-api"}],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}}`))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	t.Run("Matching Certificate", func(t *testing.T) {
+		cl := client.New(
+			client.WithGrantEndpoint(srv.URL),
+			client.WithHTTPClient(srv.Client()),
+			client.WithMutualTLS(&key),
+		)
+		res, err := cl.NewGrantRequest().Do(ctx)
+		if !assert.NoError(t, err, `Do should succeed`) {
+			return
+		}
+		if !assert.Equal(t, `OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0`, res.AccessToken().Value(), `access token should match`) {
+			return
+		}
+	})
+	t.Run("Mismatched Certificate", func(t *testing.T) {
+		orig := registered.CertS256()
+		defer registered.SetCertS256(orig)
+		registered.SetCertS256(`ErIjR9TJZmoeDVF1HPYFpJBSkHiqBbK4FUzT3wJ6pFE`)
+
+		cl := client.New(
+			client.WithGrantEndpoint(srv.URL),
+			client.WithHTTPClient(srv.Client()),
+			client.WithMutualTLS(&key),
+		)
+		_, err := cl.NewGrantRequest().Do(ctx)
+		var serr *client.StatusError
+		if !assert.True(t, errors.As(err, &serr), `error should be a *client.StatusError`) {
+			return
+		}
+		if !assert.Equal(t, http.StatusUnauthorized, serr.StatusCode, `status code should match`) {
+			return
+		}
+	})
+	t.Run("Missing Certificate", func(t *testing.T) {
+		var nocert gnap.Key
+		nocert.SetJWK(jwkey)
+		_, err := client.New(client.WithGrantEndpoint(srv.URL), client.WithMutualTLS(&nocert)).NewGrantRequest().Do(ctx)
+		if !assert.Error(t, err, `Do should fail`) {
+			return
+		}
+	})
+}
+
+func TestRedirectFinish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const (
+		serverNonce = `MBDOFXG4Y5CVJCX821LH`
+		interactRef = `4IFWWIKYBC2PQ6U56NL1`
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(`Content-Type`, `application/json`)
+		switch r.URL.Path {
+		case `/grant`:
+			w.Write([]byte(`{"continue":{"access_token":{"access":[{"type":"grant"}],"value":"80UPRY5NM33OMUKMKSKU"},"uri":"http://` + r.Host + `/continue"},"interact":{"finish":"` + serverNonce + `","redirect":"https://server.example.com/interact"}}`))
+		case `/continue`:
+			var req map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req[`interact_ref`] != interactRef {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_request"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":{"access":[{"type":"photo-api"}],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	cl := client.New(client.WithGrantEndpoint(srv.URL + `/grant`))
+
+	var result *gnap.GrantResponse
+	var resultErr error
+	h := cl.NewRedirectFinishHandler(func(w http.ResponseWriter, _ *http.Request, res *gnap.GrantResponse, err error) {
+		result, resultErr = res, err
+		w.WriteHeader(http.StatusOK)
+	})
+
+	finish := gnap.NewInteractionFinish(gnap.FinishRedirect, `VJLO6A4CAYLBXHTR0KRO`, `https://client.example.com/finish`)
+	res, err := cl.NewGrantRequest().
+		AddAccessTokens(gnap.NewAccessTokenRequest()).
+		Interact(gnap.NewInteractionRequest(gnap.StartRedirect).AddFinish(finish)).
+		Do(ctx)
+	if !assert.NoError(t, err, `Do should succeed`) {
+		return
+	}
+	if !assert.NoError(t, h.Register(finish, res), `Register should succeed`) {
+		return
+	}
+
+	hash, err := gnap.CalculateInteractionHash(``, finish.Nonce(), serverNonce, interactRef, srv.URL+`/grant`)
+	if !assert.NoError(t, err, `CalculateInteractionHash should succeed`) {
+		return
+	}
+
+	t.Run("Invalid Hash", func(t *testing.T) {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, `/finish?interact_ref=`+interactRef+`&hash=bogus`, nil))
+		if !assert.True(t, errors.Is(resultErr, client.ErrUnknownInteraction), `error should be ErrUnknownInteraction`) {
+			return
+		}
+	})
+	t.Run("Success", func(t *testing.T) {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, `/finish?interact_ref=`+interactRef+`&hash=`+hash, nil))
+		if !assert.NoError(t, resultErr, `continuation should succeed`) {
+			return
+		}
+		if !assert.Equal(t, `OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0`, result.AccessToken().Value(), `access token should match`) {
+			return
+		}
+	})
+	t.Run("Replay", func(t *testing.T) {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, `/finish?interact_ref=`+interactRef+`&hash=`+hash, nil))
+		if !assert.True(t, errors.Is(resultErr, client.ErrUnknownInteraction), `error should be ErrUnknownInteraction`) {
+			return
+		}
+	})
+	t.Run("Expired", func(t *testing.T) {
+		var expiredErr error
+		h := cl.NewRedirectFinishHandler(func(w http.ResponseWriter, _ *http.Request, _ *gnap.GrantResponse, err error) {
+			expiredErr = err
+			w.WriteHeader(http.StatusOK)
+		}, client.WithInteractionTimeout(10*time.Millisecond))
+		if !assert.NoError(t, h.Register(finish, res), `Register should succeed`) {
+			return
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, `/finish?interact_ref=`+interactRef+`&hash=`+hash, nil))
+		if !assert.True(t, errors.Is(expiredErr, client.ErrUnknownInteraction), `error should be ErrUnknownInteraction`) {
+			return
+		}
+	})
+}
+
+func TestPushFinishTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const (
+		serverNonce = `MBDOFXG4Y5CVJCX821LH`
+		interactRef = `4IFWWIKYBC2PQ6U56NL1`
+	)
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(`Content-Type`, `application/json`)
+		switch r.URL.Path {
+		case `/grant`:
+			w.Write([]byte(`{"continue":{"access_token":{"access":[{"type":"grant"}],"value":"80UPRY5NM33OMUKMKSKU"},"uri":"http://` + r.Host + `/continue"},"interact":{"finish":"` + serverNonce + `","redirect":"https://server.example.com/interact"}}`))
+		case `/continue`:
+			// never respond to the continuation
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	cl := client.New(client.WithGrantEndpoint(srv.URL + `/grant`))
+
+	results := make(chan error, 1)
+	h := cl.NewPushFinishHandler(func(_ *gnap.GrantResponse, err error) {
+		results <- err
+	}, client.WithContinueTimeout(50*time.Millisecond))
+
+	finish := gnap.NewInteractionFinish(gnap.FinishPush, `VJLO6A4CAYLBXHTR0KRO`, `https://client.example.com/finish`)
+	res, err := cl.NewGrantRequest().
+		AddAccessTokens(gnap.NewAccessTokenRequest()).
+		Interact(gnap.NewInteractionRequest(gnap.StartRedirect).AddFinish(finish)).
+		Do(ctx)
+	if !assert.NoError(t, err, `Do should succeed`) {
+		return
+	}
+	if !assert.NoError(t, h.Register(finish, res), `Register should succeed`) {
+		return
+	}
+
+	hash, err := gnap.CalculateInteractionHash(``, finish.Nonce(), serverNonce, interactRef, srv.URL+`/grant`)
+	if !assert.NoError(t, err, `CalculateInteractionHash should succeed`) {
+		return
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, `/finish`, bytes.NewBufferString(`{"interact_ref":"`+interactRef+`","hash":"`+hash+`"}`)))
+	if !assert.Equal(t, http.StatusNoContent, w.Code, `push should be accepted`) {
+		return
+	}
+
+	select {
+	case err := <-results:
+		if !assert.True(t, errors.Is(err, context.DeadlineExceeded), `error should be context.DeadlineExceeded`) {
+			return
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal(`continuation should time out`)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+	key, err := jwk.New(raw)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+	//nolint:errcheck
+	key.Set(jwk.KeyIDKey, `issuer-key-1`)
+
+	pub, err := jwk.PublicKeyOf(key)
+	if !assert.NoError(t, err, `jwk.PublicKeyOf should succeed`) {
+		return
+	}
+	set := jwk.NewSet()
+	set.Add(pub)
+
+	const (
+		issuer   = `https://as.example.com`
+		audience = `client-541-ab`
+		nonce    = `VJLO6A4CAYLBXHTR0KRO`
+	)
+
+	sign := func(t *testing.T, alg jwa.SignatureAlgorithm, signingKey jwk.Key, expires time.Time, claims map[string]interface{}) *gnap.Assertion {
+		t.Helper()
+
+		token := openid.New()
+		//nolint:errcheck
+		token.Set(jwt.IssuerKey, issuer)
+		//nolint:errcheck
+		token.Set(jwt.SubjectKey, `248289761001`)
+		//nolint:errcheck
+		token.Set(jwt.AudienceKey, audience)
+		//nolint:errcheck
+		token.Set(jwt.IssuedAtKey, time.Now())
+		if !expires.IsZero() {
+			//nolint:errcheck
+			token.Set(jwt.ExpirationKey, expires)
+		}
+		for k, v := range claims {
+			//nolint:errcheck
+			token.Set(k, v)
+		}
+
+		signed, err := jwt.Sign(token, alg, signingKey)
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			t.FailNow()
+		}
+		return gnap.NewAssertion(gnap.AssertionIDToken, string(signed))
+	}
+
+	valid := sign(t, jwa.ES256, key, time.Now().Add(time.Hour), map[string]interface{}{
+		`nonce`:         nonce,
+		openid.EmailKey: `user@example.com`,
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		token, err := client.VerifyIDToken(valid, set, issuer, audience, client.WithNonce(nonce))
+		if !assert.NoError(t, err, `VerifyIDToken should succeed`) {
+			return
+		}
+		if !assert.Equal(t, `248289761001`, token.Subject(), `sub should match`) {
+			return
+		}
+		if !assert.Equal(t, `user@example.com`, token.Email(), `email should match`) {
+			return
+		}
+	})
+	t.Run("Without Nonce", func(t *testing.T) {
+		assertion := sign(t, jwa.ES256, key, time.Now().Add(time.Hour), nil)
+		if _, err := client.VerifyIDToken(assertion, set, issuer, audience, client.WithoutNonce()); !assert.NoError(t, err, `VerifyIDToken should succeed`) {
+			return
+		}
+	})
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+	otherKey, err := jwk.New(other)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+	//nolint:errcheck
+	otherKey.Set(jwk.KeyIDKey, `issuer-key-1`)
+
+	secret, err := jwk.New([]byte(`supersecret-shared-with-the-issuer`))
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+	//nolint:errcheck
+	secret.Set(jwk.KeyIDKey, `issuer-key-1`)
+
+	testcases := []struct {
+		Name      string
+		Assertion *gnap.Assertion
+		Issuer    string
+		Audience  string
+		Options   []client.IDTokenOption
+	}{
+		{
+			Name:      "Wrong Signature",
+			Assertion: sign(t, jwa.ES256, otherKey, time.Now().Add(time.Hour), nil),
+			Issuer:    issuer,
+			Audience:  audience,
+			Options:   []client.IDTokenOption{client.WithNonce(nonce)},
+		},
+		{
+			Name:      "Wrong Audience",
+			Assertion: valid,
+			Issuer:    issuer,
+			Audience:  `client-542-cd`,
+			Options:   []client.IDTokenOption{client.WithNonce(nonce)},
+		},
+		{
+			Name:      "Expired",
+			Assertion: sign(t, jwa.ES256, key, time.Now().Add(-time.Hour), nil),
+			Issuer:    issuer,
+			Audience:  audience,
+			Options:   []client.IDTokenOption{client.WithNonce(nonce)},
+		},
+		{
+			Name:      "Missing Expiration",
+			Assertion: sign(t, jwa.ES256, key, time.Time{}, nil),
+			Issuer:    issuer,
+			Audience:  audience,
+			Options:   []client.IDTokenOption{client.WithNonce(nonce)},
+		},
+		{
+			Name:      "Wrong Nonce",
+			Assertion: valid,
+			Issuer:    issuer,
+			Audience:  audience,
+			Options:   []client.IDTokenOption{client.WithNonce(`MBDOFXG4Y5CVJCX821LH`)},
+		},
+		{
+			Name:      "Missing Nonce",
+			Assertion: valid,
+			Issuer:    issuer,
+			Audience:  audience,
+		},
+		{
+			Name:      "Wrong Issuer",
+			Assertion: valid,
+			Issuer:    `https://other.example.com`,
+			Audience:  audience,
+			Options:   []client.IDTokenOption{client.WithNonce(nonce)},
+		},
+		{
+			Name:      "Missing Issuer",
+			Assertion: valid,
+			Audience:  audience,
+			Options:   []client.IDTokenOption{client.WithNonce(nonce)},
+		},
+		{
+			Name:      "Algorithm Not Matching Key",
+			Assertion: sign(t, jwa.HS256, secret, time.Now().Add(time.Hour), map[string]interface{}{`nonce`: nonce}),
+			Issuer:    issuer,
+			Audience:  audience,
+			Options:   []client.IDTokenOption{client.WithNonce(nonce)},
+		},
+		{
+			Name:      "Unexpected Algorithm",
+			Assertion: valid,
+			Issuer:    issuer,
+			Audience:  audience,
+			Options:   []client.IDTokenOption{client.WithNonce(nonce), client.WithAlgorithm(jwa.ES384)},
+		},
+		{
+			Name:      "Wrong Format",
+			Assertion: gnap.NewAssertion(gnap.AssertionSAML2, valid.Value()),
+			Issuer:    issuer,
+			Audience:  audience,
+			Options:   []client.IDTokenOption{client.WithNonce(nonce)},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := client.VerifyIDToken(tc.Assertion, set, tc.Issuer, tc.Audience, tc.Options...)
+			if !assert.Error(t, err, `VerifyIDToken should fail`) {
+				return
+			}
+		})
+	}
+
+	t.Run("Symmetric Key In Set", func(t *testing.T) {
+		shared := jwk.NewSet()
+		shared.Add(secret)
+
+		assertion := sign(t, jwa.HS256, secret, time.Now().Add(time.Hour), map[string]interface{}{`nonce`: nonce})
+		if _, err := client.VerifyIDToken(assertion, shared, issuer, audience, client.WithNonce(nonce)); !assert.Error(t, err, `VerifyIDToken should fail`) {
+			return
+		}
+	})
+	t.Run("Expected Algorithm", func(t *testing.T) {
+		if _, err := client.VerifyIDToken(valid, set, issuer, audience, client.WithNonce(nonce), client.WithAlgorithm(jwa.ES256)); !assert.NoError(t, err, `VerifyIDToken should succeed`) {
+			return
+		}
+	})
 }
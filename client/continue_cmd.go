@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/pkg/errors"
+)
+
+// ContinueRequestCmd continues an ongoing grant request, by sending
+// a POST request to the continuation URI
+type ContinueRequestCmd struct {
+	client       *Client
+	continuation *gnap.RequestContinuation
+	payload      *gnap.ContinueRequest
+}
+
+// ModifyGrantCmd modifies an ongoing grant request, by sending a
+// PATCH request to the continuation URI
+type ModifyGrantCmd struct {
+	client       *Client
+	continuation *gnap.RequestContinuation
+	payload      *gnap.GrantRequest
+}
+
+// CancelGrantCmd revokes an ongoing grant request, by sending a
+// DELETE request to the continuation URI
+type CancelGrantCmd struct {
+	client       *Client
+	continuation *gnap.RequestContinuation
+}
+
+// NewContinueRequest creates a command to continue the grant request
+// described by the `continue` field of a previous grant response.
+func (client *Client) NewContinueRequest(continuation *gnap.RequestContinuation) *ContinueRequestCmd {
+	return &ContinueRequestCmd{
+		client:       client,
+		continuation: continuation,
+		payload:      gnap.NewContinueRequest(),
+	}
+}
+
+// InteractRef specifies the interaction reference that was returned
+// to the client when the interaction finished. If unspecified, the
+// request is sent without a body, which is used for polling.
+func (cmd *ContinueRequestCmd) InteractRef(v string) *ContinueRequestCmd {
+	cmd.payload.SetInteractRef(v)
+	return cmd
+}
+
+func (cmd *ContinueRequestCmd) Do(ctx context.Context) (*gnap.GrantResponse, error) {
+	var payload interface{}
+	if _, ok := cmd.payload.Get(`interact_ref`); ok {
+		payload = cmd.payload
+	}
+
+	var res gnap.GrantResponse
+	if err := cmd.client.sendContinuation(ctx, http.MethodPost, cmd.continuation, payload, &res); err != nil {
+		return nil, errors.Wrap(err, `failed to send continuation request`)
+	}
+	return &res, nil
+}
+
+// NewModifyGrant creates a command to modify the grant request
+// described by the `continue` field of a previous grant response.
+func (client *Client) NewModifyGrant(continuation *gnap.RequestContinuation) *ModifyGrantCmd {
+	return &ModifyGrantCmd{
+		client:       client,
+		continuation: continuation,
+		payload:      gnap.NewGrantRequest(),
+	}
+}
+
+func (cmd *ModifyGrantCmd) AddAccessTokens(v ...*gnap.AccessTokenRequest) *ModifyGrantCmd {
+	cmd.payload.AddAccessTokens(v...)
+	return cmd
+}
+
+func (cmd *ModifyGrantCmd) Interact(v *gnap.InteractionRequest) *ModifyGrantCmd {
+	cmd.payload.SetInteract(v)
+	return cmd
+}
+
+func (cmd *ModifyGrantCmd) Subject(v *gnap.SubjectRequest) *ModifyGrantCmd {
+	cmd.payload.SetSubject(v)
+	return cmd
+}
+
+func (cmd *ModifyGrantCmd) Do(ctx context.Context) (*gnap.GrantResponse, error) {
+	if err := cmd.payload.Validate(); err != nil {
+		return nil, errors.Wrap(err, `failed to validate payload`)
+	}
+
+	var res gnap.GrantResponse
+	if err := cmd.client.sendContinuation(ctx, http.MethodPatch, cmd.continuation, cmd.payload, &res); err != nil {
+		return nil, errors.Wrap(err, `failed to send grant modification request`)
+	}
+	return &res, nil
+}
+
+// NewCancelGrant creates a command to cancel the grant request
+// described by the `continue` field of a previous grant response.
+func (client *Client) NewCancelGrant(continuation *gnap.RequestContinuation) *CancelGrantCmd {
+	return &CancelGrantCmd{
+		client:       client,
+		continuation: continuation,
+	}
+}
+
+// Do sends the cancellation request. Upon success the grant is
+// revoked, and the authorization server does not return a new
+// grant response.
+func (cmd *CancelGrantCmd) Do(ctx context.Context) error {
+	if err := cmd.client.sendContinuation(ctx, http.MethodDelete, cmd.continuation, nil, nil); err != nil {
+		return errors.Wrap(err, `failed to send grant cancellation request`)
+	}
+	return nil
+}
+
+func (client *Client) sendContinuation(ctx context.Context, method string, continuation *gnap.RequestContinuation, payload, dst interface{}) error {
+	if continuation == nil {
+		return errors.New(`continuation must be non-nil`)
+	}
+
+	uri := continuation.URI()
+	if uri == "" {
+		return errors.New(`continuation URI is not available`)
+	}
+
+	var token string
+	if at := continuation.AccessToken(); at != nil {
+		token = at.Value()
+	}
+	if token == "" {
+		return errors.New(`continuation access token is not available`)
+	}
+
+	return client.send(ctx, method, uri, token, payload, dst)
+}
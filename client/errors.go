@@ -0,0 +1,71 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/lestrrat-go/gnap"
+)
+
+// StatusError is returned when the authorization server responds
+// with a non-2xx HTTP status.
+type StatusError struct {
+	// StatusCode is the HTTP status code returned by the server
+	StatusCode int
+
+	// Response contains the decoded response body, if the server
+	// returned a valid GNAP response. It is nil otherwise.
+	Response *gnap.GrantResponse
+
+	// Header contains the HTTP headers of the response
+	Header http.Header
+
+	// Body contains the raw response body
+	Body []byte
+}
+
+func newStatusError(res *http.Response, body []byte) *StatusError {
+	serr := &StatusError{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte{'{'}) {
+		var gres gnap.GrantResponse
+		if err := json.Unmarshal(body, &gres); err == nil {
+			serr.Response = &gres
+		}
+	}
+	return serr
+}
+
+func (e *StatusError) Error() string {
+	if gerr := e.gnapError(); gerr != nil {
+		return fmt.Sprintf(`unexpected HTTP status %d: %s`, e.StatusCode, gerr)
+	}
+	return fmt.Sprintf(`unexpected HTTP status %d`, e.StatusCode)
+}
+
+// Unwrap returns the error returned by the authorization server, so
+// that it can be matched using errors.As:
+//
+//	var gerr *gnap.Error
+//	if errors.As(err, &gerr) && gerr.Code() == gnap.TooFast {
+//	  ...
+//	}
+func (e *StatusError) Unwrap() error {
+	if gerr := e.gnapError(); gerr != nil {
+		return gerr
+	}
+	return nil
+}
+
+func (e *StatusError) gnapError() *gnap.Error {
+	if e.Response == nil {
+		return nil
+	}
+	return e.Response.Error()
+}
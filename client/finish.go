@@ -0,0 +1,319 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/pkg/errors"
+)
+
+// maxPushSize is the maximum size of the push messages accepted by
+// PushFinishHandler
+const maxPushSize = 1 << 16
+
+// DefaultInteractionTimeout is the default duration that finish
+// handlers wait for an interaction to finish before discarding the
+// registered grant
+const DefaultInteractionTimeout = 30 * time.Minute
+
+// DefaultContinueTimeout is the default duration that PushFinishHandler
+// waits for the continuation request sent once a push is received
+const DefaultContinueTimeout = 30 * time.Second
+
+// ErrUnknownInteraction is returned when the `interact_ref` and `hash`
+// received by a finish handler do not match any pending grant
+var ErrUnknownInteraction = errors.New(`interaction does not match any pending grant`)
+
+// VerifyInteractionHash verifies the `hash` value that the client
+// received along with `interactRef` when the interaction finished.
+//
+// `finish` is the InteractionFinish sent in the grant request, which
+// holds the client nonce and the hash method, and `serverNonce` is the
+// `finish` value of the InteractionResponse returned by the server.
+// The hash is calculated using the grant endpoint of the client.
+func (client *Client) VerifyInteractionHash(finish *gnap.InteractionFinish, serverNonce, interactRef, hash string) error {
+	if finish == nil {
+		return errors.New(`finish must be non-nil`)
+	}
+
+	if client.grantEndpoint == "" {
+		return errors.New(`grant endpoint must be specified`)
+	}
+
+	if hash == "" {
+		return errors.New(`hash is required`)
+	}
+
+	if interactRef == "" {
+		return errors.New(`interact_ref is required`)
+	}
+
+	return gnap.VerifyInteractionHash(hash, finish.HashMethod(), finish.Nonce(), serverNonce, interactRef, client.grantEndpoint)
+}
+
+// FinishFunc is called by finish handlers with the result of the
+// continuation request that was sent once the interaction finished.
+// If the interaction could not be matched or verified, or if the
+// continuation request failed, `res` is nil and `err` is non-nil.
+//
+// The function is responsible for writing the response to `w`.
+type FinishFunc func(w http.ResponseWriter, r *http.Request, res *gnap.GrantResponse, err error)
+
+// pendingInteraction is a grant that is waiting for the interaction
+// with the resource owner to finish
+type pendingInteraction struct {
+	finish       *gnap.InteractionFinish
+	serverNonce  string
+	continuation *gnap.RequestContinuation
+	deadline     time.Time
+}
+
+// pendingInteractions holds the pending interactions of a finish
+// handler, keyed by client nonce. Interactions that do not finish
+// before their deadline are discarded.
+type pendingInteractions struct {
+	client  *Client
+	timeout time.Duration
+	mu      sync.Mutex
+	pending map[string]*pendingInteraction
+}
+
+func newPendingInteractions(client *Client, options []FinishHandlerOption) pendingInteractions {
+	timeout := DefaultInteractionTimeout
+	for _, option := range options {
+		switch option.Ident() {
+		case identInteractionTimeout{}:
+			timeout = option.Value().(time.Duration)
+		}
+	}
+	return pendingInteractions{
+		client:  client,
+		timeout: timeout,
+	}
+}
+
+// prune removes the interactions whose deadline has passed. The caller
+// must hold the lock
+func (p *pendingInteractions) prune(now time.Time) {
+	for nonce, pi := range p.pending {
+		if now.After(pi.deadline) {
+			delete(p.pending, nonce)
+		}
+	}
+}
+
+func (p *pendingInteractions) register(method gnap.FinishMode, finish *gnap.InteractionFinish, res *gnap.GrantResponse) error {
+	if finish == nil || res == nil {
+		return errors.New(`finish and response must be non-nil`)
+	}
+
+	if v := finish.Method(); v != method {
+		return errors.Errorf(`expected finish method %q, got %q`, method, v)
+	}
+
+	if finish.Nonce() == "" {
+		return errors.New(`finish must have a nonce`)
+	}
+
+	if !gnap.IsSupportedHashMethod(finish.HashMethod()) {
+		return errors.Errorf(`unsupported hash method %q`, finish.HashMethod())
+	}
+
+	if res.Continue() == nil {
+		return errors.New(`response does not contain a continuation`)
+	}
+
+	interact := res.Interact()
+	if interact == nil || interact.Finish() == "" {
+		return errors.New(`response does not contain a server nonce`)
+	}
+
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pending == nil {
+		p.pending = make(map[string]*pendingInteraction)
+	}
+	p.prune(now)
+	p.pending[finish.Nonce()] = &pendingInteraction{
+		finish:       finish,
+		serverNonce:  interact.Finish(),
+		continuation: res.Continue(),
+		deadline:     now.Add(p.timeout),
+	}
+	return nil
+}
+
+// match finds the pending interaction for which `hash` is valid, and
+// removes it so that it cannot be used again
+func (p *pendingInteractions) match(interactRef, hash string) (*pendingInteraction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.prune(time.Now())
+	for nonce, pi := range p.pending {
+		if err := p.client.VerifyInteractionHash(pi.finish, pi.serverNonce, interactRef, hash); err == nil {
+			delete(p.pending, nonce)
+			return pi, nil
+		}
+	}
+	return nil, ErrUnknownInteraction
+}
+
+// cont verifies the received values, and continues the matching grant
+func (p *pendingInteractions) cont(ctx context.Context, interactRef, hash string) (*gnap.GrantResponse, error) {
+	if interactRef == "" || hash == "" {
+		return nil, errors.New(`interact_ref and hash are required`)
+	}
+
+	pi, err := p.match(interactRef, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.client.NewContinueRequest(pi.continuation).InteractRef(interactRef).Do(ctx)
+}
+
+// RedirectFinishHandler is the http.Handler for the URI that the
+// resource owner is redirected to when the interaction finishes, for
+// grants using the "redirect" finish method.
+//
+// Grants must be registered with the handler using Register. When the
+// resource owner is redirected back to the client, the handler matches
+// the `interact_ref` and `hash` query parameters against the pending
+// grants, continues the grant, and passes the result to its FinishFunc.
+type RedirectFinishHandler struct {
+	pending  pendingInteractions
+	callback FinishFunc
+}
+
+// NewRedirectFinishHandler creates a new RedirectFinishHandler. `fn`
+// is called for every request that the handler receives.
+//
+// Registered grants are discarded if the interaction does not finish
+// within DefaultInteractionTimeout, which can be changed using
+// WithInteractionTimeout.
+func (client *Client) NewRedirectFinishHandler(fn FinishFunc, options ...FinishHandlerOption) *RedirectFinishHandler {
+	return &RedirectFinishHandler{
+		pending:  newPendingInteractions(client, options),
+		callback: fn,
+	}
+}
+
+// Register registers a pending grant with the handler. `finish` must
+// be the InteractionFinish sent in the grant request, and `res` the
+// response returned by the authorization server.
+func (h *RedirectFinishHandler) Register(finish *gnap.InteractionFinish, res *gnap.GrantResponse) error {
+	return h.pending.register(gnap.FinishRedirect, finish, res)
+}
+
+func (h *RedirectFinishHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set(`Allow`, http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	res, err := h.pending.cont(r.Context(), q.Get(`interact_ref`), q.Get(`hash`))
+	h.callback(w, r, res, err)
+}
+
+// PushFinishFunc is called by PushFinishHandler with the result of the
+// continuation request that was sent once the interaction finished.
+// If the continuation request failed, `res` is nil and `err` is non-nil.
+type PushFinishFunc func(res *gnap.GrantResponse, err error)
+
+// PushFinishHandler is the http.Handler for the URI that the
+// authorization server sends the `interact_ref` and `hash` values to
+// when the interaction finishes, for grants using the "push" finish
+// method.
+//
+// Grants must be registered with the handler using Register. Once the
+// values received from the server are matched against a pending grant,
+// the handler responds to the server, continues the grant in the
+// background, and passes the result to its PushFinishFunc. Values that
+// do not match any pending grant are rejected with HTTP 400.
+type PushFinishHandler struct {
+	pending  pendingInteractions
+	callback PushFinishFunc
+	timeout  time.Duration
+}
+
+type pushMessage struct {
+	InteractRef string `json:"interact_ref"`
+	Hash        string `json:"hash"`
+}
+
+// NewPushFinishHandler creates a new PushFinishHandler. `fn` is called
+// for every pending grant that is continued by the handler.
+//
+// Registered grants are discarded if the interaction does not finish
+// within DefaultInteractionTimeout, which can be changed using
+// WithInteractionTimeout. The continuation request is abandoned if it
+// does not complete within DefaultContinueTimeout, which can be changed
+// using WithContinueTimeout.
+func (client *Client) NewPushFinishHandler(fn PushFinishFunc, options ...FinishHandlerOption) *PushFinishHandler {
+	timeout := DefaultContinueTimeout
+	for _, option := range options {
+		switch option.Ident() {
+		case identContinueTimeout{}:
+			timeout = option.Value().(time.Duration)
+		}
+	}
+	return &PushFinishHandler{
+		pending:  newPendingInteractions(client, options),
+		callback: fn,
+		timeout:  timeout,
+	}
+}
+
+// Register registers a pending grant with the handler. `finish` must
+// be the InteractionFinish sent in the grant request, and `res` the
+// response returned by the authorization server.
+func (h *PushFinishHandler) Register(finish *gnap.InteractionFinish, res *gnap.GrantResponse) error {
+	return h.pending.register(gnap.FinishPush, finish, res)
+}
+
+func (h *PushFinishHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set(`Allow`, http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var msg pushMessage
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxPushSize)).Decode(&msg); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if msg.InteractRef == "" || msg.Hash == "" {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	pi, err := h.pending.match(msg.InteractRef, msg.Hash)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	// the grant is continued after responding, as the server may not
+	// be able to handle the continuation request before the push
+	// request completes. The request context is done by then, so the
+	// continuation gets its own deadline
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+		defer cancel()
+		res, err := h.pending.client.NewContinueRequest(pi.continuation).InteractRef(msg.InteractRef).Do(ctx)
+		h.callback(res, err)
+	}()
+}
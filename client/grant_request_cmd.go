@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/pkg/errors"
+)
+
+// MultipleAccessTokens specifies whether the access tokens are requested
+// in the array form, even when a single access token is requested. The
+// authorization server then responds with an array of access tokens
+func (cmd *GrantRequestCmd) MultipleAccessTokens(v bool) *GrantRequestCmd {
+	cmd.payload.SetMultipleAccessTokens(v)
+	return cmd
+}
+
+// Do sends the grant request to the grant endpoint of the authorization
+// server, and returns the decoded response.
+//
+// If the authorization server responds with a non-2xx status, a
+// *StatusError is returned.
+func (cmd *GrantRequestCmd) Do(ctx context.Context) (*gnap.GrantResponse, error) {
+	if err := cmd.payload.Validate(); err != nil {
+		return nil, errors.Wrap(err, `failed to validate payload`)
+	}
+
+	if cmd.client.grantEndpoint == "" {
+		return nil, errors.New(`grant endpoint is not configured (use client.WithGrantEndpoint)`)
+	}
+
+	var res gnap.GrantResponse
+	if err := cmd.client.send(ctx, http.MethodPost, cmd.client.grantEndpoint, "", cmd.payload, &res); err != nil {
+		return nil, errors.Wrap(err, `failed to send grant request`)
+	}
+	return &res, nil
+}
@@ -1,14 +1,6 @@
 package client
 
-import (
-	"bytes"
-	"context"
-	"encoding/json"
-	"net/http"
-
-	"github.com/lestrrat-go/gnap"
-	"github.com/pkg/errors"
-)
+import "github.com/lestrrat-go/gnap"
 
 type GrantRequestCmd struct {
 	client  *Client
@@ -37,7 +29,7 @@ func (cmd *GrantRequestCmd) Client(v *gnap.Client) *GrantRequestCmd {
 	return cmd
 }
 
-func (cmd *GrantRequestCmd) Interact(v *gnap.Interaction) *GrantRequestCmd {
+func (cmd *GrantRequestCmd) Interact(v *gnap.InteractionRequest) *GrantRequestCmd {
 	cmd.payload.SetInteract(v)
 	return cmd
 }
@@ -47,22 +39,7 @@ func (cmd *GrantRequestCmd) Subject(v *gnap.SubjectRequest) *GrantRequestCmd {
 	return cmd
 }
 
-func (cmd *GrantRequestCmd) Do(ctx context.Context) error {
-	if err := cmd.payload.Validate(); err != nil {
-		errors.Wrap(err, `failed to validate payload`)
-	}
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(cmd.payload); err != nil {
-		return errors.Wrap(err, `failed to encode payload`)
-	}
-	req, err := http.NewRequest(http.MethodPost, `dummy`, &buf)
-	if err != nil {
-		return errors.Wrap(err, `failed to create HTTP request`)
-	}
-	res, err := cmd.client.httpcl.Do(req)
-	if err != nil {
-		return errors.Wrap(err, `failed to complete HTTP request`)
-	}
-	_ = res
-	return nil
+func (cmd *GrantRequestCmd) User(v *gnap.User) *GrantRequestCmd {
+	cmd.payload.SetUser(v)
+	return cmd
 }
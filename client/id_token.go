@@ -0,0 +1,160 @@
+package client
+
+import (
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/gnap/proof"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/lestrrat-go/jwx/jwt/openid"
+	"github.com/pkg/errors"
+)
+
+// VerifyIDToken verifies an assertion of format `id_token`, which is
+// an OpenID Connect ID Token, and returns its claims.
+//
+// The signature is verified using the keys in `set`, which is usually
+// the JWKS published by the issuer. The key is selected by the `kid`
+// header, or is the only key in the set. The `alg` header must be
+// compatible with the key (see proof.CheckAlgorithm), and must match
+// the algorithm given with WithAlgorithm, if any. Symmetric keys are
+// rejected. The token must have been issued by `issuer` to `audience`,
+// and must not have expired.
+//
+// The `nonce` claim must match the value given with WithNonce. If the
+// client did not send a nonce to the issuer, it must explicitly skip
+// the check using WithoutNonce, otherwise verification fails.
+func VerifyIDToken(assertion *gnap.Assertion, set jwk.Set, issuer, audience string, options ...IDTokenOption) (openid.Token, error) {
+	if assertion == nil {
+		return nil, errors.New(`assertion must be non-nil`)
+	}
+
+	if assertion.Format() != gnap.AssertionIDToken {
+		return nil, errors.Errorf(`unsupported assertion format %q`, assertion.Format())
+	}
+
+	if set == nil {
+		return nil, errors.New(`key set must be non-nil`)
+	}
+
+	if issuer == "" {
+		return nil, errors.New(`issuer is required`)
+	}
+
+	if audience == "" {
+		return nil, errors.New(`audience is required`)
+	}
+
+	var alg jwa.SignatureAlgorithm
+	var clock jwt.Clock = jwt.ClockFunc(time.Now)
+	var nonce string
+	var skipNonce bool
+	var skew time.Duration
+	for _, option := range options {
+		switch option.Ident() {
+		case identAcceptableSkew{}:
+			skew = option.Value().(time.Duration)
+		case identAlgorithm{}:
+			alg = option.Value().(jwa.SignatureAlgorithm)
+		case identClock{}:
+			clock = option.Value().(jwt.Clock)
+		case identNonce{}:
+			nonce = option.Value().(string)
+		case identSkipNonce{}:
+			skipNonce = option.Value().(bool)
+		}
+	}
+
+	if nonce == "" && !skipNonce {
+		return nil, errors.New(`nonce is required (use WithoutNonce to skip the check)`)
+	}
+
+	alg, key, err := idTokenKey(assertion.Value(), set, alg)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to verify id_token`)
+	}
+
+	token, err := jwt.ParseString(assertion.Value(),
+		jwt.WithVerify(alg, key),
+		jwt.WithToken(openid.New()),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to verify id_token`)
+	}
+
+	// exp is optional for JWTs in general, but is required for ID Tokens
+	if token.Expiration().IsZero() {
+		return nil, errors.New(`id_token does not have an exp claim`)
+	}
+
+	if token.Issuer() != issuer {
+		return nil, errors.Errorf(`unexpected issuer %q in id_token`, token.Issuer())
+	}
+
+	if nonce != "" {
+		if v, ok := token.Get(`nonce`); !ok || v != nonce {
+			return nil, errors.New(`nonce in id_token does not match`)
+		}
+	}
+
+	if err := jwt.Validate(token,
+		jwt.WithAudience(audience),
+		jwt.WithClock(clock),
+		jwt.WithAcceptableSkew(skew),
+	); err != nil {
+		return nil, errors.Wrap(err, `failed to validate id_token`)
+	}
+
+	return token.(openid.Token), nil
+}
+
+// idTokenKey selects the key in `set` that verifies the id_token, and
+// returns it along with the algorithm from the `alg` header, once the
+// algorithm has been checked against the key and against `expected`
+func idTokenKey(value string, set jwk.Set, expected jwa.SignatureAlgorithm) (jwa.SignatureAlgorithm, interface{}, error) {
+	msg, err := jws.ParseString(value)
+	if err != nil {
+		return "", nil, errors.Wrap(err, `failed to parse id_token`)
+	}
+
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return "", nil, errors.Errorf(`expected 1 signature, got %d`, len(sigs))
+	}
+	hdrs := sigs[0].ProtectedHeaders()
+
+	var key jwk.Key
+	var ok bool
+	if kid := hdrs.KeyID(); kid != "" {
+		key, ok = set.LookupKeyID(kid)
+		if !ok {
+			return "", nil, errors.Errorf(`key %q not found in key set`, kid)
+		}
+	} else {
+		if set.Len() != 1 {
+			return "", nil, errors.New(`id_token does not specify a key ID, and the key set does not have exactly one key`)
+		}
+		key, _ = set.Get(0)
+	}
+
+	if key.KeyType() == jwa.OctetSeq {
+		return "", nil, errors.New(`symmetric keys cannot be used to verify id_tokens`)
+	}
+
+	alg := hdrs.Algorithm()
+	if expected != "" && alg != expected {
+		return "", nil, errors.Errorf(`unexpected algorithm %q (expected %q)`, alg, expected)
+	}
+	if err := proof.CheckAlgorithm(alg, key); err != nil {
+		return "", nil, err
+	}
+
+	var raw interface{}
+	if err := key.Raw(&raw); err != nil {
+		return "", nil, errors.Wrap(err, `failed to obtain raw key`)
+	}
+	return alg, raw, nil
+}
@@ -2,11 +2,28 @@ package client
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/gnap/proof"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
 	"github.com/lestrrat-go/option"
 )
 
+type identAcceptableSkew struct{}
+type identAlgorithm struct{}
+type identClock struct{}
+type identContinueTimeout struct{}
+type identGrantEndpoint struct{}
 type identHTTPClient struct{}
+type identInteractionTimeout struct{}
+type identMutualTLS struct{}
+type identNonce struct{}
+type identPollInterval struct{}
+type identSigner struct{}
+type identSkipNonce struct{}
+type identTooFastIncrement struct{}
 
 type ClientOption interface {
 	option.Interface
@@ -19,8 +36,152 @@ type clientOption struct {
 
 func (*clientOption) clientOption() {}
 
+type PollOption interface {
+	option.Interface
+	pollOption()
+}
+
+type pollOption struct {
+	option.Interface
+}
+
+func (*pollOption) pollOption() {}
+
+// FinishHandlerOption is an option that can be passed to
+// NewRedirectFinishHandler and NewPushFinishHandler
+type FinishHandlerOption interface {
+	option.Interface
+	finishHandlerOption()
+}
+
+type finishHandlerOption struct {
+	option.Interface
+}
+
+func (*finishHandlerOption) finishHandlerOption() {}
+
+// IDTokenOption is an option that can be passed to VerifyIDToken
+type IDTokenOption interface {
+	option.Interface
+	idTokenOption()
+}
+
+type idTokenOption struct {
+	option.Interface
+}
+
+func (*idTokenOption) idTokenOption() {}
+
 func WithHTTPClient(v *http.Client) ClientOption {
 	return &clientOption{
 		option.New(identHTTPClient{}, v),
 	}
 }
+
+// WithGrantEndpoint specifies the URI of the grant endpoint of the
+// authorization server. Grant requests are sent to this URI
+func WithGrantEndpoint(v string) ClientOption {
+	return &clientOption{
+		option.New(identGrantEndpoint{}, v),
+	}
+}
+
+// WithSigner specifies the signer used to add key proofing to every
+// request sent to the authorization server. For example, use
+// proof.NewHTTPSigSigner to sign requests with HTTP Message Signatures
+func WithSigner(v proof.Signer) ClientOption {
+	return &clientOption{
+		option.New(identSigner{}, v),
+	}
+}
+
+// WithMutualTLS configures the HTTP client to present the certificate
+// in the `cert` field of the key, along with the private key in the
+// `jwk` field, as the TLS client certificate.
+//
+// The HTTP client specified in WithHTTPClient (or http.DefaultClient)
+// is copied, and is not modified.
+func WithMutualTLS(v *gnap.Key) ClientOption {
+	return &clientOption{
+		option.New(identMutualTLS{}, v),
+	}
+}
+
+// WithPollInterval specifies the interval used between polling
+// attempts when the authorization server does not specify the
+// `wait` value in the continuation. The default is DefaultPollInterval
+func WithPollInterval(v time.Duration) PollOption {
+	return &pollOption{
+		option.New(identPollInterval{}, v),
+	}
+}
+
+// WithTooFastIncrement specifies the duration added to the polling
+// interval when the authorization server responds with a "too_fast"
+// error. The default is DefaultTooFastIncrement. Non-positive values
+// are ignored
+func WithTooFastIncrement(v time.Duration) PollOption {
+	return &pollOption{
+		option.New(identTooFastIncrement{}, v),
+	}
+}
+
+// WithInteractionTimeout specifies how long a finish handler keeps a
+// registered grant around while waiting for the interaction to finish.
+// The default is DefaultInteractionTimeout
+func WithInteractionTimeout(v time.Duration) FinishHandlerOption {
+	return &finishHandlerOption{
+		option.New(identInteractionTimeout{}, v),
+	}
+}
+
+// WithContinueTimeout specifies how long PushFinishHandler waits for
+// the continuation request that it sends once a push is received. The
+// default is DefaultContinueTimeout
+func WithContinueTimeout(v time.Duration) FinishHandlerOption {
+	return &finishHandlerOption{
+		option.New(identContinueTimeout{}, v),
+	}
+}
+
+// WithNonce specifies the expected value of the `nonce` claim of the
+// id_token
+func WithNonce(v string) IDTokenOption {
+	return &idTokenOption{
+		option.New(identNonce{}, v),
+	}
+}
+
+// WithoutNonce skips the verification of the `nonce` claim of the
+// id_token. Only use it if the client did not send a nonce to the
+// issuer, as the nonce is what prevents the id_token from being
+// replayed
+func WithoutNonce() IDTokenOption {
+	return &idTokenOption{
+		option.New(identSkipNonce{}, true),
+	}
+}
+
+// WithAlgorithm specifies the algorithm that the id_token must be
+// signed with. Tokens whose `alg` header differs are rejected
+func WithAlgorithm(v jwa.SignatureAlgorithm) IDTokenOption {
+	return &idTokenOption{
+		option.New(identAlgorithm{}, v),
+	}
+}
+
+// WithClock specifies the clock used to check the `exp`, `iat` and
+// `nbf` claims of the id_token
+func WithClock(v jwt.Clock) IDTokenOption {
+	return &idTokenOption{
+		option.New(identClock{}, v),
+	}
+}
+
+// WithAcceptableSkew specifies the clock skew that is tolerated when
+// checking the `exp`, `iat` and `nbf` claims of the id_token
+func WithAcceptableSkew(v time.Duration) IDTokenOption {
+	return &idTokenOption{
+		option.New(identAcceptableSkew{}, v),
+	}
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/pkg/errors"
+)
+
+// DefaultPollInterval is the interval used between polling attempts
+// when the authorization server does not specify the `wait` value
+const DefaultPollInterval = 5 * time.Second
+
+// DefaultTooFastIncrement is the duration added to the polling
+// interval when the authorization server responds with a "too_fast"
+// error
+const DefaultTooFastIncrement = 5 * time.Second
+
+// PollGrant repeatedly continues the grant request described by
+// `continuation` until the authorization server either issues an
+// access token, returns an error, or stops returning a continuation.
+// Errors returned by the server, including those carried in the body
+// of a successful response, can be matched using errors.As with a
+// *gnap.Error.
+//
+// Between each attempt the client sleeps for the number of seconds
+// specified in the `wait` field of the latest continuation. When the
+// server responds with a "too_fast" error (or HTTP 429), the interval
+// is increased by DefaultTooFastIncrement (see WithTooFastIncrement),
+// or set to the value of the Retry-After header if that is longer.
+//
+// The loop is aborted when `ctx` is cancelled, in which case the
+// context's error is returned. Use context.WithTimeout to limit the
+// total amount of time spent polling.
+func (client *Client) PollGrant(ctx context.Context, continuation *gnap.RequestContinuation, options ...PollOption) (*gnap.GrantResponse, error) {
+	if continuation == nil {
+		return nil, errors.New(`continuation must be non-nil`)
+	}
+
+	defaultInterval := DefaultPollInterval
+	increment := DefaultTooFastIncrement
+	for _, option := range options {
+		switch option.Ident() {
+		case identPollInterval{}:
+			defaultInterval = option.Value().(time.Duration)
+		case identTooFastIncrement{}:
+			increment = option.Value().(time.Duration)
+		}
+	}
+
+	// the increment is what keeps a zero interval from turning into a
+	// busy loop, so it can never be disabled
+	if increment <= 0 {
+		increment = DefaultTooFastIncrement
+	}
+
+	interval := pollInterval(continuation, defaultInterval)
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		res, err := client.NewContinueRequest(continuation).Do(ctx)
+		if err != nil {
+			var serr *StatusError
+			if !errors.As(err, &serr) || !serr.isTooFast() {
+				return nil, errors.Wrap(err, `failed to poll grant`)
+			}
+
+			// always slow down, so that a zero interval does not turn
+			// into a busy loop against the server
+			next := interval + increment
+			if d, ok := serr.retryAfter(); ok && d > next {
+				next = d
+			}
+			interval = next
+			timer.Reset(interval)
+			continue
+		}
+
+		// servers may report errors such as "user_denied" in a
+		// successful response. The *gnap.Error is kept in the chain,
+		// so that it can be matched using errors.As
+		if gerr := res.Error(); gerr != nil {
+			return nil, errors.Wrap(gerr, `failed to poll grant`)
+		}
+
+		if res.AccessToken() != nil || res.Continue() == nil {
+			return res, nil
+		}
+
+		continuation = res.Continue()
+		interval = pollInterval(continuation, defaultInterval)
+		timer.Reset(interval)
+	}
+}
+
+func pollInterval(continuation *gnap.RequestContinuation, defaultInterval time.Duration) time.Duration {
+	if wait := continuation.Wait(); wait != nil {
+		return time.Duration(*wait) * time.Second
+	}
+	return defaultInterval
+}
+
+func (e *StatusError) isTooFast() bool {
+	if e.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return gnap.ErrorCode(e) == gnap.TooFast
+}
+
+func (e *StatusError) retryAfter() (time.Duration, bool) {
+	v := e.Header.Get(`Retry-After`)
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
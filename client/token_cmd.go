@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/pkg/errors"
+)
+
+// RotateTokenCmd rotates an access token, by sending a POST request
+// to the token management URI
+type RotateTokenCmd struct {
+	client *Client
+	token  *gnap.AccessToken
+}
+
+// RevokeTokenCmd revokes an access token, by sending a DELETE request
+// to the token management URI
+type RevokeTokenCmd struct {
+	client *Client
+	token  *gnap.AccessToken
+}
+
+// NewRotateToken creates a command to rotate `token`. The token must
+// have been issued with a management URI (see gnap.AccessToken.Manage).
+func (client *Client) NewRotateToken(token *gnap.AccessToken) *RotateTokenCmd {
+	return &RotateTokenCmd{
+		client: client,
+		token:  token,
+	}
+}
+
+// Do sends the rotation request, and returns the new access token.
+// Upon success the original token is no longer valid.
+func (cmd *RotateTokenCmd) Do(ctx context.Context) (*gnap.AccessToken, error) {
+	var res gnap.GrantResponse
+	if err := cmd.client.sendManagement(ctx, http.MethodPost, cmd.token, &res); err != nil {
+		return nil, errors.Wrap(err, `failed to send token rotation request`)
+	}
+
+	token := res.AccessToken()
+	if token == nil {
+		return nil, errors.New(`response does not contain an access token`)
+	}
+	return token, nil
+}
+
+// NewRevokeToken creates a command to revoke `token`. The token must
+// have been issued with a management URI (see gnap.AccessToken.Manage).
+func (client *Client) NewRevokeToken(token *gnap.AccessToken) *RevokeTokenCmd {
+	return &RevokeTokenCmd{
+		client: client,
+		token:  token,
+	}
+}
+
+func (cmd *RevokeTokenCmd) Do(ctx context.Context) error {
+	if err := cmd.client.sendManagement(ctx, http.MethodDelete, cmd.token, nil); err != nil {
+		return errors.Wrap(err, `failed to send token revocation request`)
+	}
+	return nil
+}
+
+func (client *Client) sendManagement(ctx context.Context, method string, token *gnap.AccessToken, dst interface{}) error {
+	if token == nil {
+		return errors.New(`token must be non-nil`)
+	}
+
+	uri := token.Manage()
+	if uri == "" {
+		return errors.New(`token management URI is not available`)
+	}
+
+	value := token.Value()
+	if value == "" {
+		return errors.New(`token value is not available`)
+	}
+
+	return client.send(ctx, method, uri, value, nil, dst)
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/pkg/errors"
+)
+
+// UserCodeGrant is a grant request that is waiting for the resource
+// owner to enter a user code at the authorization server
+type UserCodeGrant struct {
+	client   *Client
+	response *gnap.GrantResponse
+}
+
+// DoUserCode sends the grant request using the "user_code" interaction
+// start mode, which is added to the `interact` field if necessary.
+//
+// The user code in the returned UserCodeGrant should be displayed to
+// the resource owner, and Wait should then be called to poll the
+// server until the grant is approved.
+func (cmd *GrantRequestCmd) DoUserCode(ctx context.Context) (*UserCodeGrant, error) {
+	interact := cmd.payload.Interact()
+	if interact == nil {
+		cmd.payload.SetInteract(gnap.NewInteractionRequest(gnap.StartUserCode))
+	} else if !hasStartMode(interact, gnap.StartUserCode) {
+		interact.AddStart(gnap.StartUserCode)
+	}
+
+	res, err := cmd.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the server may approve the grant right away, in which case
+	// there is no need to interact
+	if res.AccessToken() == nil {
+		if ir := res.Interact(); ir == nil || ir.UserCode() == nil {
+			return nil, errors.New(`response does not contain a user code`)
+		}
+		if res.Continue() == nil {
+			return nil, errors.New(`response does not contain a continuation`)
+		}
+	}
+
+	return &UserCodeGrant{
+		client:   cmd.client,
+		response: res,
+	}, nil
+}
+
+// Response returns the grant response returned by the server
+func (g *UserCodeGrant) Response() *gnap.GrantResponse {
+	return g.response
+}
+
+// UserCode returns the user code, or nil if the grant has already
+// been approved. If the URL of the user code is empty, the resource
+// owner must enter the code at a URL that is known to the client by
+// other means.
+func (g *UserCodeGrant) UserCode() *gnap.UserCode {
+	if ir := g.response.Interact(); ir != nil {
+		return ir.UserCode()
+	}
+	return nil
+}
+
+// Wait polls the server until the grant is approved or denied, or
+// `ctx` is cancelled. See PollGrant for details.
+func (g *UserCodeGrant) Wait(ctx context.Context, options ...PollOption) (*gnap.GrantResponse, error) {
+	if g.response.AccessToken() != nil {
+		return g.response, nil
+	}
+	return g.client.PollGrant(ctx, g.response.Continue(), options...)
+}
+
+func hasStartMode(interact *gnap.InteractionRequest, mode gnap.StartMode) bool {
+	for _, v := range interact.Start() {
+		if v == mode {
+			return true
+		}
+	}
+	return false
+}
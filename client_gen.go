@@ -13,6 +13,7 @@ import (
 
 type Client struct {
 	classID     *string
+	display     *ClientDisplay
 	instanceID  *string
 	key         *Key
 	extraFields map[string]interface{}
@@ -38,6 +39,11 @@ func (c *Client) Get(key string) (interface{}, bool) {
 			return nil, false
 		}
 		return c.classID, true
+	case "display":
+		if c.display == nil {
+			return nil, false
+		}
+		return c.display, true
 	case "instance_id":
 		if c.instanceID == nil {
 			return nil, false
@@ -67,6 +73,12 @@ func (c *Client) Set(key string, value interface{}) error {
 		} else {
 			return errors.Errorf(`invalid type for "class_id" (%T)`, value)
 		}
+	case "display":
+		if v, ok := value.(*ClientDisplay); ok {
+			c.display = v
+		} else {
+			return errors.Errorf(`invalid type for "display" (%T)`, value)
+		}
 	case "instance_id":
 		if v, ok := value.(string); ok {
 			c.instanceID = &v
@@ -101,6 +113,14 @@ func (c *Client) ClassID() string {
 	return *(c.classID)
 }
 
+func (c *Client) SetDisplay(v *ClientDisplay) {
+	c.display = v
+}
+
+func (c *Client) Display() *ClientDisplay {
+	return c.display
+}
+
 func (c *Client) SetInstanceID(v string) {
 	c.instanceID = &v
 }
@@ -149,6 +169,7 @@ func (c Client) MarshalJSON() ([]byte, error) {
 
 func (c *Client) UnmarshalJSON(data []byte) error {
 	c.classID = nil
+	c.display = nil
 	c.instanceID = nil
 	c.key = nil
 	dec := json.NewDecoder(bytes.NewReader(data))
@@ -187,6 +208,10 @@ LOOP:
 					return errors.Wrap(err, `error reading class_id`)
 				}
 				c.classID = &tmp
+			case "display":
+				if err := dec.Decode(&(c.display)); err != nil {
+					return errors.Wrap(err, `error reading display`)
+				}
 			case "instance_id":
 				var tmp string
 				if err := dec.Decode(&tmp); err != nil {
@@ -217,6 +242,9 @@ func (c *Client) makePairs() []*mapiter.Pair {
 	if tmp := c.classID; tmp != nil {
 		pairs = append(pairs, &mapiter.Pair{Key: "class_id", Value: *tmp})
 	}
+	if tmp := c.display; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "display", Value: *tmp})
+	}
 	if tmp := c.instanceID; tmp != nil {
 		pairs = append(pairs, &mapiter.Pair{Key: "instance_id", Value: *tmp})
 	}
@@ -0,0 +1,178 @@
+package gnap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/lestrrat-go/iter/mapiter"
+	"github.com/pkg/errors"
+)
+
+type ContinueRequest struct {
+	interactRef *string
+	extraFields map[string]interface{}
+}
+
+func NewContinueRequest() *ContinueRequest {
+	return &ContinueRequest{}
+}
+
+func (c *ContinueRequest) Validate() error {
+	return nil
+}
+
+func (c *ContinueRequest) Get(key string) (interface{}, bool) {
+	switch key {
+	case "interact_ref":
+		if c.interactRef == nil {
+			return nil, false
+		}
+		return c.interactRef, true
+	default:
+		if c.extraFields == nil {
+			return nil, false
+		}
+		v, ok := c.extraFields[key]
+		return v, ok
+	}
+}
+
+func (c *ContinueRequest) Set(key string, value interface{}) error {
+	switch key {
+	case "interact_ref":
+		if v, ok := value.(string); ok {
+			c.interactRef = &v
+		} else if value == nil {
+			c.interactRef = nil
+		} else {
+			return errors.Errorf(`invalid type for "interact_ref" (%T)`, value)
+		}
+	default:
+		if c.extraFields == nil {
+			c.extraFields = make(map[string]interface{})
+		}
+		c.extraFields[key] = value
+	}
+	return nil
+}
+
+func (c *ContinueRequest) SetInteractRef(v string) {
+	c.interactRef = &v
+}
+
+func (c *ContinueRequest) InteractRef() string {
+	if c.interactRef == nil {
+		return ""
+	}
+	return *(c.interactRef)
+}
+
+func (c ContinueRequest) MarshalJSON() ([]byte, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	buf.WriteByte('{')
+	var i int
+	for iter := c.Iterate(ctx); iter.Next(ctx); {
+		pair := iter.Pair()
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		i++
+		buf.WriteString(strconv.Quote(pair.Key.(string)))
+		buf.WriteByte(':')
+		if err := enc.Encode(pair.Value); err != nil {
+			return nil, errors.Wrapf(err, `failed to encode %s`, pair.Key.(string))
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (c *ContinueRequest) UnmarshalJSON(data []byte) error {
+	c.interactRef = nil
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return errors.Wrap(err, `error reading token`)
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok != '{' {
+			return errors.Errorf(`expected '{', but got '%c'`, tok)
+		}
+	default:
+		return errors.Errorf(`expected '{', but got '%c'`, tok)
+	}
+LOOP:
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return errors.Wrap(err, `error reading token`)
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			if tok == '}' { // End of object
+				break LOOP
+			}
+			return errors.Errorf(`unexpected delimiter '%c'`, tok)
+		case string:
+			switch tok {
+			case "interact_ref":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading interact_ref`)
+				}
+				c.interactRef = &tmp
+			default:
+				var tmp interface{}
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrapf(err, `error reading %s`, tok)
+				}
+				if c.extraFields == nil {
+					c.extraFields = map[string]interface{}{}
+				}
+				c.extraFields[tok] = tmp
+			}
+		}
+	}
+	return nil
+}
+
+func (c *ContinueRequest) makePairs() []*mapiter.Pair {
+	var pairs []*mapiter.Pair
+	if tmp := c.interactRef; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "interact_ref", Value: *tmp})
+	}
+	var extraKeys []string
+	for k := range c.extraFields {
+		extraKeys = append(extraKeys, k)
+	}
+	for _, k := range extraKeys {
+		pairs = append(pairs, &mapiter.Pair{Key: k, Value: c.extraFields[k]})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.(string) < pairs[j].Key.(string)
+	})
+	return pairs
+}
+
+func (c *ContinueRequest) Iterate(ctx context.Context) mapiter.Iterator {
+	pairs := c.makePairs()
+	ch := make(chan *mapiter.Pair, len(pairs))
+	go func(ctx context.Context, ch chan *mapiter.Pair, pairs []*mapiter.Pair) {
+		defer close(ch)
+		for _, pair := range pairs {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- pair:
+			}
+		}
+	}(ctx, ch, pairs)
+	return mapiter.New(ch)
+}
@@ -0,0 +1,41 @@
+package gnap
+
+import (
+	"errors"
+)
+
+// Error codes returned by the authorization server
+const (
+	InvalidRequest          = "invalid_request"
+	InvalidClient           = "invalid_client"
+	InvalidInteraction      = "invalid_interaction"
+	InvalidFlag             = "invalid_flag"
+	InvalidRotation         = "invalid_rotation"
+	KeyRotationNotSupported = "key_rotation_not_supported"
+	InvalidContinuation     = "invalid_continuation"
+	UserDenied              = "user_denied"
+	RequestDenied           = "request_denied"
+	UnknownUser             = "unknown_user"
+	UnknownInteraction      = "unknown_interaction"
+	TooFast                 = "too_fast"
+	TooManyAttempts         = "too_many_attempts"
+)
+
+// Error implements the error interface, so that errors returned by
+// the authorization server can be matched using errors.As
+func (c *Error) Error() string {
+	if desc := c.Description(); desc != "" {
+		return c.Code() + `: ` + desc
+	}
+	return c.Code()
+}
+
+// ErrorCode returns the code of the first *Error in the chain of
+// `err`, or the empty string if there is none
+func ErrorCode(err error) string {
+	var gerr *Error
+	if errors.As(err, &gerr) {
+		return gerr.Code()
+	}
+	return ""
+}
@@ -0,0 +1,222 @@
+package gnap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/lestrrat-go/iter/mapiter"
+	"github.com/pkg/errors"
+)
+
+// Error describes an error returned by the authorization server
+type Error struct {
+	code        *string
+	description *string
+	extraFields map[string]interface{}
+}
+
+func NewError(code string) *Error {
+	return &Error{
+		code: &code,
+	}
+}
+
+func (c *Error) Validate() error {
+	if c.code == nil {
+		return errors.Errorf(`field "code" is required`)
+	}
+	return nil
+}
+
+func (c *Error) Get(key string) (interface{}, bool) {
+	switch key {
+	case "code":
+		if c.code == nil {
+			return nil, false
+		}
+		return c.code, true
+	case "description":
+		if c.description == nil {
+			return nil, false
+		}
+		return c.description, true
+	default:
+		if c.extraFields == nil {
+			return nil, false
+		}
+		v, ok := c.extraFields[key]
+		return v, ok
+	}
+}
+
+func (c *Error) Set(key string, value interface{}) error {
+	switch key {
+	case "code":
+		if v, ok := value.(string); ok {
+			c.code = &v
+		} else if value == nil {
+			c.code = nil
+		} else {
+			return errors.Errorf(`invalid type for "code" (%T)`, value)
+		}
+	case "description":
+		if v, ok := value.(string); ok {
+			c.description = &v
+		} else if value == nil {
+			c.description = nil
+		} else {
+			return errors.Errorf(`invalid type for "description" (%T)`, value)
+		}
+	default:
+		if c.extraFields == nil {
+			c.extraFields = make(map[string]interface{})
+		}
+		c.extraFields[key] = value
+	}
+	return nil
+}
+
+func (c *Error) SetCode(v string) {
+	c.code = &v
+}
+
+func (c *Error) Code() string {
+	if c.code == nil {
+		return ""
+	}
+	return *(c.code)
+}
+
+func (c *Error) SetDescription(v string) {
+	c.description = &v
+}
+
+func (c *Error) Description() string {
+	if c.description == nil {
+		return ""
+	}
+	return *(c.description)
+}
+
+func (c Error) MarshalJSON() ([]byte, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	buf.WriteByte('{')
+	var i int
+	for iter := c.Iterate(ctx); iter.Next(ctx); {
+		pair := iter.Pair()
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		i++
+		buf.WriteString(strconv.Quote(pair.Key.(string)))
+		buf.WriteByte(':')
+		if err := enc.Encode(pair.Value); err != nil {
+			return nil, errors.Wrapf(err, `failed to encode %s`, pair.Key.(string))
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (c *Error) UnmarshalJSON(data []byte) error {
+	c.code = nil
+	c.description = nil
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return errors.Wrap(err, `error reading token`)
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok != '{' {
+			return errors.Errorf(`expected '{', but got '%c'`, tok)
+		}
+	case string:
+		c.code = &tok
+		return nil
+	default:
+		return errors.Errorf(`expected '{' or string, but got '%c'`, tok)
+	}
+LOOP:
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return errors.Wrap(err, `error reading token`)
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			if tok == '}' { // End of object
+				break LOOP
+			}
+			return errors.Errorf(`unexpected delimiter '%c'`, tok)
+		case string:
+			switch tok {
+			case "code":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading code`)
+				}
+				c.code = &tmp
+			case "description":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading description`)
+				}
+				c.description = &tmp
+			default:
+				var tmp interface{}
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrapf(err, `error reading %s`, tok)
+				}
+				if c.extraFields == nil {
+					c.extraFields = map[string]interface{}{}
+				}
+				c.extraFields[tok] = tmp
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Error) makePairs() []*mapiter.Pair {
+	var pairs []*mapiter.Pair
+	if tmp := c.code; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "code", Value: *tmp})
+	}
+	if tmp := c.description; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "description", Value: *tmp})
+	}
+	var extraKeys []string
+	for k := range c.extraFields {
+		extraKeys = append(extraKeys, k)
+	}
+	for _, k := range extraKeys {
+		pairs = append(pairs, &mapiter.Pair{Key: k, Value: c.extraFields[k]})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.(string) < pairs[j].Key.(string)
+	})
+	return pairs
+}
+
+func (c *Error) Iterate(ctx context.Context) mapiter.Iterator {
+	pairs := c.makePairs()
+	ch := make(chan *mapiter.Pair, len(pairs))
+	go func(ctx context.Context, ch chan *mapiter.Pair, pairs []*mapiter.Pair) {
+		defer close(ch)
+		for _, pair := range pairs {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- pair:
+			}
+		}
+	}(ctx, ch, pairs)
+	return mapiter.New(ch)
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -44,12 +45,24 @@ func TestDataTypes(t *testing.T) {
 			ra1.AddActions("read", "write", "delete")
 			ra1.AddLocations("https://server.example.net/", "https://resource.local/other")
 			ra1.AddDataTypes("metadata", "images")
-			atr1.AddAccess(&ra1)
+			atr1.AddAccessObjects(ra1)
 			expected.AddAccessTokens(&atr1)
 			t.Run("Roundtrip", func(t *testing.T) {
 				datatypeRoundtrip(t, src, &expected)
 			})
 		})
+		t.Run("Single Access Token Array", func(t *testing.T) {
+			const src = `{"access_token":[{"access":["photos"],"label":"photos"}]}`
+			var expected gnap.GrantRequest
+			var atr gnap.AccessTokenRequest
+			atr.AddAccess(gnap.NewAccessReference("photos"))
+			atr.SetLabel("photos")
+			expected.AddAccessTokens(&atr)
+			expected.SetMultipleAccessTokens(true)
+			t.Run("Roundtrip", func(t *testing.T) {
+				datatypeRoundtrip(t, src, &expected)
+			})
+		})
 		t.Run("Multiple Access Tokens", func(t *testing.T) {
 			const src = `{"access_token":[{"access":[{"actions":["read","write","delete"],"datatypes":["metadata","images"],"locations":["https://server.example.net/","https://resource.local/other"],"type":"photo-api"}]},{"access":[{"actions":["foo","bar"],"datatypes":["data","pictures","walrus whiskers"],"locations":["https://resource.other/"],"type":"walrus-access"}]}]}`
 
@@ -60,7 +73,7 @@ func TestDataTypes(t *testing.T) {
 			ra1.AddActions("read", "write", "delete")
 			ra1.AddLocations("https://server.example.net/", "https://resource.local/other")
 			ra1.AddDataTypes("metadata", "images")
-			atr1.AddAccess(&ra1)
+			atr1.AddAccessObjects(ra1)
 
 			var atr2 gnap.AccessTokenRequest
 			var ra2 gnap.ResourceAccess
@@ -68,14 +81,195 @@ func TestDataTypes(t *testing.T) {
 			ra2.AddActions("foo", "bar")
 			ra2.AddLocations("https://resource.other/")
 			ra2.AddDataTypes("data", "pictures", "walrus whiskers")
-			atr2.AddAccess(&ra2)
+			atr2.AddAccessObjects(ra2)
 
 			expected.AddAccessTokens(&atr1, &atr2)
+			expected.SetMultipleAccessTokens(true)
 			t.Run("Roundtrip", func(t *testing.T) {
 				datatypeRoundtrip(t, src, &expected)
 			})
 		})
 	})
+	t.Run("AccessToken Flags", func(t *testing.T) {
+		var ra gnap.ResourceAccess
+		ra.SetType("photo-api")
+
+		t.Run("Flags", func(t *testing.T) {
+			const src = `{"access":[{"type":"photo-api"}],"flags":["bearer","durable"],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}`
+
+			expected := gnap.NewAccessToken(gnap.NewAccessEntry(ra), "OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0")
+			expected.AddFlags(gnap.Bearer, gnap.Durable)
+
+			if !t.Run("Roundtrip", func(t *testing.T) {
+				datatypeRoundtrip(t, src, expected)
+			}) {
+				return
+			}
+			if !assert.True(t, expected.IsBearer(), `token should be a bearer token`) {
+				return
+			}
+			if !assert.True(t, expected.IsDurable(), `token should be durable`) {
+				return
+			}
+			if !assert.False(t, expected.HasFlag(gnap.Split), `token should not be split`) {
+				return
+			}
+		})
+		t.Run("Legacy Booleans", func(t *testing.T) {
+			bound, unbound := true, false
+			testcases := []struct {
+				Name     string
+				Src      string
+				Expected string
+				Bound    *bool
+			}{
+				{
+					Name:     "Bound",
+					Src:      `{"access":[{"type":"photo-api"}],"bound":true,"durable":false,"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}`,
+					Expected: `{"access":[{"type":"photo-api"}],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}`,
+					Bound:    &bound,
+				},
+				{
+					Name:     "Unbound",
+					Src:      `{"access":[{"type":"photo-api"}],"bound":false,"durable":true,"split":true,"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}`,
+					Expected: `{"access":[{"type":"photo-api"}],"flags":["bearer","durable"],"split":true,"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}`,
+					Bound:    &unbound,
+				},
+				{
+					Name:     "Mixed",
+					Src:      `{"access":[{"type":"photo-api"}],"bound":false,"flags":["bearer"],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}`,
+					Expected: `{"access":[{"type":"photo-api"}],"flags":["bearer"],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}`,
+					Bound:    &unbound,
+				},
+				{
+					Name:     "Flags Only",
+					Src:      `{"access":[{"type":"photo-api"}],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}`,
+					Expected: `{"access":[{"type":"photo-api"}],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}`,
+				},
+			}
+
+			for _, tc := range testcases {
+				tc := tc
+				t.Run(tc.Name, func(t *testing.T) {
+					var token gnap.AccessToken
+					if !assert.NoError(t, json.Unmarshal([]byte(tc.Src), &token), `json.Unmarshal should succeed`) {
+						return
+					}
+
+					buf, err := json.Marshal(&token)
+					if !assert.NoError(t, err, `json.Marshal should succeed`) {
+						return
+					}
+					if !assert.Equal(t, tc.Expected, string(buf), `legacy booleans should be converted to flags`) {
+						return
+					}
+					if !assert.Equal(t, tc.Bound, token.Bound(), `Bound should match the legacy field`) {
+						return
+					}
+				})
+			}
+		})
+		t.Run("Deprecated Accessors", func(t *testing.T) {
+			token := gnap.NewAccessToken(gnap.NewAccessEntry(ra), "OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0")
+			if !assert.Nil(t, token.Bound(), `bound should not be present`) {
+				return
+			}
+			if !assert.Nil(t, token.Durable(), `durable should not be present`) {
+				return
+			}
+			if !assert.Nil(t, token.Split(), `split should not be present`) {
+				return
+			}
+
+			bound, durable, split := false, true, true
+			token.SetBound(&bound)
+			token.SetDurable(&durable)
+			token.SetSplit(&split)
+			if !assert.Equal(t, []gnap.AccessTokenAttribute{gnap.Bearer, gnap.Durable}, token.Flags(), `flags should match`) {
+				return
+			}
+			if !assert.False(t, *token.Bound(), `token should not be bound`) {
+				return
+			}
+			if !assert.True(t, *token.Durable(), `token should be durable`) {
+				return
+			}
+			if !assert.True(t, *token.Split(), `split should be present`) {
+				return
+			}
+
+			buf, err := json.Marshal(token)
+			if !assert.NoError(t, err, `json.Marshal should succeed`) {
+				return
+			}
+			if !assert.Equal(t, `{"access":[{"type":"photo-api"}],"flags":["bearer","durable"],"split":true,"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}`, string(buf), `JSON should match`) {
+				return
+			}
+
+			token.SetBound(nil)
+			token.SetDurable(nil)
+			token.SetSplit(nil)
+			if !assert.Empty(t, token.Flags(), `flags should be removed`) {
+				return
+			}
+			if !assert.Nil(t, token.Split(), `split should be removed`) {
+				return
+			}
+		})
+		t.Run("Bearer Token With Key", func(t *testing.T) {
+			const src = `{"access":[{"type":"photo-api"}],"flags":["bearer"],"key":{"crv":"P-256","kty":"EC","x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU","y":"x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"},"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}`
+
+			var token gnap.AccessToken
+			if !assert.NoError(t, json.Unmarshal([]byte(src), &token), `json.Unmarshal should succeed`) {
+				return
+			}
+			if !assert.Error(t, token.Validate(), `Validate should fail`) {
+				return
+			}
+		})
+	})
+	t.Run("User", func(t *testing.T) {
+		t.Run("String", func(t *testing.T) {
+			const src = `"XUT2MFM1XBIKJKSDU8QM"`
+
+			expected := gnap.NewUserReference("XUT2MFM1XBIKJKSDU8QM")
+
+			t.Run("Roundtrip", func(t *testing.T) {
+				datatypeRoundtrip(t, src, expected)
+			})
+		})
+		t.Run("Object", func(t *testing.T) {
+			const src = `{"assertions":[{"format":"id_token","value":"eyj..."}],"sub_ids":[{"email":"user@example.com","format":"email"}]}`
+
+			var expected gnap.User
+			expected.AddSubIDs(*gnap.NewEmailSubjectID("user@example.com"))
+			expected.AddAssertions(*gnap.NewAssertion(gnap.AssertionIDToken, "eyj..."))
+
+			t.Run("Roundtrip", func(t *testing.T) {
+				datatypeRoundtrip(t, src, &expected)
+			})
+		})
+		t.Run("Reference In Object", func(t *testing.T) {
+			var user gnap.User
+			if !assert.Error(t, json.Unmarshal([]byte(`{"ref":"XUT2MFM1XBIKJKSDU8QM"}`), &user), `json.Unmarshal should fail`) {
+				return
+			}
+			if !assert.Error(t, json.Unmarshal([]byte(`{"ref":"XUT2MFM1XBIKJKSDU8QM","sub_ids":[{"email":"user@example.com","format":"email"}]}`), &user), `json.Unmarshal should fail`) {
+				return
+			}
+		})
+		t.Run("Reference With Subject Identifiers", func(t *testing.T) {
+			user := gnap.NewUserReference("XUT2MFM1XBIKJKSDU8QM")
+			user.AddSubIDs(*gnap.NewEmailSubjectID("user@example.com"))
+
+			if !assert.Error(t, user.Validate(), `Validate should fail`) {
+				return
+			}
+			if _, err := json.Marshal(user); !assert.Error(t, err, `json.Marshal should fail`) {
+				return
+			}
+		})
+	})
 	t.Run("AccessTokenRequest", func(t *testing.T) {
 		const src = `{"access":[{"actions":["read","write","delete"],"datatypes":["metadata","images"],"locations":["https://server.example.net/","https://resource.local/other"],"type":"photo-api"},{"actions":["foo","bar"],"datatypes":["data","pictures","walrus whiskers"],"locations":["https://resource.other/"],"type":"walrus-access"}],"flags":["split"],"label":"token1-23"}`
 		var expected gnap.AccessTokenRequest
@@ -87,19 +281,72 @@ func TestDataTypes(t *testing.T) {
 		ra1.AddActions("read", "write", "delete")
 		ra1.AddLocations("https://server.example.net/", "https://resource.local/other")
 		ra1.AddDataTypes("metadata", "images")
-		expected.AddAccess(&ra1)
+		expected.AddAccessObjects(ra1)
 
 		var ra2 gnap.ResourceAccess
 		ra2.SetType("walrus-access")
 		ra2.AddActions("foo", "bar")
 		ra2.AddLocations("https://resource.other/")
 		ra2.AddDataTypes("data", "pictures", "walrus whiskers")
-		expected.AddAccess(&ra2)
+		expected.AddAccessObjects(ra2)
 
 		t.Run("Roundtrip", func(t *testing.T) {
 			datatypeRoundtrip(t, src, &expected)
 		})
 	})
+	t.Run("Access References", func(t *testing.T) {
+		var ra gnap.ResourceAccess
+		ra.SetType("photo-api")
+		ra.AddActions("read")
+
+		t.Run("AccessTokenRequest", func(t *testing.T) {
+			const src = `{"access":["dolphin-metadata",{"actions":["read"],"type":"photo-api"}]}`
+			var expected gnap.AccessTokenRequest
+			expected.AddAccessReferences("dolphin-metadata")
+			expected.AddAccessObjects(ra)
+
+			t.Run("Roundtrip", func(t *testing.T) {
+				datatypeRoundtrip(t, src, &expected)
+			})
+		})
+		t.Run("AccessToken", func(t *testing.T) {
+			const src = `{"access":[{"actions":["read"],"type":"photo-api"},"dolphin-metadata"],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}`
+			expected := gnap.NewAccessToken(gnap.NewAccessEntry(ra), "OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0")
+			expected.AddAccessReferences("dolphin-metadata")
+
+			if !t.Run("Roundtrip", func(t *testing.T) {
+				datatypeRoundtrip(t, src, expected)
+			}) {
+				return
+			}
+
+			entries := expected.Access()
+			if !assert.False(t, entries[0].IsReference(), `first entry should be an object`) {
+				return
+			}
+			if !assert.True(t, entries[1].IsReference(), `second entry should be a reference`) {
+				return
+			}
+			if !assert.Equal(t, []gnap.ResourceAccess{ra}, gnap.AccessObjects(entries), `objects should match`) {
+				return
+			}
+			if !assert.Equal(t, []gnap.ResourceAccessReference{"dolphin-metadata"}, gnap.AccessReferences(entries), `references should match`) {
+				return
+			}
+		})
+		t.Run("Empty Entry", func(t *testing.T) {
+			var entry gnap.AccessEntry
+			if !assert.False(t, entry.IsReference(), `zero value should not be a reference`) {
+				return
+			}
+			if _, err := json.Marshal(entry); !assert.Error(t, err, `json.Marshal should fail`) {
+				return
+			}
+			if !assert.Error(t, json.Unmarshal([]byte(`""`), &entry), `json.Unmarshal should fail`) {
+				return
+			}
+		})
+	})
 	t.Run("Client", func(t *testing.T) {
 		t.Run("String", func(t *testing.T) {
 			const src = `"client-541-ab"`
@@ -112,6 +359,123 @@ func TestDataTypes(t *testing.T) {
 			})
 
 		})
+		t.Run("Display", func(t *testing.T) {
+			const src = `{"display":{"logo_uri":"https://example.net/client/logo.png","name":"My Client Display Name","uri":"https://example.net/client"},"key":"7C7C4AZ9KHRS6X63AJAO"}`
+
+			display := gnap.NewClientDisplay()
+			display.SetName("My Client Display Name")
+			display.SetURI("https://example.net/client")
+			display.SetLogoURI("https://example.net/client/logo.png")
+
+			expected := gnap.NewClient(*gnap.NewKeyReference("7C7C4AZ9KHRS6X63AJAO"))
+			expected.SetDisplay(display)
+
+			t.Run("Roundtrip", func(t *testing.T) {
+				datatypeRoundtrip(t, src, expected)
+			})
+		})
+		t.Run("Key Reference", func(t *testing.T) {
+			const src = `{"key":"7C7C4AZ9KHRS6X63AJAO"}`
+
+			expected := gnap.NewClient(*gnap.NewKeyReference("7C7C4AZ9KHRS6X63AJAO"))
+
+			t.Run("Roundtrip", func(t *testing.T) {
+				datatypeRoundtrip(t, src, expected)
+			})
+			if !assert.True(t, expected.Key().IsReference(), `key should be a reference`) {
+				return
+			}
+		})
+		t.Run("Key Object", func(t *testing.T) {
+			const src = `{"key":{"cert":"MIIEHDCCAwSgAwIBAgIBATANBgkqhkiG9w0BAQsFADA","proof":"mtls"}}`
+
+			var key gnap.Key
+			key.SetCert("MIIEHDCCAwSgAwIBAgIBATANBgkqhkiG9w0BAQsFADA")
+			form := gnap.MutualTLS
+			key.SetProof(&form)
+			expected := gnap.NewClient(key)
+
+			t.Run("Roundtrip", func(t *testing.T) {
+				datatypeRoundtrip(t, src, expected)
+			})
+			if !assert.False(t, expected.Key().IsReference(), `key should not be a reference`) {
+				return
+			}
+		})
+		t.Run("Key Reference In Object", func(t *testing.T) {
+			var client gnap.Client
+			if !assert.Error(t, json.Unmarshal([]byte(`{"key":{"ref":"7C7C4AZ9KHRS6X63AJAO"}}`), &client), `json.Unmarshal should fail`) {
+				return
+			}
+			if !assert.Error(t, json.Unmarshal([]byte(`{"key":{"ref":"7C7C4AZ9KHRS6X63AJAO","proof":"httpsig"}}`), &client), `json.Unmarshal should fail`) {
+				return
+			}
+		})
+		t.Run("Key Reference With Key Material", func(t *testing.T) {
+			key := gnap.NewKeyReference("7C7C4AZ9KHRS6X63AJAO")
+			key.SetCert("MIIEHDCCAwSgAwIBAgIBATANBgkqhkiG9w0BAQsFADA")
+
+			if !assert.Error(t, key.Validate(), `Validate should fail`) {
+				return
+			}
+			if _, err := json.Marshal(key); !assert.Error(t, err, `json.Marshal should fail`) {
+				return
+			}
+		})
+	})
+	t.Run("Symmetric Key", func(t *testing.T) {
+		secret, err := jwk.New([]byte(`supersecret-shared-with-the-server`))
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+
+		var key gnap.Key
+		key.SetJWK(secret)
+		form := gnap.HTTPSig
+		key.SetProof(&form)
+
+		var token gnap.AccessToken
+		token.SetValue(`OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0`)
+		token.SetKey(secret)
+
+		for _, v := range []interface{}{gnap.NewClient(key), &token} {
+			buf, err := json.Marshal(v)
+			if !assert.Error(t, err, `json.Marshal should fail`) {
+				return
+			}
+			if !assert.NotContains(t, string(buf), `"k"`, `key material should not be encoded`) {
+				return
+			}
+		}
+	})
+	t.Run("Error", func(t *testing.T) {
+		t.Run("Object", func(t *testing.T) {
+			const src = `{"code":"too_fast","description":"Slow down"}`
+
+			expected := gnap.NewError(gnap.TooFast)
+			expected.SetDescription("Slow down")
+
+			t.Run("Roundtrip", func(t *testing.T) {
+				datatypeRoundtrip(t, src, expected)
+			})
+		})
+		t.Run("Legacy String", func(t *testing.T) {
+			var res gnap.GrantResponse
+			if !assert.NoError(t, json.Unmarshal([]byte(`{"error":"user_denied"}`), &res), `json.Unmarshal should succeed`) {
+				return
+			}
+			if !assert.Equal(t, gnap.UserDenied, res.Error().Code(), `error code should match`) {
+				return
+			}
+
+			buf, err := json.Marshal(res)
+			if !assert.NoError(t, err, `json.Marshal should succeed`) {
+				return
+			}
+			if !assert.Equal(t, `{"error":{"code":"user_denied"}}`, string(buf), `error should be encoded as an object`) {
+				return
+			}
+		})
 	})
 	t.Run("InteractionFinish", func(t *testing.T) {
 		const src = `{"method":"redirect","nonce":"LKLTI25DK82FX4T4QFZC","uri":"https://client.example.net/return/123455"}`
@@ -125,6 +489,115 @@ func TestDataTypes(t *testing.T) {
 			datatypeRoundtrip(t, src, &expected)
 		})
 	})
+	t.Run("InteractionResponse", func(t *testing.T) {
+		const src = `{"finish":"MBDOFXG4Y5CVJCX821LH","user_code":{"code":"A1BC-3DFF","url":"https://srv.ex/device"}}`
+
+		uc := gnap.NewUserCode("A1BC-3DFF")
+		uc.SetURL("https://srv.ex/device")
+
+		var expected gnap.InteractionResponse
+		expected.SetFinish("MBDOFXG4Y5CVJCX821LH")
+		expected.SetUserCode(uc)
+
+		t.Run("Roundtrip", func(t *testing.T) {
+			datatypeRoundtrip(t, src, &expected)
+		})
+	})
+	t.Run("SubjectRequest", func(t *testing.T) {
+		const src = `{"assertion_formats":["id_token","saml2"],"sub_id_formats":["iss_sub","opaque"]}`
+
+		var expected gnap.SubjectRequest
+		expected.AddSubIDFormats(gnap.SubIDIssSub, gnap.SubIDOpaque)
+		expected.AddAssertionFormats(gnap.AssertionIDToken, gnap.AssertionSAML2)
+
+		t.Run("Roundtrip", func(t *testing.T) {
+			datatypeRoundtrip(t, src, &expected)
+		})
+	})
+	t.Run("GrantResponse with Subject", func(t *testing.T) {
+		const src = `{"subject":{"assertions":[{"format":"id_token","value":"eyj..."}],"sub_ids":[{"format":"opaque","id":"XUT2MFM1XBIKJKSDU8QM"},{"format":"iss_sub","iss":"https://as.example.com","sub":"248289761001"}],"updated_at":"2020-09-21T17:49:14Z"}}`
+
+		var subject gnap.SubjectResponse
+		subject.AddSubIDs(*gnap.NewOpaqueSubjectID("XUT2MFM1XBIKJKSDU8QM"), *gnap.NewIssSubSubjectID("https://as.example.com", "248289761001"))
+		subject.AddAssertions(*gnap.NewAssertion(gnap.AssertionIDToken, "eyj..."))
+		subject.SetUpdatedAt("2020-09-21T17:49:14Z")
+
+		var expected gnap.GrantResponse
+		expected.SetSubject(&subject)
+
+		if !t.Run("Roundtrip", func(t *testing.T) {
+			datatypeRoundtrip(t, src, &expected)
+		}) {
+			return
+		}
+
+		if !assert.Equal(t, "248289761001", expected.Subject().SubjectID(gnap.SubIDIssSub).Sub(), `iss_sub identifier should be found`) {
+			return
+		}
+		if !assert.Equal(t, "eyj...", expected.Subject().Assertion(gnap.AssertionIDToken).Value(), `id_token assertion should be found`) {
+			return
+		}
+		if !assert.Nil(t, expected.Subject().SubjectID(gnap.SubIDEmail), `email identifier should not be found`) {
+			return
+		}
+	})
+	t.Run("GrantResponse with Single Token Array", func(t *testing.T) {
+		const src = `{"access_token":[{"access":["photos"],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}]}`
+
+		var expected gnap.GrantResponse
+		expected.AddAccessTokens(gnap.NewAccessToken(gnap.NewAccessReference("photos"), "OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"))
+		expected.SetMultipleAccessTokens(true)
+
+		if !t.Run("Roundtrip", func(t *testing.T) {
+			datatypeRoundtrip(t, src, &expected)
+		}) {
+			return
+		}
+
+		expected.SetAccessToken(expected.AccessToken())
+		buf, err := json.Marshal(expected)
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		if !assert.Equal(t, `{"access_token":{"access":["photos"],"value":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0"}}`, string(buf), `SetAccessToken should produce a single object`) {
+			return
+		}
+	})
+	t.Run("SubjectIdentifier", func(t *testing.T) {
+		testcases := []struct {
+			Name  string
+			ID    *gnap.SubjectIdentifier
+			Error bool
+		}{
+			{Name: "opaque", ID: gnap.NewOpaqueSubjectID("XUT2MFM1XBIKJKSDU8QM")},
+			{Name: "email", ID: gnap.NewEmailSubjectID("user@example.com")},
+			{Name: "iss_sub", ID: gnap.NewIssSubSubjectID("https://as.example.com", "248289761001")},
+			{Name: "did", ID: gnap.NewDIDSubjectID("did:example:123456")},
+			{Name: "opaque without id", ID: gnap.NewSubjectIdentifier(gnap.SubIDOpaque), Error: true},
+			{Name: "email without email", ID: gnap.NewSubjectIdentifier(gnap.SubIDEmail), Error: true},
+			{Name: "iss_sub without sub", ID: func() *gnap.SubjectIdentifier {
+				v := gnap.NewSubjectIdentifier(gnap.SubIDIssSub)
+				v.SetIss("https://as.example.com")
+				return v
+			}(), Error: true},
+		}
+
+		for _, tc := range testcases {
+			tc := tc
+			t.Run(tc.Name, func(t *testing.T) {
+				err := tc.ID.Validate()
+				if tc.Error {
+					if !assert.Error(t, err, `Validate should fail`) {
+						return
+					}
+					return
+				}
+				if !assert.NoError(t, err, `Validate should succeed`) {
+					return
+				}
+			})
+		}
+	})
 	t.Run("ResourceAccess", func(t *testing.T) {
 		const src = `{"actions":["read"],"datatypes":["file"],"extra":"foo","identifier":"gnap.go","locations":["https://github.com/lestrrat-go/gnap"],"type":"sourcecode"}`
 
@@ -144,3 +617,95 @@ func TestDataTypes(t *testing.T) {
 		})
 	})
 }
+
+func TestInteractionHash(t *testing.T) {
+	const (
+		clientNonce   = `VJLO6A4CAYLBXHTR0KRO`
+		serverNonce   = `MBDOFXG4Y5CVJCX821LH`
+		interactRef   = `4IFWWIKYBC2PQ6U56NL1`
+		grantEndpoint = `https://server.example.com/tx`
+	)
+
+	for _, method := range []string{"", gnap.HashSHA3_512, gnap.HashSHA256, gnap.HashSHA512} {
+		method := method
+		t.Run(method, func(t *testing.T) {
+			hash, err := gnap.CalculateInteractionHash(method, clientNonce, serverNonce, interactRef, grantEndpoint)
+			if !assert.NoError(t, err, `CalculateInteractionHash should succeed`) {
+				return
+			}
+			if !assert.NoError(t, gnap.VerifyInteractionHash(hash, method, clientNonce, serverNonce, interactRef, grantEndpoint), `VerifyInteractionHash should succeed`) {
+				return
+			}
+			if !assert.Error(t, gnap.VerifyInteractionHash(hash, method, clientNonce, serverNonce, `tampered`, grantEndpoint), `VerifyInteractionHash should fail`) {
+				return
+			}
+		})
+	}
+	t.Run("SHA-256 Value", func(t *testing.T) {
+		hash, err := gnap.CalculateInteractionHash(gnap.HashSHA256, clientNonce, serverNonce, interactRef, grantEndpoint)
+		if !assert.NoError(t, err, `CalculateInteractionHash should succeed`) {
+			return
+		}
+		if !assert.Equal(t, `jdHcrti02HLCwGU3qhUZ3wZXt8IjrV_BtE3oUyOuKNk`, hash, `hash should match`) {
+			return
+		}
+	})
+	t.Run("Unsupported Method", func(t *testing.T) {
+		_, err := gnap.CalculateInteractionHash(`md5`, clientNonce, serverNonce, interactRef, grantEndpoint)
+		if !assert.Error(t, err, `CalculateInteractionHash should fail`) {
+			return
+		}
+		if !assert.False(t, gnap.IsSupportedHashMethod(`md5`), `md5 should not be supported`) {
+			return
+		}
+	})
+	t.Run("Supported Methods", func(t *testing.T) {
+		for _, method := range []string{"", gnap.HashSHA3_512, gnap.HashSHA256, gnap.HashSHA512} {
+			if !assert.True(t, gnap.IsSupportedHashMethod(method), `%q should be supported`, method) {
+				return
+			}
+		}
+	})
+}
+
+func TestGrantRequestValidation(t *testing.T) {
+	newAccessToken := func(label string) *gnap.AccessTokenRequest {
+		var ra gnap.ResourceAccess
+		ra.SetType("photo-api")
+
+		atr := gnap.NewAccessTokenRequest().AddAccessObjects(ra)
+		if label != "" {
+			atr.SetLabel(label)
+		}
+		return atr
+	}
+
+	t.Run("Single Unlabeled Token", func(t *testing.T) {
+		var req gnap.GrantRequest
+		req.AddAccessTokens(newAccessToken(""))
+		if !assert.NoError(t, req.Validate(), `Validate should succeed`) {
+			return
+		}
+	})
+	t.Run("Multiple Labeled Tokens", func(t *testing.T) {
+		var req gnap.GrantRequest
+		req.AddAccessTokens(newAccessToken("token1"), newAccessToken("token2"))
+		if !assert.NoError(t, req.Validate(), `Validate should succeed`) {
+			return
+		}
+	})
+	t.Run("Multiple Unlabeled Tokens", func(t *testing.T) {
+		var req gnap.GrantRequest
+		req.AddAccessTokens(newAccessToken(""), newAccessToken(""))
+		if !assert.Error(t, req.Validate(), `Validate should fail`) {
+			return
+		}
+	})
+	t.Run("Multiple Tokens With One Label", func(t *testing.T) {
+		var req gnap.GrantRequest
+		req.AddAccessTokens(newAccessToken("token1"), newAccessToken(""))
+		if !assert.Error(t, req.Validate(), `Validate should fail`) {
+			return
+		}
+	})
+}
@@ -11,4 +11,5 @@ require (
 	github.com/lestrrat-go/xstrings v0.0.0-20210218230845-c71072c00975
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/crypto v0.0.0-20201217014255-9d1352758620
 )
@@ -0,0 +1,15 @@
+package gnap
+
+// MultipleAccessTokens returns true if the request asks for multiple
+// access tokens, that is if `access_token` is an array. This is the
+// case when the array form was decoded, even if it held a single
+// element, or when SetMultipleAccessTokens(true) was called
+func (c *GrantRequest) MultipleAccessTokens() bool {
+	return c.accessTokensArray || len(c.accessTokens) > 1
+}
+
+// SetMultipleAccessTokens specifies whether `access_token` is encoded
+// as an array even when the request holds a single access token
+func (c *GrantRequest) SetMultipleAccessTokens(v bool) {
+	c.accessTokensArray = v
+}
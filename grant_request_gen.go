@@ -12,12 +12,14 @@ import (
 )
 
 type GrantRequest struct {
-	accessTokens []*AccessTokenRequest
-	capabilities []string
-	client       *Client
-	interact     *InteractionRequest
-	subject      *SubjectRequest
-	extraFields  map[string]interface{}
+	accessTokens      []*AccessTokenRequest
+	capabilities      []string
+	client            *Client
+	interact          *InteractionRequest
+	subject           *SubjectRequest
+	user              *User
+	extraFields       map[string]interface{}
+	accessTokensArray bool
 }
 
 func NewGrantRequest() *GrantRequest {
@@ -25,7 +27,7 @@ func NewGrantRequest() *GrantRequest {
 }
 
 func (c *GrantRequest) Validate() error {
-	if len(c.accessTokens) > 0 {
+	if len(c.accessTokens) > 1 {
 		for _, access := range c.accessTokens {
 			if access.label == nil {
 				return errors.Errorf(`"label" is required in "access" field for multiple access token requests (2.1.1)`)
@@ -62,6 +64,11 @@ func (c *GrantRequest) Get(key string) (interface{}, bool) {
 			return nil, false
 		}
 		return c.subject, true
+	case "user":
+		if c.user == nil {
+			return nil, false
+		}
+		return c.user, true
 	default:
 		if c.extraFields == nil {
 			return nil, false
@@ -103,6 +110,12 @@ func (c *GrantRequest) Set(key string, value interface{}) error {
 		} else {
 			return errors.Errorf(`invalid type for "subject" (%T)`, value)
 		}
+	case "user":
+		if v, ok := value.(*User); ok {
+			c.user = v
+		} else {
+			return errors.Errorf(`invalid type for "user" (%T)`, value)
+		}
 	default:
 		if c.extraFields == nil {
 			c.extraFields = make(map[string]interface{})
@@ -154,6 +167,14 @@ func (c *GrantRequest) Subject() *SubjectRequest {
 	return c.subject
 }
 
+func (c *GrantRequest) SetUser(v *User) {
+	c.user = v
+}
+
+func (c *GrantRequest) User() *User {
+	return c.user
+}
+
 func (c GrantRequest) MarshalJSON() ([]byte, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -172,7 +193,7 @@ func (c GrantRequest) MarshalJSON() ([]byte, error) {
 		switch pair.Key.(string) {
 		case "access_token":
 			v := pair.Value.([]*AccessTokenRequest)
-			if len(v) == 1 {
+			if len(v) == 1 && !c.accessTokensArray {
 				pair.Value = v[0]
 			}
 		}
@@ -186,10 +207,12 @@ func (c GrantRequest) MarshalJSON() ([]byte, error) {
 
 func (c *GrantRequest) UnmarshalJSON(data []byte) error {
 	c.accessTokens = nil
+	c.accessTokensArray = false
 	c.capabilities = nil
 	c.client = nil
 	c.interact = nil
 	c.subject = nil
+	c.user = nil
 	dec := json.NewDecoder(bytes.NewReader(data))
 	tok, err := dec.Token()
 	if err != nil {
@@ -226,6 +249,7 @@ LOOP:
 					if err := json.Unmarshal(nextThing, &(c.accessTokens)); err != nil {
 						return errors.Wrap(err, `error decoding access_token`)
 					}
+					c.accessTokensArray = true
 				} else {
 					var tmp AccessTokenRequest
 					if err := json.Unmarshal(nextThing, &tmp); err != nil {
@@ -249,6 +273,10 @@ LOOP:
 				if err := dec.Decode(&(c.subject)); err != nil {
 					return errors.Wrap(err, `error reading subject`)
 				}
+			case "user":
+				if err := dec.Decode(&(c.user)); err != nil {
+					return errors.Wrap(err, `error reading user`)
+				}
 			default:
 				var tmp interface{}
 				if err := dec.Decode(&tmp); err != nil {
@@ -281,6 +309,9 @@ func (c *GrantRequest) makePairs() []*mapiter.Pair {
 	if tmp := c.subject; tmp != nil {
 		pairs = append(pairs, &mapiter.Pair{Key: "subject", Value: *tmp})
 	}
+	if tmp := c.user; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "user", Value: *tmp})
+	}
 	var extraKeys []string
 	for k := range c.extraFields {
 		extraKeys = append(extraKeys, k)
@@ -0,0 +1,32 @@
+package gnap
+
+// SetAccessToken replaces the access tokens in the response with the
+// single token `v`, which is encoded as a single object
+func (c *GrantResponse) SetAccessToken(v *AccessToken) {
+	c.accessTokens = []*AccessToken{v}
+	c.accessTokensArray = false
+}
+
+// AccessToken returns the first access token in the response, or nil
+// if there is none. Use AccessTokens when more than one token was
+// requested
+func (c *GrantResponse) AccessToken() *AccessToken {
+	if len(c.accessTokens) == 0 {
+		return nil
+	}
+	return c.accessTokens[0]
+}
+
+// MultipleAccessTokens returns true if `access_token` is an array,
+// either because the array form was decoded or because
+// SetMultipleAccessTokens(true) was called
+func (c *GrantResponse) MultipleAccessTokens() bool {
+	return c.accessTokensArray || len(c.accessTokens) > 1
+}
+
+// SetMultipleAccessTokens specifies whether `access_token` is encoded
+// as an array even when the response holds a single access token. The
+// response to a request for multiple access tokens uses the array form
+func (c *GrantResponse) SetMultipleAccessTokens(v bool) {
+	c.accessTokensArray = v
+}
@@ -12,11 +12,13 @@ import (
 )
 
 type GrantResponse struct {
-	accessToken  *AccessToken
-	continuation *RequestContinuation
-	error        *string
-	interact     *InteractionResponse
-	extraFields  map[string]interface{}
+	accessTokens      []*AccessToken
+	continuation      *RequestContinuation
+	error             *Error
+	interact          *InteractionResponse
+	subject           *SubjectResponse
+	extraFields       map[string]interface{}
+	accessTokensArray bool
 }
 
 func NewGrantResponse() *GrantResponse {
@@ -30,10 +32,10 @@ func (c *GrantResponse) Validate() error {
 func (c *GrantResponse) Get(key string) (interface{}, bool) {
 	switch key {
 	case "access_token":
-		if c.accessToken == nil {
+		if len(c.accessTokens) == 0 {
 			return nil, false
 		}
-		return c.accessToken, true
+		return c.accessTokens, true
 	case "continue":
 		if c.continuation == nil {
 			return nil, false
@@ -49,6 +51,11 @@ func (c *GrantResponse) Get(key string) (interface{}, bool) {
 			return nil, false
 		}
 		return c.interact, true
+	case "subject":
+		if c.subject == nil {
+			return nil, false
+		}
+		return c.subject, true
 	default:
 		if c.extraFields == nil {
 			return nil, false
@@ -61,8 +68,8 @@ func (c *GrantResponse) Get(key string) (interface{}, bool) {
 func (c *GrantResponse) Set(key string, value interface{}) error {
 	switch key {
 	case "access_token":
-		if v, ok := value.(*AccessToken); ok {
-			c.accessToken = v
+		if v, ok := value.([]*AccessToken); ok {
+			c.accessTokens = v
 		} else {
 			return errors.Errorf(`invalid type for "access_token" (%T)`, value)
 		}
@@ -73,10 +80,8 @@ func (c *GrantResponse) Set(key string, value interface{}) error {
 			return errors.Errorf(`invalid type for "continue" (%T)`, value)
 		}
 	case "error":
-		if v, ok := value.(string); ok {
-			c.error = &v
-		} else if value == nil {
-			c.error = nil
+		if v, ok := value.(*Error); ok {
+			c.error = v
 		} else {
 			return errors.Errorf(`invalid type for "error" (%T)`, value)
 		}
@@ -86,6 +91,12 @@ func (c *GrantResponse) Set(key string, value interface{}) error {
 		} else {
 			return errors.Errorf(`invalid type for "interact" (%T)`, value)
 		}
+	case "subject":
+		if v, ok := value.(*SubjectResponse); ok {
+			c.subject = v
+		} else {
+			return errors.Errorf(`invalid type for "subject" (%T)`, value)
+		}
 	default:
 		if c.extraFields == nil {
 			c.extraFields = make(map[string]interface{})
@@ -95,12 +106,13 @@ func (c *GrantResponse) Set(key string, value interface{}) error {
 	return nil
 }
 
-func (c *GrantResponse) SetAccessToken(v *AccessToken) {
-	c.accessToken = v
+func (c *GrantResponse) AddAccessTokens(v ...*AccessToken) *GrantResponse {
+	c.accessTokens = append(c.accessTokens, v...)
+	return c
 }
 
-func (c *GrantResponse) AccessToken() *AccessToken {
-	return c.accessToken
+func (c *GrantResponse) AccessTokens() []*AccessToken {
+	return c.accessTokens
 }
 
 func (c *GrantResponse) SetContinue(v *RequestContinuation) {
@@ -111,15 +123,12 @@ func (c *GrantResponse) Continue() *RequestContinuation {
 	return c.continuation
 }
 
-func (c *GrantResponse) SetError(v string) {
-	c.error = &v
+func (c *GrantResponse) SetError(v *Error) {
+	c.error = v
 }
 
-func (c *GrantResponse) Error() string {
-	if c.error == nil {
-		return ""
-	}
-	return *(c.error)
+func (c *GrantResponse) Error() *Error {
+	return c.error
 }
 
 func (c *GrantResponse) SetInteract(v *InteractionResponse) {
@@ -130,6 +139,14 @@ func (c *GrantResponse) Interact() *InteractionResponse {
 	return c.interact
 }
 
+func (c *GrantResponse) SetSubject(v *SubjectResponse) {
+	c.subject = v
+}
+
+func (c *GrantResponse) Subject() *SubjectResponse {
+	return c.subject
+}
+
 func (c GrantResponse) MarshalJSON() ([]byte, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -145,6 +162,13 @@ func (c GrantResponse) MarshalJSON() ([]byte, error) {
 		i++
 		buf.WriteString(strconv.Quote(pair.Key.(string)))
 		buf.WriteByte(':')
+		switch pair.Key.(string) {
+		case "access_token":
+			v := pair.Value.([]*AccessToken)
+			if len(v) == 1 && !c.accessTokensArray {
+				pair.Value = v[0]
+			}
+		}
 		if err := enc.Encode(pair.Value); err != nil {
 			return nil, errors.Wrapf(err, `failed to encode %s`, pair.Key.(string))
 		}
@@ -154,10 +178,12 @@ func (c GrantResponse) MarshalJSON() ([]byte, error) {
 }
 
 func (c *GrantResponse) UnmarshalJSON(data []byte) error {
-	c.accessToken = nil
+	c.accessTokens = nil
+	c.accessTokensArray = false
 	c.continuation = nil
 	c.error = nil
 	c.interact = nil
+	c.subject = nil
 	dec := json.NewDecoder(bytes.NewReader(data))
 	tok, err := dec.Token()
 	if err != nil {
@@ -186,23 +212,38 @@ LOOP:
 		case string:
 			switch tok {
 			case "access_token":
-				if err := dec.Decode(&(c.accessToken)); err != nil {
-					return errors.Wrap(err, `error reading access_token`)
+				var nextThing json.RawMessage
+				if err := dec.Decode(&nextThing); err != nil {
+					return errors.Wrap(err, `error reading next token access_token`)
+				}
+				if bytes.HasPrefix(nextThing, []byte{'['}) {
+					if err := json.Unmarshal(nextThing, &(c.accessTokens)); err != nil {
+						return errors.Wrap(err, `error decoding access_token`)
+					}
+					c.accessTokensArray = true
+				} else {
+					var tmp AccessToken
+					if err := json.Unmarshal(nextThing, &tmp); err != nil {
+						return errors.Wrap(err, `error reading access_token`)
+					}
+					c.accessTokens = append(c.accessTokens, &tmp)
 				}
 			case "continue":
 				if err := dec.Decode(&(c.continuation)); err != nil {
 					return errors.Wrap(err, `error reading continue`)
 				}
 			case "error":
-				var tmp string
-				if err := dec.Decode(&tmp); err != nil {
+				if err := dec.Decode(&(c.error)); err != nil {
 					return errors.Wrap(err, `error reading error`)
 				}
-				c.error = &tmp
 			case "interact":
 				if err := dec.Decode(&(c.interact)); err != nil {
 					return errors.Wrap(err, `error reading interact`)
 				}
+			case "subject":
+				if err := dec.Decode(&(c.subject)); err != nil {
+					return errors.Wrap(err, `error reading subject`)
+				}
 			default:
 				var tmp interface{}
 				if err := dec.Decode(&tmp); err != nil {
@@ -220,8 +261,8 @@ LOOP:
 
 func (c *GrantResponse) makePairs() []*mapiter.Pair {
 	var pairs []*mapiter.Pair
-	if tmp := c.accessToken; tmp != nil {
-		pairs = append(pairs, &mapiter.Pair{Key: "access_token", Value: *tmp})
+	if tmp := c.accessTokens; len(tmp) > 0 {
+		pairs = append(pairs, &mapiter.Pair{Key: "access_token", Value: tmp})
 	}
 	if tmp := c.continuation; tmp != nil {
 		pairs = append(pairs, &mapiter.Pair{Key: "continue", Value: *tmp})
@@ -232,6 +273,9 @@ func (c *GrantResponse) makePairs() []*mapiter.Pair {
 	if tmp := c.interact; tmp != nil {
 		pairs = append(pairs, &mapiter.Pair{Key: "interact", Value: *tmp})
 	}
+	if tmp := c.subject; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "subject", Value: *tmp})
+	}
 	var extraKeys []string
 	for k := range c.extraFields {
 		extraKeys = append(extraKeys, k)
@@ -0,0 +1,79 @@
+package gnap
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"hash"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/sha3"
+)
+
+// Hash methods that can be specified in the `hash_method` field of
+// InteractionFinish
+const (
+	HashSHA3_512 = "sha3-512"
+	HashSHA256   = "sha-256"
+	HashSHA512   = "sha-512"
+
+	// DefaultHashMethod is the hash method used when `hash_method`
+	// is not specified
+	DefaultHashMethod = HashSHA3_512
+)
+
+// IsSupportedHashMethod returns true if `method` can be used to
+// calculate the interaction hash. The empty string stands for
+// DefaultHashMethod, and is supported.
+func IsSupportedHashMethod(method string) bool {
+	switch method {
+	case "", HashSHA3_512, HashSHA256, HashSHA512:
+		return true
+	default:
+		return false
+	}
+}
+
+func newHash(method string) (hash.Hash, error) {
+	switch method {
+	case "", HashSHA3_512:
+		return sha3.New512(), nil
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashSHA512:
+		return sha512.New(), nil
+	default:
+		return nil, errors.Errorf(`unsupported hash method %q`, method)
+	}
+}
+
+// CalculateInteractionHash computes the `hash` value that the
+// authorization server sends to the client when the interaction
+// finishes. The hash is calculated over the client nonce, the server
+// nonce, the interaction reference, and the grant endpoint URI, using
+// `method`. If `method` is empty, DefaultHashMethod is used.
+func CalculateInteractionHash(method, clientNonce, serverNonce, interactRef, grantEndpoint string) (string, error) {
+	h, err := newHash(method)
+	if err != nil {
+		return "", err
+	}
+
+	h.Write([]byte(clientNonce + "\n" + serverNonce + "\n" + interactRef + "\n" + grantEndpoint))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyInteractionHash verifies the `hash` value received by the
+// client when the interaction finishes. See CalculateInteractionHash
+// for the description of the parameters.
+func VerifyInteractionHash(hash, method, clientNonce, serverNonce, interactRef, grantEndpoint string) error {
+	expected, err := CalculateInteractionHash(method, clientNonce, serverNonce, interactRef, grantEndpoint)
+	if err != nil {
+		return errors.Wrap(err, `failed to calculate interaction hash`)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(hash)) != 1 {
+		return errors.New(`interaction hash does not match`)
+	}
+	return nil
+}
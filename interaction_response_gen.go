@@ -15,7 +15,7 @@ type InteractionResponse struct {
 	app         *string
 	finish      *string
 	redirect    *string
-	userCode    *string
+	userCode    *UserCode
 	extraFields map[string]interface{}
 }
 
@@ -85,10 +85,8 @@ func (c *InteractionResponse) Set(key string, value interface{}) error {
 			return errors.Errorf(`invalid type for "redirect" (%T)`, value)
 		}
 	case "user_code":
-		if v, ok := value.(string); ok {
-			c.userCode = &v
-		} else if value == nil {
-			c.userCode = nil
+		if v, ok := value.(*UserCode); ok {
+			c.userCode = v
 		} else {
 			return errors.Errorf(`invalid type for "user_code" (%T)`, value)
 		}
@@ -134,15 +132,12 @@ func (c *InteractionResponse) Redirect() string {
 	return *(c.redirect)
 }
 
-func (c *InteractionResponse) SetUserCode(v string) {
-	c.userCode = &v
+func (c *InteractionResponse) SetUserCode(v *UserCode) {
+	c.userCode = v
 }
 
-func (c *InteractionResponse) UserCode() string {
-	if c.userCode == nil {
-		return ""
-	}
-	return *(c.userCode)
+func (c *InteractionResponse) UserCode() *UserCode {
+	return c.userCode
 }
 
 func (c InteractionResponse) MarshalJSON() ([]byte, error) {
@@ -219,11 +214,9 @@ LOOP:
 				}
 				c.redirect = &tmp
 			case "user_code":
-				var tmp string
-				if err := dec.Decode(&tmp); err != nil {
+				if err := dec.Decode(&(c.userCode)); err != nil {
 					return errors.Wrap(err, `error reading user_code`)
 				}
-				c.userCode = &tmp
 			default:
 				var tmp interface{}
 				if err := dec.Decode(&tmp); err != nil {
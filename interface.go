@@ -27,8 +27,9 @@ type AccessReference string
 
 type AccessTokenAttribute string
 const (
-	Bearer AccessTokenAttribute = "bearer"
-	Split  AccessTokenAttribute = "split"
+	Bearer  AccessTokenAttribute = "bearer"
+	Durable AccessTokenAttribute = "durable"
+	Split   AccessTokenAttribute = "split"
 )
 
 type StartMode string
@@ -51,6 +52,23 @@ const (
 
 type ResourceAccessReference string
 
+type SubjectIDFormat string
+const (
+	SubIDAccount     SubjectIDFormat = "account"
+	SubIDAliases     SubjectIDFormat = "aliases"
+	SubIDDID         SubjectIDFormat = "did"
+	SubIDEmail       SubjectIDFormat = "email"
+	SubIDIssSub      SubjectIDFormat = "iss_sub"
+	SubIDOpaque      SubjectIDFormat = "opaque"
+	SubIDPhoneNumber SubjectIDFormat = "phone_number"
+)
+
+type AssertionFormat string
+const (
+	AssertionIDToken AssertionFormat = "id_token"
+	AssertionSAML2   AssertionFormat = "saml2"
+)
+
 type ProofForm string
 const(
 	DetachedJWS ProofForm = "jwsd"
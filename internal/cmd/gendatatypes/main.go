@@ -32,22 +32,38 @@ type datadef struct {
 	// is a field of the otherwise empty object. string value specifies
 	// the field name
 	allowString string
-	clientCmd   bool
+	// stringOnly specifies that the allowString field is only valid in
+	// the string form. It is never combined with other fields, and
+	// objects containing it are rejected
+	stringOnly bool
+	// legacyString is like allowString, but the string form is only
+	// accepted when decoding. It is never produced when encoding
+	legacyString string
+	// postUnmarshal is code that is run after the object has been
+	// decoded, for example to convert fields from older drafts
+	postUnmarshal string
+	// internalFields are declared in the struct as is. They are not
+	// part of the JSON representation
+	internalFields string
+	clientCmd      bool
 }
 
 var types = []*datadef{
 	{
 		name: "AccessToken",
+		extraValidation: "\nif c.HasFlag(Bearer) && c.key != nil {" +
+			"\n  return errors.Errorf(`\"key\" must not be specified for bearer tokens`)" +
+			"\n}",
+		postUnmarshal: "\nc.decodeLegacyFlags()",
+		// legacyBound records a `bound: true` field from older drafts,
+		// which has no flag of its own (see Bound)
+		internalFields: "\nlegacyBound bool",
 		fields: []*fielddef{
 			{
 				name:     "value",
 				required: true,
 				typ:      "*string",
 			},
-			{
-				name: "bound",
-				typ:  "*bool",
-			},
 			{
 				name: "label",
 				typ:  "*string",
@@ -59,7 +75,7 @@ var types = []*datadef{
 			{
 				name:     "access",
 				required: true,
-				typ:      "[]ResourceAccess",
+				typ:      "[]AccessEntry",
 			},
 			{
 				name: "expires_in",
@@ -70,12 +86,8 @@ var types = []*datadef{
 				typ:  "jwk.Key",
 			},
 			{
-				name: "durable",
-				typ:  "*bool",
-			},
-			{
-				name: "split",
-				typ:  "*bool",
+				name: "flags",
+				typ:  "[]AccessTokenAttribute",
 			},
 		},
 	},
@@ -99,10 +111,20 @@ var types = []*datadef{
 			},
 		},
 	},
+	{
+		name: "ContinueRequest",
+		fields: []*fielddef{
+			{
+				name:     "interactRef",
+				jsonname: "interact_ref",
+				typ:      "*string",
+			},
+		},
+	},
 	{
 		name:      "GrantRequest",
 		clientCmd: true,
-		extraValidation: "\nif len(c.accessTokens) > 0 {" +
+		extraValidation: "\nif len(c.accessTokens) > 1 {" +
 			"\n  for _, access := range c.accessTokens {" +
 			"\n    if access.label == nil {" +
 			"\n      return errors.Errorf(`\"label\" is required in \"access\" field for multiple access token requests (2.1.1)`)" +
@@ -132,6 +154,26 @@ var types = []*datadef{
 				name: "subject",
 				typ:  "*SubjectRequest",
 			},
+			{
+				name: "user",
+				typ:  "*User",
+			},
+		},
+	},
+	{
+		name:         "Error",
+		comment:      "Error describes an error returned by the authorization server",
+		legacyString: "code",
+		fields: []*fielddef{
+			{
+				name:     "code",
+				required: true,
+				typ:      "*string",
+			},
+			{
+				name: "description",
+				typ:  "*string",
+			},
 		},
 	},
 	{
@@ -144,8 +186,10 @@ var types = []*datadef{
 				typ:      "*RequestContinuation",
 			},
 			{
-				name: "accessToken",
-				typ:  "*AccessToken",
+				name:        "accessTokens",
+				jsonname:    "access_token",
+				typ:         "[]*AccessToken",
+				allowSingle: true,
 			},
 			{
 				name: "interact",
@@ -153,20 +197,186 @@ var types = []*datadef{
 			},
 			{
 				name: "error",
+				typ:  "*Error",
+			},
+			{
+				name: "subject",
+				typ:  "*SubjectResponse",
+			},
+		},
+	},
+	{
+		name:    "IntrospectionRequest",
+		comment: "IntrospectionRequest is sent by resource servers to the token introspection endpoint",
+		fields: []*fielddef{
+			{
+				name:     "accessToken",
+				required: true,
+				typ:      "*string",
+			},
+			{
+				name: "proof",
+				typ:  "*ProofForm",
+			},
+			{
+				name: "resourceServer",
 				typ:  "*string",
 			},
 		},
 	},
 	{
-		name: "SubjectRequest",
+		name:    "IntrospectionResponse",
+		comment: "IntrospectionResponse describes an access token, as returned by the token introspection endpoint",
 		fields: []*fielddef{
 			{
-				name: "subIDs",
-				typ:  "[]string",
+				name:     "active",
+				required: true,
+				typ:      "*bool",
+			},
+			{
+				name: "access",
+				typ:  "[]AccessEntry",
+			},
+			{
+				name: "key",
+				typ:  "*Key",
+			},
+			{
+				name: "flags",
+				typ:  "[]AccessTokenAttribute",
+			},
+			{
+				name:     "expiresAt",
+				jsonname: "exp",
+				typ:      "*int64",
+			},
+			{
+				name:     "issuedAt",
+				jsonname: "iat",
+				typ:      "*int64",
+			},
+		},
+	},
+	{
+		name:    "SubjectRequest",
+		comment: "SubjectRequest describes the formats of the subject information requested by the client",
+		fields: []*fielddef{
+			{
+				name:     "subIDFormats",
+				pubname:  "SubIDFormats",
+				jsonname: "sub_id_formats",
+				typ:      "[]SubjectIDFormat",
+			},
+			{
+				name: "assertionFormats",
+				typ:  "[]AssertionFormat",
+			},
+		},
+	},
+	{
+		name:    "SubjectResponse",
+		comment: "SubjectResponse holds the subject information returned by the authorization server",
+		fields: []*fielddef{
+			{
+				name:     "subIDs",
+				pubname:  "SubIDs",
+				jsonname: "sub_ids",
+				typ:      "[]SubjectIdentifier",
 			},
 			{
 				name: "assertions",
-				typ:  "[]string",
+				typ:  "[]Assertion",
+			},
+			{
+				name: "updatedAt",
+				typ:  "*string",
+			},
+		},
+	},
+	{
+		name:    "SubjectIdentifier",
+		comment: "SubjectIdentifier identifies a subject. The fields that are used depend on the format",
+		extraValidation: "\nif err := c.validateFormat(); err != nil {" +
+			"\n  return err" +
+			"\n}",
+		fields: []*fielddef{
+			{
+				name:     "format",
+				required: true,
+				typ:      "*SubjectIDFormat",
+			},
+			{
+				name:    "id",
+				pubname: "ID",
+				typ:     "*string",
+			},
+			{
+				name: "email",
+				typ:  "*string",
+			},
+			{
+				name: "iss",
+				typ:  "*string",
+			},
+			{
+				name: "sub",
+				typ:  "*string",
+			},
+			{
+				name: "phoneNumber",
+				typ:  "*string",
+			},
+			{
+				name:    "uri",
+				pubname: "URI",
+				typ:     "*string",
+			},
+			{
+				name:    "url",
+				pubname: "URL",
+				typ:     "*string",
+			},
+			{
+				name: "identifiers",
+				typ:  "[]SubjectIdentifier",
+			},
+		},
+	},
+	{
+		name:        "User",
+		comment:     "User describes the resource owner, as known to the client",
+		allowString: "ref",
+		stringOnly:  true,
+		fields: []*fielddef{
+			{
+				name: "ref",
+				typ:  "*string",
+			},
+			{
+				name:     "subIDs",
+				pubname:  "SubIDs",
+				jsonname: "sub_ids",
+				typ:      "[]SubjectIdentifier",
+			},
+			{
+				name: "assertions",
+				typ:  "[]Assertion",
+			},
+		},
+	},
+	{
+		name:    "Assertion",
+		comment: "Assertion is an identity assertion about the subject, such as an OpenID Connect ID Token",
+		fields: []*fielddef{
+			{
+				name:     "format",
+				required: true,
+				typ:      "*AssertionFormat",
+			},
+			{
+				name:     "value",
+				required: true,
+				typ:      "*string",
 			},
 		},
 	},
@@ -204,6 +414,10 @@ var types = []*datadef{
 				name: "classID",
 				typ:  "*string",
 			},
+			{
+				name: "display",
+				typ:  "*ClientDisplay",
+			},
 		},
 	},
 	{
@@ -225,8 +439,14 @@ var types = []*datadef{
 		},
 	},
 	{
-		name: "Key",
+		name:        "Key",
+		allowString: "ref",
+		stringOnly:  true,
 		fields: []*fielddef{
+			{
+				name: "ref",
+				typ:  "*string",
+			},
 			{
 				name: "proof",
 				typ:  "*ProofForm",
@@ -252,7 +472,7 @@ var types = []*datadef{
 		fields: []*fielddef{
 			{
 				name: "access",
-				typ:  "[]*ResourceAccess",
+				typ:  "[]AccessEntry",
 			},
 			{
 				name: "label",
@@ -334,7 +554,7 @@ var types = []*datadef{
 			},
 			{
 				name: "userCode",
-				typ: "*string",
+				typ:  "*UserCode",
 			},
 		},
 	},
@@ -405,7 +625,7 @@ func _main() error {
 // type name, true if we need to take the pointer of the value
 func intype(v string) (string, bool) {
 	switch v {
-	case "*string", "*FinishMode":
+	case "*string", "*FinishMode", "*SubjectIDFormat", "*AssertionFormat":
 		return strings.TrimPrefix(v, "*"), true
 	}
 	return v, false
@@ -413,12 +633,22 @@ func intype(v string) (string, bool) {
 
 func zeroval(v string) string {
 	switch v {
-	case "string", "FinishMode":
+	case "string", "FinishMode", "SubjectIDFormat", "AssertionFormat":
 		return `""`
 	}
 	return "nil"
 }
 
+// jsonName returns the JSON name of the field `name` of `ddef`
+func jsonName(ddef *datadef, name string) string {
+	for _, fdef := range ddef.fields {
+		if fdef.name == name {
+			return fdef.jsonname
+		}
+	}
+	return name
+}
+
 func qualifyPkg(v string) string {
 	nptr := strings.TrimPrefix(v, "*")
 	var isPtr bool
@@ -518,6 +748,16 @@ func genType(ddef *datadef) error {
 		fmt.Fprintf(&buf, "\n%s %s", fdef.name, fdef.typ)
 	}
 	fmt.Fprintf(&buf, "\nextraFields map[string]interface{}")
+	for _, fdef := range ddef.fields {
+		if fdef.allowSingle {
+			// records that the array form was used, so that a one
+			// element array is not turned into a single object
+			fmt.Fprintf(&buf, "\n%sArray bool", fdef.name)
+		}
+	}
+	if code := ddef.internalFields; code != "" {
+		fmt.Fprintf(&buf, "%s", code)
+	}
 	fmt.Fprintf(&buf, "\n}")
 
 	fmt.Fprintf(&buf, "\n\nfunc New%s(", ddef.name)
@@ -563,6 +803,11 @@ func genType(ddef *datadef) error {
 			fmt.Fprintf(&buf, "\n}")
 		}
 	}
+	if ddef.stringOnly {
+		fmt.Fprintf(&buf, "\nif c.%s != nil && len(c.makePairs()) > 1 {", ddef.allowString)
+		fmt.Fprintf(&buf, "\nreturn errors.Errorf(`field %#v must not be combined with other fields`)", jsonName(ddef, ddef.allowString))
+		fmt.Fprintf(&buf, "\n}")
+	}
 	if code := ddef.extraValidation; code != "" {
 		fmt.Fprintf(&buf, "%s", code)
 	}
@@ -677,6 +922,11 @@ func genType(ddef *datadef) error {
 		fmt.Fprintf(&buf, "\nif len(pairs) == 1 && pairs[0].Key.(string) == %#v {", jsonname)
 		fmt.Fprintf(&buf, "\nreturn []byte(strconv.Quote(pairs[0].Value.(string))), nil")
 		fmt.Fprintf(&buf, "\n}")
+		if ddef.stringOnly {
+			fmt.Fprintf(&buf, "\nif c.%s != nil {", fieldname)
+			fmt.Fprintf(&buf, "\nreturn nil, errors.Errorf(`field %#v must not be combined with other fields`)", jsonname)
+			fmt.Fprintf(&buf, "\n}")
+		}
 		fmt.Fprintf(&buf, "\nfor i, pair := range pairs {")
 	} else {
 		fmt.Fprintf(&buf, "\nvar i int")
@@ -705,7 +955,7 @@ func genType(ddef *datadef) error {
 		for _, fdef := range singles {
 			fmt.Fprintf(&buf, "\ncase %#v:", fdef.jsonname)
 			fmt.Fprintf(&buf, "\nv := pair.Value.(%s)", fdef.typ)
-			fmt.Fprintf(&buf, "\nif len(v) == 1 {")
+			fmt.Fprintf(&buf, "\nif len(v) == 1 && !c.%sArray {", fdef.name)
 			fmt.Fprintf(&buf, "\npair.Value = v[0]")
 			fmt.Fprintf(&buf, "\n}") // end if
 		}
@@ -728,6 +978,9 @@ func genType(ddef *datadef) error {
 		default:
 			fmt.Fprintf(&buf, "\nc.%s = nil", fdef.name)
 		}
+		if fdef.allowSingle {
+			fmt.Fprintf(&buf, "\nc.%sArray = false", fdef.name)
+		}
 	}
 
 	fmt.Fprintf(&buf, "\ndec := json.NewDecoder(bytes.NewReader(data))")
@@ -740,7 +993,11 @@ func genType(ddef *datadef) error {
 	fmt.Fprintf(&buf, "\nif tok != '{' { ")
 	fmt.Fprintf(&buf, "\nreturn errors.Errorf(`expected '{', but got '%%c'`, tok)")
 	fmt.Fprintf(&buf, "\n}")
-	if fieldname := ddef.allowString; fieldname != "" {
+	stringField := ddef.allowString
+	if stringField == "" {
+		stringField = ddef.legacyString
+	}
+	if fieldname := stringField; fieldname != "" {
 		fmt.Fprintf(&buf, "\ncase string:")
 		fmt.Fprintf(&buf, "\nc.%s = &tok", fieldname)
 		fmt.Fprintf(&buf, "\nreturn nil")
@@ -769,6 +1026,10 @@ func genType(ddef *datadef) error {
 	fmt.Fprintf(&buf, "\nswitch tok {")
 	for _, fdef := range ddef.fields {
 		fmt.Fprintf(&buf, "\ncase %s:", strconv.Quote(fdef.jsonname))
+		if ddef.stringOnly && fdef.name == ddef.allowString {
+			fmt.Fprintf(&buf, "\nreturn errors.Errorf(`field %#v is only allowed in the string form`)", fdef.jsonname)
+			continue
+		}
 		switch fdef.typ {
 		case "*string":
 			fmt.Fprintf(&buf, "\nvar tmp string")
@@ -776,6 +1037,17 @@ func genType(ddef *datadef) error {
 			fmt.Fprintf(&buf, "\nreturn errors.Wrap(err, `error reading %s`)", fdef.jsonname)
 			fmt.Fprintf(&buf, "\n}")
 			fmt.Fprintf(&buf, "\nc.%s = &tmp", fdef.name)
+		case "jwk.Key":
+			// jwk.Key is an interface, so it needs to be parsed explicitly
+			fmt.Fprintf(&buf, "\nvar tmp json.RawMessage")
+			fmt.Fprintf(&buf, "\nif err := dec.Decode(&tmp); err != nil {")
+			fmt.Fprintf(&buf, "\nreturn errors.Wrap(err, `error reading %s`)", fdef.jsonname)
+			fmt.Fprintf(&buf, "\n}")
+			fmt.Fprintf(&buf, "\nkey, err := jwk.ParseKey(tmp)")
+			fmt.Fprintf(&buf, "\nif err != nil {")
+			fmt.Fprintf(&buf, "\nreturn errors.Wrap(err, `error parsing %s`)", fdef.jsonname)
+			fmt.Fprintf(&buf, "\n}")
+			fmt.Fprintf(&buf, "\nc.%s = key", fdef.name)
 		default:
 			if !strings.HasPrefix(fdef.typ, "[]") || !fdef.allowSingle {
 				fmt.Fprintf(&buf, "\nif err := dec.Decode(&(c.%s)); err != nil {", fdef.name)
@@ -791,6 +1063,7 @@ func genType(ddef *datadef) error {
 				fmt.Fprintf(&buf, "\nif err := json.Unmarshal(nextThing, &(c.%s)); err != nil {", fdef.name)
 				fmt.Fprintf(&buf, "\nreturn errors.Wrap(err, `error decoding %s`)", fdef.jsonname)
 				fmt.Fprintf(&buf, "\n}")
+				fmt.Fprintf(&buf, "\nc.%sArray = true", fdef.name)
 				fmt.Fprintf(&buf, "\n} else {")
 
 				// either []thing or []*thing. if []*thing, remember we want pointers at the end
@@ -825,6 +1098,9 @@ func genType(ddef *datadef) error {
 	fmt.Fprintf(&buf, "\n}") // end switch (inside case string)
 	fmt.Fprintf(&buf, "\n}") // end switch
 	fmt.Fprintf(&buf, "\n}") // end for
+	if code := ddef.postUnmarshal; code != "" {
+		fmt.Fprintf(&buf, "%s", code)
+	}
 	fmt.Fprintf(&buf, "\nreturn nil")
 	fmt.Fprintf(&buf, "\n}") // end method
 
@@ -844,6 +1120,11 @@ func genType(ddef *datadef) error {
 			fmt.Fprintf(&buf, "\nif tmp := c.%s; tmp != \"\" {", fdef.name)
 			fmt.Fprintf(&buf, "\npairs = append(pairs, &mapiter.Pair{Key: %#v, Value: tmp})", fdef.jsonname)
 			fmt.Fprintf(&buf, "\n}")
+		case fdef.typ == "jwk.Key":
+			// Never serialize private key material
+			fmt.Fprintf(&buf, "\nif tmp := c.%s; tmp != nil {", fdef.name)
+			fmt.Fprintf(&buf, "\npairs = append(pairs, &mapiter.Pair{Key: %#v, Value: publicJWK(tmp)})", fdef.jsonname)
+			fmt.Fprintf(&buf, "\n}")
 		}
 	}
 	fmt.Fprintf(&buf, "\nvar extraKeys []string")
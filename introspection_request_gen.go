@@ -0,0 +1,247 @@
+package gnap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/lestrrat-go/iter/mapiter"
+	"github.com/pkg/errors"
+)
+
+// IntrospectionRequest is sent by resource servers to the token introspection endpoint
+type IntrospectionRequest struct {
+	accessToken    *string
+	proof          *ProofForm
+	resourceServer *string
+	extraFields    map[string]interface{}
+}
+
+func NewIntrospectionRequest(accessToken string) *IntrospectionRequest {
+	return &IntrospectionRequest{
+		accessToken: &accessToken,
+	}
+}
+
+func (c *IntrospectionRequest) Validate() error {
+	if c.accessToken == nil {
+		return errors.Errorf(`field "accessToken" is required`)
+	}
+	return nil
+}
+
+func (c *IntrospectionRequest) Get(key string) (interface{}, bool) {
+	switch key {
+	case "access_token":
+		if c.accessToken == nil {
+			return nil, false
+		}
+		return c.accessToken, true
+	case "proof":
+		if c.proof == nil {
+			return nil, false
+		}
+		return c.proof, true
+	case "resource_server":
+		if c.resourceServer == nil {
+			return nil, false
+		}
+		return c.resourceServer, true
+	default:
+		if c.extraFields == nil {
+			return nil, false
+		}
+		v, ok := c.extraFields[key]
+		return v, ok
+	}
+}
+
+func (c *IntrospectionRequest) Set(key string, value interface{}) error {
+	switch key {
+	case "access_token":
+		if v, ok := value.(string); ok {
+			c.accessToken = &v
+		} else if value == nil {
+			c.accessToken = nil
+		} else {
+			return errors.Errorf(`invalid type for "access_token" (%T)`, value)
+		}
+	case "proof":
+		if v, ok := value.(*ProofForm); ok {
+			c.proof = v
+		} else {
+			return errors.Errorf(`invalid type for "proof" (%T)`, value)
+		}
+	case "resource_server":
+		if v, ok := value.(string); ok {
+			c.resourceServer = &v
+		} else if value == nil {
+			c.resourceServer = nil
+		} else {
+			return errors.Errorf(`invalid type for "resource_server" (%T)`, value)
+		}
+	default:
+		if c.extraFields == nil {
+			c.extraFields = make(map[string]interface{})
+		}
+		c.extraFields[key] = value
+	}
+	return nil
+}
+
+func (c *IntrospectionRequest) SetAccessToken(v string) {
+	c.accessToken = &v
+}
+
+func (c *IntrospectionRequest) AccessToken() string {
+	if c.accessToken == nil {
+		return ""
+	}
+	return *(c.accessToken)
+}
+
+func (c *IntrospectionRequest) SetProof(v *ProofForm) {
+	c.proof = v
+}
+
+func (c *IntrospectionRequest) Proof() *ProofForm {
+	return c.proof
+}
+
+func (c *IntrospectionRequest) SetResourceServer(v string) {
+	c.resourceServer = &v
+}
+
+func (c *IntrospectionRequest) ResourceServer() string {
+	if c.resourceServer == nil {
+		return ""
+	}
+	return *(c.resourceServer)
+}
+
+func (c IntrospectionRequest) MarshalJSON() ([]byte, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	buf.WriteByte('{')
+	var i int
+	for iter := c.Iterate(ctx); iter.Next(ctx); {
+		pair := iter.Pair()
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		i++
+		buf.WriteString(strconv.Quote(pair.Key.(string)))
+		buf.WriteByte(':')
+		if err := enc.Encode(pair.Value); err != nil {
+			return nil, errors.Wrapf(err, `failed to encode %s`, pair.Key.(string))
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (c *IntrospectionRequest) UnmarshalJSON(data []byte) error {
+	c.accessToken = nil
+	c.proof = nil
+	c.resourceServer = nil
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return errors.Wrap(err, `error reading token`)
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok != '{' {
+			return errors.Errorf(`expected '{', but got '%c'`, tok)
+		}
+	default:
+		return errors.Errorf(`expected '{', but got '%c'`, tok)
+	}
+LOOP:
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return errors.Wrap(err, `error reading token`)
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			if tok == '}' { // End of object
+				break LOOP
+			}
+			return errors.Errorf(`unexpected delimiter '%c'`, tok)
+		case string:
+			switch tok {
+			case "access_token":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading access_token`)
+				}
+				c.accessToken = &tmp
+			case "proof":
+				if err := dec.Decode(&(c.proof)); err != nil {
+					return errors.Wrap(err, `error reading proof`)
+				}
+			case "resource_server":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading resource_server`)
+				}
+				c.resourceServer = &tmp
+			default:
+				var tmp interface{}
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrapf(err, `error reading %s`, tok)
+				}
+				if c.extraFields == nil {
+					c.extraFields = map[string]interface{}{}
+				}
+				c.extraFields[tok] = tmp
+			}
+		}
+	}
+	return nil
+}
+
+func (c *IntrospectionRequest) makePairs() []*mapiter.Pair {
+	var pairs []*mapiter.Pair
+	if tmp := c.accessToken; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "access_token", Value: *tmp})
+	}
+	if tmp := c.proof; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "proof", Value: *tmp})
+	}
+	if tmp := c.resourceServer; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "resource_server", Value: *tmp})
+	}
+	var extraKeys []string
+	for k := range c.extraFields {
+		extraKeys = append(extraKeys, k)
+	}
+	for _, k := range extraKeys {
+		pairs = append(pairs, &mapiter.Pair{Key: k, Value: c.extraFields[k]})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.(string) < pairs[j].Key.(string)
+	})
+	return pairs
+}
+
+func (c *IntrospectionRequest) Iterate(ctx context.Context) mapiter.Iterator {
+	pairs := c.makePairs()
+	ch := make(chan *mapiter.Pair, len(pairs))
+	go func(ctx context.Context, ch chan *mapiter.Pair, pairs []*mapiter.Pair) {
+		defer close(ch)
+		for _, pair := range pairs {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- pair:
+			}
+		}
+	}(ctx, ch, pairs)
+	return mapiter.New(ch)
+}
@@ -0,0 +1,319 @@
+package gnap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/lestrrat-go/iter/mapiter"
+	"github.com/pkg/errors"
+)
+
+// IntrospectionResponse describes an access token, as returned by the token introspection endpoint
+type IntrospectionResponse struct {
+	access      []AccessEntry
+	active      *bool
+	expiresAt   *int64
+	flags       []AccessTokenAttribute
+	issuedAt    *int64
+	key         *Key
+	extraFields map[string]interface{}
+}
+
+func NewIntrospectionResponse(active bool) *IntrospectionResponse {
+	return &IntrospectionResponse{
+		active: &active,
+	}
+}
+
+func (c *IntrospectionResponse) Validate() error {
+	if c.active == nil {
+		return errors.Errorf(`field "active" is required`)
+	}
+	return nil
+}
+
+func (c *IntrospectionResponse) Get(key string) (interface{}, bool) {
+	switch key {
+	case "access":
+		if len(c.access) == 0 {
+			return nil, false
+		}
+		return c.access, true
+	case "active":
+		if c.active == nil {
+			return nil, false
+		}
+		return c.active, true
+	case "exp":
+		if c.expiresAt == nil {
+			return nil, false
+		}
+		return c.expiresAt, true
+	case "flags":
+		if len(c.flags) == 0 {
+			return nil, false
+		}
+		return c.flags, true
+	case "iat":
+		if c.issuedAt == nil {
+			return nil, false
+		}
+		return c.issuedAt, true
+	case "key":
+		if c.key == nil {
+			return nil, false
+		}
+		return c.key, true
+	default:
+		if c.extraFields == nil {
+			return nil, false
+		}
+		v, ok := c.extraFields[key]
+		return v, ok
+	}
+}
+
+func (c *IntrospectionResponse) Set(key string, value interface{}) error {
+	switch key {
+	case "access":
+		if v, ok := value.([]AccessEntry); ok {
+			c.access = v
+		} else {
+			return errors.Errorf(`invalid type for "access" (%T)`, value)
+		}
+	case "active":
+		if v, ok := value.(*bool); ok {
+			c.active = v
+		} else {
+			return errors.Errorf(`invalid type for "active" (%T)`, value)
+		}
+	case "exp":
+		if v, ok := value.(*int64); ok {
+			c.expiresAt = v
+		} else {
+			return errors.Errorf(`invalid type for "exp" (%T)`, value)
+		}
+	case "flags":
+		if v, ok := value.([]AccessTokenAttribute); ok {
+			c.flags = v
+		} else {
+			return errors.Errorf(`invalid type for "flags" (%T)`, value)
+		}
+	case "iat":
+		if v, ok := value.(*int64); ok {
+			c.issuedAt = v
+		} else {
+			return errors.Errorf(`invalid type for "iat" (%T)`, value)
+		}
+	case "key":
+		if v, ok := value.(*Key); ok {
+			c.key = v
+		} else {
+			return errors.Errorf(`invalid type for "key" (%T)`, value)
+		}
+	default:
+		if c.extraFields == nil {
+			c.extraFields = make(map[string]interface{})
+		}
+		c.extraFields[key] = value
+	}
+	return nil
+}
+
+func (c *IntrospectionResponse) AddAccess(v ...AccessEntry) *IntrospectionResponse {
+	c.access = append(c.access, v...)
+	return c
+}
+
+func (c *IntrospectionResponse) Access() []AccessEntry {
+	return c.access
+}
+
+func (c *IntrospectionResponse) SetActive(v *bool) {
+	c.active = v
+}
+
+func (c *IntrospectionResponse) Active() *bool {
+	return c.active
+}
+
+func (c *IntrospectionResponse) SetExpiresAt(v *int64) {
+	c.expiresAt = v
+}
+
+func (c *IntrospectionResponse) ExpiresAt() *int64 {
+	return c.expiresAt
+}
+
+func (c *IntrospectionResponse) AddFlags(v ...AccessTokenAttribute) *IntrospectionResponse {
+	c.flags = append(c.flags, v...)
+	return c
+}
+
+func (c *IntrospectionResponse) Flags() []AccessTokenAttribute {
+	return c.flags
+}
+
+func (c *IntrospectionResponse) SetIssuedAt(v *int64) {
+	c.issuedAt = v
+}
+
+func (c *IntrospectionResponse) IssuedAt() *int64 {
+	return c.issuedAt
+}
+
+func (c *IntrospectionResponse) SetKey(v *Key) {
+	c.key = v
+}
+
+func (c *IntrospectionResponse) Key() *Key {
+	return c.key
+}
+
+func (c IntrospectionResponse) MarshalJSON() ([]byte, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	buf.WriteByte('{')
+	var i int
+	for iter := c.Iterate(ctx); iter.Next(ctx); {
+		pair := iter.Pair()
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		i++
+		buf.WriteString(strconv.Quote(pair.Key.(string)))
+		buf.WriteByte(':')
+		if err := enc.Encode(pair.Value); err != nil {
+			return nil, errors.Wrapf(err, `failed to encode %s`, pair.Key.(string))
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (c *IntrospectionResponse) UnmarshalJSON(data []byte) error {
+	c.access = nil
+	c.active = nil
+	c.expiresAt = nil
+	c.flags = nil
+	c.issuedAt = nil
+	c.key = nil
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return errors.Wrap(err, `error reading token`)
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok != '{' {
+			return errors.Errorf(`expected '{', but got '%c'`, tok)
+		}
+	default:
+		return errors.Errorf(`expected '{', but got '%c'`, tok)
+	}
+LOOP:
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return errors.Wrap(err, `error reading token`)
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			if tok == '}' { // End of object
+				break LOOP
+			}
+			return errors.Errorf(`unexpected delimiter '%c'`, tok)
+		case string:
+			switch tok {
+			case "access":
+				if err := dec.Decode(&(c.access)); err != nil {
+					return errors.Wrap(err, `error reading access`)
+				}
+			case "active":
+				if err := dec.Decode(&(c.active)); err != nil {
+					return errors.Wrap(err, `error reading active`)
+				}
+			case "exp":
+				if err := dec.Decode(&(c.expiresAt)); err != nil {
+					return errors.Wrap(err, `error reading exp`)
+				}
+			case "flags":
+				if err := dec.Decode(&(c.flags)); err != nil {
+					return errors.Wrap(err, `error reading flags`)
+				}
+			case "iat":
+				if err := dec.Decode(&(c.issuedAt)); err != nil {
+					return errors.Wrap(err, `error reading iat`)
+				}
+			case "key":
+				if err := dec.Decode(&(c.key)); err != nil {
+					return errors.Wrap(err, `error reading key`)
+				}
+			default:
+				var tmp interface{}
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrapf(err, `error reading %s`, tok)
+				}
+				if c.extraFields == nil {
+					c.extraFields = map[string]interface{}{}
+				}
+				c.extraFields[tok] = tmp
+			}
+		}
+	}
+	return nil
+}
+
+func (c *IntrospectionResponse) makePairs() []*mapiter.Pair {
+	var pairs []*mapiter.Pair
+	if tmp := c.access; len(tmp) > 0 {
+		pairs = append(pairs, &mapiter.Pair{Key: "access", Value: tmp})
+	}
+	if tmp := c.active; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "active", Value: *tmp})
+	}
+	if tmp := c.expiresAt; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "exp", Value: *tmp})
+	}
+	if tmp := c.flags; len(tmp) > 0 {
+		pairs = append(pairs, &mapiter.Pair{Key: "flags", Value: tmp})
+	}
+	if tmp := c.issuedAt; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "iat", Value: *tmp})
+	}
+	if tmp := c.key; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "key", Value: *tmp})
+	}
+	var extraKeys []string
+	for k := range c.extraFields {
+		extraKeys = append(extraKeys, k)
+	}
+	for _, k := range extraKeys {
+		pairs = append(pairs, &mapiter.Pair{Key: k, Value: c.extraFields[k]})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.(string) < pairs[j].Key.(string)
+	})
+	return pairs
+}
+
+func (c *IntrospectionResponse) Iterate(ctx context.Context) mapiter.Iterator {
+	pairs := c.makePairs()
+	ch := make(chan *mapiter.Pair, len(pairs))
+	go func(ctx context.Context, ch chan *mapiter.Pair, pairs []*mapiter.Pair) {
+		defer close(ch)
+		for _, pair := range pairs {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- pair:
+			}
+		}
+	}(ctx, ch, pairs)
+	return mapiter.New(ch)
+}
@@ -0,0 +1,50 @@
+package gnap
+
+import (
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// publicJWK returns the public portion of a jwk.Key. Keys held by
+// the client are usually private keys, as they are also used to sign
+// requests, but only the public portion should ever be sent over the
+// wire. Symmetric keys have no public portion: for those, and for keys
+// whose public portion cannot be obtained, the returned value fails to
+// encode, so that the key material is never sent
+func publicJWK(key jwk.Key) interface{} {
+	if key.KeyType() == jwa.OctetSeq {
+		return unencodableJWK{errors.New(`symmetric keys must not be sent over the wire (use a key reference)`)}
+	}
+
+	pub, err := jwk.PublicKeyOf(key)
+	if err != nil {
+		return unencodableJWK{errors.Wrap(err, `failed to obtain public key`)}
+	}
+	return pub
+}
+
+// unencodableJWK takes the place of a key that must not be encoded,
+// and reports why when it is
+type unencodableJWK struct {
+	err error
+}
+
+func (v unencodableJWK) MarshalJSON() ([]byte, error) {
+	return nil, v.err
+}
+
+// NewKeyReference creates a Key that refers to a key pre-registered
+// with the authorization server. It is encoded as a plain string, and
+// must not be combined with key material or a proof method
+func NewKeyReference(ref string) *Key {
+	var key Key
+	key.SetRef(ref)
+	return &key
+}
+
+// IsReference returns true if the key only holds a reference to a
+// pre-registered key, and no key material
+func (c *Key) IsReference() bool {
+	return c.Ref() != "" && c.JWK() == nil && c.Cert() == "" && c.CertS256() == ""
+}
@@ -17,6 +17,7 @@ type Key struct {
 	certS256    *string
 	jwk         jwk.Key
 	proof       *ProofForm
+	ref         *string
 	extraFields map[string]interface{}
 }
 
@@ -25,6 +26,9 @@ func NewKey() *Key {
 }
 
 func (c *Key) Validate() error {
+	if c.ref != nil && len(c.makePairs()) > 1 {
+		return errors.Errorf(`field "ref" must not be combined with other fields`)
+	}
 	return nil
 }
 
@@ -50,6 +54,11 @@ func (c *Key) Get(key string) (interface{}, bool) {
 			return nil, false
 		}
 		return c.proof, true
+	case "ref":
+		if c.ref == nil {
+			return nil, false
+		}
+		return c.ref, true
 	default:
 		if c.extraFields == nil {
 			return nil, false
@@ -89,6 +98,14 @@ func (c *Key) Set(key string, value interface{}) error {
 		} else {
 			return errors.Errorf(`invalid type for "proof" (%T)`, value)
 		}
+	case "ref":
+		if v, ok := value.(string); ok {
+			c.ref = &v
+		} else if value == nil {
+			c.ref = nil
+		} else {
+			return errors.Errorf(`invalid type for "ref" (%T)`, value)
+		}
 	default:
 		if c.extraFields == nil {
 			c.extraFields = make(map[string]interface{})
@@ -136,19 +153,37 @@ func (c *Key) Proof() *ProofForm {
 	return c.proof
 }
 
+func (c *Key) SetRef(v string) {
+	c.ref = &v
+}
+
+func (c *Key) Ref() string {
+	if c.ref == nil {
+		return ""
+	}
+	return *(c.ref)
+}
+
 func (c Key) MarshalJSON() ([]byte, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
 	buf.WriteByte('{')
-	var i int
+	var pairs []*mapiter.Pair
 	for iter := c.Iterate(ctx); iter.Next(ctx); {
-		pair := iter.Pair()
+		pairs = append(pairs, iter.Pair())
+	}
+	if len(pairs) == 1 && pairs[0].Key.(string) == "ref" {
+		return []byte(strconv.Quote(pairs[0].Value.(string))), nil
+	}
+	if c.ref != nil {
+		return nil, errors.Errorf(`field "ref" must not be combined with other fields`)
+	}
+	for i, pair := range pairs {
 		if i > 0 {
 			buf.WriteByte(',')
 		}
-		i++
 		buf.WriteString(strconv.Quote(pair.Key.(string)))
 		buf.WriteByte(':')
 		if err := enc.Encode(pair.Value); err != nil {
@@ -164,6 +199,7 @@ func (c *Key) UnmarshalJSON(data []byte) error {
 	c.certS256 = nil
 	c.jwk = nil
 	c.proof = nil
+	c.ref = nil
 	dec := json.NewDecoder(bytes.NewReader(data))
 	tok, err := dec.Token()
 	if err != nil {
@@ -174,8 +210,11 @@ func (c *Key) UnmarshalJSON(data []byte) error {
 		if tok != '{' {
 			return errors.Errorf(`expected '{', but got '%c'`, tok)
 		}
+	case string:
+		c.ref = &tok
+		return nil
 	default:
-		return errors.Errorf(`expected '{', but got '%c'`, tok)
+		return errors.Errorf(`expected '{' or string, but got '%c'`, tok)
 	}
 LOOP:
 	for {
@@ -204,13 +243,21 @@ LOOP:
 				}
 				c.certS256 = &tmp
 			case "jwk":
-				if err := dec.Decode(&(c.jwk)); err != nil {
+				var tmp json.RawMessage
+				if err := dec.Decode(&tmp); err != nil {
 					return errors.Wrap(err, `error reading jwk`)
 				}
+				key, err := jwk.ParseKey(tmp)
+				if err != nil {
+					return errors.Wrap(err, `error parsing jwk`)
+				}
+				c.jwk = key
 			case "proof":
 				if err := dec.Decode(&(c.proof)); err != nil {
 					return errors.Wrap(err, `error reading proof`)
 				}
+			case "ref":
+				return errors.Errorf(`field "ref" is only allowed in the string form`)
 			default:
 				var tmp interface{}
 				if err := dec.Decode(&tmp); err != nil {
@@ -234,9 +281,15 @@ func (c *Key) makePairs() []*mapiter.Pair {
 	if tmp := c.certS256; tmp != nil {
 		pairs = append(pairs, &mapiter.Pair{Key: "cert#S256", Value: *tmp})
 	}
+	if tmp := c.jwk; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "jwk", Value: publicJWK(tmp)})
+	}
 	if tmp := c.proof; tmp != nil {
 		pairs = append(pairs, &mapiter.Pair{Key: "proof", Value: *tmp})
 	}
+	if tmp := c.ref; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "ref", Value: *tmp})
+	}
 	var extraKeys []string
 	for k := range c.extraFields {
 		extraKeys = append(extraKeys, k)
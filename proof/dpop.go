@@ -0,0 +1,308 @@
+package proof
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/pkg/errors"
+)
+
+const dpopType = `dpop+jwt`
+
+// DPoPSigner adds a DPoP proof JWT to requests, in the DPoP header.
+type DPoPSigner struct {
+	key jwk.Key
+	pub jwk.Key
+	alg jwa.SignatureAlgorithm
+}
+
+// NewDPoPSigner creates a new signer using the JWK in `key`. The JWK
+// must be a private key.
+func NewDPoPSigner(key *gnap.Key) (*DPoPSigner, error) {
+	jwkey, alg, err := jwsSigningKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if jwkey.KeyType() == jwa.OctetSeq {
+		return nil, errors.New(`DPoP requires an asymmetric key`)
+	}
+
+	pub, err := jwk.PublicKeyOf(jwkey)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to obtain public key`)
+	}
+
+	return &DPoPSigner{
+		key: jwkey,
+		pub: pub,
+		alg: alg,
+	}, nil
+}
+
+func (s *DPoPSigner) Sign(req *http.Request) error {
+	var token string
+	if v, ok := accessToken(req); ok {
+		token = v
+	}
+
+	proof, err := s.Build(req.Method, targetURI(req), token)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(`DPoP`, string(proof))
+	return nil
+}
+
+// Build creates a DPoP proof JWT for a request with the given method
+// and URI. If `token` is non-empty, the `ath` claim is populated with
+// the hash of the token.
+func (s *DPoPSigner) Build(method, uri, token string) ([]byte, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to generate jti`)
+	}
+
+	htu, err := dpopTargetURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	t := jwt.New()
+	//nolint:errcheck
+	t.Set(jwt.JwtIDKey, jti)
+	//nolint:errcheck
+	t.Set(jwt.IssuedAtKey, time.Now())
+	//nolint:errcheck
+	t.Set(`htm`, method)
+	//nolint:errcheck
+	t.Set(`htu`, htu)
+	if token != "" {
+		//nolint:errcheck
+		t.Set(`ath`, tokenHash(token))
+	}
+
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to encode claims`)
+	}
+
+	hdrs := jws.NewHeaders()
+	//nolint:errcheck
+	hdrs.Set(jws.TypeKey, dpopType)
+	//nolint:errcheck
+	hdrs.Set(jws.JWKKey, s.pub)
+
+	signed, err := jws.Sign(payload, s.alg, s.key, jws.WithHeaders(hdrs))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to sign DPoP proof`)
+	}
+	return signed, nil
+}
+
+// ReplayCache records the `jti` values of DPoP proofs that have
+// already been seen, so that proofs cannot be replayed.
+type ReplayCache interface {
+	// Add records `jti` until `expires`. It returns false if `jti`
+	// has already been recorded and has not expired yet.
+	Add(jti string, expires time.Time) bool
+}
+
+// MemoryReplayCache is a ReplayCache that keeps its entries in memory.
+// Expired entries are purged as new entries are added.
+type MemoryReplayCache struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+func NewMemoryReplayCache() *MemoryReplayCache {
+	return &MemoryReplayCache{
+		entries: make(map[string]time.Time),
+	}
+}
+
+func (c *MemoryReplayCache) Add(jti string, expires time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, v := range c.entries {
+		if now.After(v) {
+			delete(c.entries, k)
+		}
+	}
+
+	if _, ok := c.entries[jti]; ok {
+		return false
+	}
+	c.entries[jti] = expires
+	return true
+}
+
+// DPoPVerifier verifies the DPoP proofs in incoming requests.
+type DPoPVerifier struct {
+	cache ReplayCache
+}
+
+// NewDPoPVerifier creates a new DPoPVerifier. If `cache` is nil, the
+// verifier does not check for replayed proofs.
+func NewDPoPVerifier(cache ReplayCache) *DPoPVerifier {
+	return &DPoPVerifier{
+		cache: cache,
+	}
+}
+
+// VerifyDPoP verifies the DPoP proof in the request against the JWK
+// in `key`. It does not check for replayed proofs: use a DPoPVerifier
+// with a ReplayCache for that.
+func VerifyDPoP(req *http.Request, key *gnap.Key) error {
+	return NewDPoPVerifier(nil).Verify(req, key)
+}
+
+// Verify verifies the DPoP proof in the request against the JWK in
+// `key`.
+//
+// The JWK in the proof header must be the same key as `key`, and the
+// `htm`, `htu`, `iat`, and `ath` claims are checked against the request.
+func (v *DPoPVerifier) Verify(req *http.Request, key *gnap.Key) error {
+	jwkey, err := verificationKey(key)
+	if err != nil {
+		return errors.Wrap(err, `invalid key`)
+	}
+
+	values := req.Header.Values(`DPoP`)
+	if len(values) != 1 {
+		return errors.New(`exactly one DPoP header is required`)
+	}
+	compact := []byte(values[0])
+
+	msg, err := jws.Parse(compact)
+	if err != nil {
+		return errors.Wrap(err, `failed to parse DPoP proof`)
+	}
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return errors.New(`expected exactly one signature`)
+	}
+	hdrs := sigs[0].ProtectedHeaders()
+
+	if v := hdrs.Type(); v != dpopType {
+		return errors.Errorf(`invalid "typ" header: expected %q, got %q`, dpopType, v)
+	}
+
+	embedded := hdrs.JWK()
+	if embedded == nil {
+		return errors.New(`"jwk" header is required`)
+	}
+	if !sameKey(embedded, jwkey) {
+		return errors.New(`DPoP proof was not created with the bound key`)
+	}
+
+	alg := hdrs.Algorithm()
+	if err := CheckAlgorithm(alg, jwkey); err != nil {
+		return err
+	}
+
+	payload, err := jws.Verify(compact, alg, jwkey)
+	if err != nil {
+		return errors.Wrap(err, `failed to verify signature`)
+	}
+
+	t := jwt.New()
+	if err := json.Unmarshal(payload, t); err != nil {
+		return errors.Wrap(err, `failed to decode claims`)
+	}
+
+	if err := checkClaim(t, `htm`, req.Method); err != nil {
+		return err
+	}
+
+	htu, err := dpopTargetURI(targetURI(req))
+	if err != nil {
+		return err
+	}
+	if err := checkClaim(t, `htu`, htu); err != nil {
+		return err
+	}
+
+	if token, ok := accessToken(req); ok {
+		if err := checkClaim(t, `ath`, tokenHash(token)); err != nil {
+			return err
+		}
+	}
+
+	iat := t.IssuedAt()
+	if iat.IsZero() {
+		return errors.New(`"iat" claim is required`)
+	}
+	if err := checkCreated(iat.Unix()); err != nil {
+		return err
+	}
+
+	jti := t.JwtID()
+	if jti == "" {
+		return errors.New(`"jti" claim is required`)
+	}
+	if v.cache != nil && !v.cache.Add(jti, iat.Add(AcceptableSkew)) {
+		return errors.New(`DPoP proof has already been used`)
+	}
+	return nil
+}
+
+func checkClaim(t jwt.Token, name, expected string) error {
+	v, ok := t.Get(name)
+	if !ok {
+		return errors.Errorf(`%q claim is required`, name)
+	}
+	if s, _ := v.(string); s != expected {
+		return errors.Errorf(`%q claim does not match the request`, name)
+	}
+	return nil
+}
+
+// dpopTargetURI returns the URI without query and fragment parts,
+// as required for the `htu` claim
+func dpopTargetURI(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", errors.Wrap(err, `failed to parse URI`)
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String(), nil
+}
+
+// sameKey reports whether two keys have the same JWK thumbprint
+func sameKey(a, b jwk.Key) bool {
+	ta, err := a.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return false
+	}
+	tb, err := b.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ta, tb)
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
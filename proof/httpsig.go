@@ -0,0 +1,430 @@
+package proof
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+const httpsigLabel = `gnap`
+const httpsigTag = `gnap`
+
+// httpsigAlgorithms lists the JWS algorithms that have an equivalent
+// algorithm in the HTTP Signature Algorithms registry
+var httpsigAlgorithms = map[jwa.SignatureAlgorithm]struct{}{
+	jwa.RS256: {}, // rsa-v1_5-sha256
+	jwa.PS512: {}, // rsa-pss-sha512
+	jwa.ES256: {}, // ecdsa-p256-sha256
+	jwa.ES384: {}, // ecdsa-p384-sha384
+	jwa.EdDSA: {}, // ed25519
+	jwa.HS256: {}, // hmac-sha256
+}
+
+// HTTPSigSigner signs requests using HTTP Message Signatures.
+//
+// The signature covers the request method, the target URI, and if
+// present, the Content-Digest and Authorization headers. The
+// Content-Digest header is computed and added by the signer.
+type HTTPSigSigner struct {
+	key jwk.Key
+	alg jwa.SignatureAlgorithm
+}
+
+// NewHTTPSigSigner creates a new signer using the JWK in `key`.
+// The JWK must be a private key (or a symmetric key)
+func NewHTTPSigSigner(key *gnap.Key) (*HTTPSigSigner, error) {
+	jwkey, err := signingKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, `invalid key`)
+	}
+
+	alg, err := httpsigAlgorithm(jwkey)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to determine signature algorithm`)
+	}
+
+	return &HTTPSigSigner{
+		key: jwkey,
+		alg: alg,
+	}, nil
+}
+
+func httpsigAlgorithm(key jwk.Key) (jwa.SignatureAlgorithm, error) {
+	alg, err := signatureAlgorithm(key, jwa.PS512)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := httpsigAlgorithms[alg]; !ok {
+		return "", errors.Errorf(`algorithm %q cannot be used with HTTP Message Signatures`, alg)
+	}
+	return alg, nil
+}
+
+func (s *HTTPSigSigner) Sign(req *http.Request) error {
+	body, err := readBody(req)
+	if err != nil {
+		return errors.Wrap(err, `failed to read request body`)
+	}
+
+	components := []string{`@method`, `@target-uri`}
+	if len(body) > 0 {
+		req.Header.Set(`Content-Digest`, contentDigest(body))
+		components = append(components, `content-digest`)
+	}
+	if req.Header.Get(`Authorization`) != "" {
+		components = append(components, `authorization`)
+	}
+
+	var params bytes.Buffer
+	params.WriteByte('(')
+	for i, component := range components {
+		if i > 0 {
+			params.WriteByte(' ')
+		}
+		params.WriteString(strconv.Quote(component))
+	}
+	params.WriteByte(')')
+	params.WriteString(`;created=`)
+	params.WriteString(strconv.FormatInt(time.Now().Unix(), 10))
+	if kid := s.key.KeyID(); kid != "" {
+		params.WriteString(`;keyid=`)
+		params.WriteString(strconv.Quote(kid))
+	}
+	params.WriteString(`;tag=`)
+	params.WriteString(strconv.Quote(httpsigTag))
+
+	base, err := signatureBase(req, components, params.String())
+	if err != nil {
+		return errors.Wrap(err, `failed to build signature base`)
+	}
+
+	signer, err := jws.NewSigner(s.alg)
+	if err != nil {
+		return errors.Wrapf(err, `failed to create signer for %s`, s.alg)
+	}
+
+	signature, err := signer.Sign(base, s.key)
+	if err != nil {
+		return errors.Wrap(err, `failed to sign request`)
+	}
+
+	req.Header.Set(`Signature-Input`, httpsigLabel+`=`+params.String())
+	req.Header.Set(`Signature`, httpsigLabel+`=:`+base64.StdEncoding.EncodeToString(signature)+`:`)
+	return nil
+}
+
+// VerifyHTTPSig verifies the HTTP Message Signature in the request
+// against the JWK in `key`.
+//
+// In addition to the signature itself, the coverage of the signature
+// is checked: the method and target URI must always be covered, and
+// the Content-Digest and Authorization headers must be covered if
+// the request contains a body or an access token, respectively.
+//
+// The signature must also carry a "created" parameter that is within
+// AcceptableSkew of the current time. Signatures without one, or with
+// a stale one, are rejected to limit the window for replay.
+func VerifyHTTPSig(req *http.Request, key *gnap.Key) error {
+	jwkey, err := verificationKey(key)
+	if err != nil {
+		return errors.Wrap(err, `invalid key`)
+	}
+
+	alg, err := httpsigAlgorithm(jwkey)
+	if err != nil {
+		return errors.Wrap(err, `failed to determine signature algorithm`)
+	}
+
+	inputs, err := parseDictionary(req.Header.Get(`Signature-Input`))
+	if err != nil {
+		return errors.Wrap(err, `failed to parse Signature-Input header`)
+	}
+	signatures, err := parseDictionary(req.Header.Get(`Signature`))
+	if err != nil {
+		return errors.Wrap(err, `failed to parse Signature header`)
+	}
+
+	label, input, ok := selectSignatureInput(inputs)
+	if !ok {
+		return errors.New(`missing Signature-Input header`)
+	}
+
+	rawsig, ok := signatures[label]
+	if !ok {
+		return errors.Errorf(`missing signature %q in Signature header`, label)
+	}
+	if len(rawsig) < 2 || rawsig[0] != ':' || rawsig[len(rawsig)-1] != ':' {
+		return errors.Errorf(`invalid signature %q in Signature header`, label)
+	}
+	signature, err := base64.StdEncoding.DecodeString(rawsig[1 : len(rawsig)-1])
+	if err != nil {
+		return errors.Wrap(err, `failed to decode signature`)
+	}
+
+	components, params, err := parseInnerList(input)
+	if err != nil {
+		return errors.Wrap(err, `failed to parse signature parameters`)
+	}
+	v, ok := params[`created`]
+	if !ok {
+		return errors.New(`signature parameter "created" is required`)
+	}
+	created, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return errors.Wrap(err, `invalid signature parameter "created"`)
+	}
+	if err := checkCreated(created); err != nil {
+		return err
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return errors.Wrap(err, `failed to read request body`)
+	}
+
+	required := []string{`@method`, `@target-uri`}
+	if len(body) > 0 {
+		required = append(required, `content-digest`)
+		if err := verifyContentDigest(req.Header.Get(`Content-Digest`), body); err != nil {
+			return errors.Wrap(err, `failed to verify Content-Digest header`)
+		}
+	}
+	if req.Header.Get(`Authorization`) != "" {
+		required = append(required, `authorization`)
+	}
+	for _, name := range required {
+		var found bool
+		for _, component := range components {
+			if component == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf(`signature does not cover %q`, name)
+		}
+	}
+
+	base, err := signatureBase(req, components, input)
+	if err != nil {
+		return errors.Wrap(err, `failed to build signature base`)
+	}
+
+	verifier, err := jws.NewVerifier(alg)
+	if err != nil {
+		return errors.Wrapf(err, `failed to create verifier for %s`, alg)
+	}
+	if err := verifier.Verify(base, signature, jwkey); err != nil {
+		return errors.Wrap(err, `failed to verify signature`)
+	}
+	return nil
+}
+
+// selectSignatureInput picks the signature that was created for GNAP,
+// or the only signature if there is just one.
+func selectSignatureInput(inputs map[string]string) (string, string, bool) {
+	for label, input := range inputs {
+		_, params, err := parseInnerList(input)
+		if err != nil {
+			continue
+		}
+		if params[`tag`] == httpsigTag {
+			return label, input, true
+		}
+	}
+
+	if len(inputs) == 1 {
+		for label, input := range inputs {
+			return label, input, true
+		}
+	}
+	return "", "", false
+}
+
+func signatureBase(req *http.Request, components []string, params string) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, component := range components {
+		var value string
+		switch component {
+		case `@method`:
+			value = strings.ToUpper(req.Method)
+		case `@target-uri`:
+			value = targetURI(req)
+		default:
+			if strings.HasPrefix(component, `@`) {
+				return nil, errors.Errorf(`unsupported derived component %q`, component)
+			}
+			raw := req.Header.Values(component)
+			if len(raw) == 0 {
+				return nil, errors.Errorf(`header %q is not present`, component)
+			}
+			values := make([]string, len(raw))
+			for i, v := range raw {
+				values[i] = strings.TrimSpace(v)
+			}
+			value = strings.Join(values, `, `)
+		}
+		buf.WriteString(strconv.Quote(component))
+		buf.WriteString(`: `)
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(`"@signature-params": `)
+	buf.WriteString(params)
+	return buf.Bytes(), nil
+}
+
+func contentDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `sha-256=:` + base64.StdEncoding.EncodeToString(sum[:]) + `:`
+}
+
+// digestAlgorithms are the Content-Digest algorithms supported when
+// verifying requests, in the order in which they are checked
+var digestAlgorithms = []struct {
+	name string
+	hash func() hash.Hash
+}{
+	{name: `sha-256`, hash: sha256.New},
+	{name: `sha-512`, hash: sha512.New},
+}
+
+// verifyContentDigest checks every supported digest in the
+// Content-Digest header against the body. Unsupported algorithms are
+// ignored, but at least one supported algorithm must be present
+func verifyContentDigest(header string, body []byte) error {
+	digests, err := parseDictionary(header)
+	if err != nil {
+		return errors.Wrap(err, `failed to parse header`)
+	}
+
+	var verified bool
+	for _, alg := range digestAlgorithms {
+		value, ok := digests[alg.name]
+		if !ok {
+			continue
+		}
+
+		if len(value) < 2 || value[0] != ':' || value[len(value)-1] != ':' {
+			return errors.Errorf(`invalid digest value for %q`, alg.name)
+		}
+		expected, err := base64.StdEncoding.DecodeString(value[1 : len(value)-1])
+		if err != nil {
+			return errors.Wrapf(err, `failed to decode digest value for %q`, alg.name)
+		}
+
+		h := alg.hash()
+		h.Write(body)
+		if subtle.ConstantTimeCompare(expected, h.Sum(nil)) != 1 {
+			return errors.Errorf(`digest mismatch for %q`, alg.name)
+		}
+		verified = true
+	}
+
+	if !verified {
+		return errors.New(`no supported digest algorithm found`)
+	}
+	return nil
+}
+
+// splitTopLevel splits s by sep, ignoring separators that appear
+// within quoted strings or parenthesized inner lists
+func splitTopLevel(s string, sep byte) []string {
+	var list []string
+	var quoted bool
+	var depth int
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth == 0:
+			list = append(list, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(list, s[start:])
+}
+
+// parseDictionary parses a structured field dictionary, and returns
+// the raw (unparsed) value of each member
+func parseDictionary(s string) (map[string]string, error) {
+	members := make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return members, nil
+	}
+
+	for _, member := range splitTopLevel(s, ',') {
+		member = strings.TrimSpace(member)
+		i := strings.IndexByte(member, '=')
+		if i <= 0 {
+			return nil, errors.Errorf(`invalid dictionary member %q`, member)
+		}
+		members[member[:i]] = member[i+1:]
+	}
+	return members, nil
+}
+
+// parseInnerList parses an inner list of strings followed by
+// parameters, as used in the Signature-Input header
+func parseInnerList(s string) ([]string, map[string]string, error) {
+	if !strings.HasPrefix(s, `(`) {
+		return nil, nil, errors.New(`expected inner list`)
+	}
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, nil, errors.New(`unterminated inner list`)
+	}
+
+	var items []string
+	for _, item := range strings.Fields(s[1:end]) {
+		v, err := strconv.Unquote(item)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, `invalid inner list item %s`, item)
+		}
+		items = append(items, v)
+	}
+
+	params := make(map[string]string)
+	for _, param := range splitTopLevel(s[end+1:], ';') {
+		param = strings.TrimSpace(param)
+		if param == "" {
+			continue
+		}
+		i := strings.IndexByte(param, '=')
+		if i <= 0 {
+			params[param] = ""
+			continue
+		}
+		name, value := param[:i], param[i+1:]
+		if strings.HasPrefix(value, `"`) {
+			v, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, `invalid parameter value for %q`, name)
+			}
+			value = v
+		}
+		params[name] = value
+	}
+	return items, params, nil
+}
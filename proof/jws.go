@@ -0,0 +1,348 @@
+package proof
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+const (
+	detachedJWSType = `gnap-binding-jwsd`
+	attachedJWSType = `gnap-binding-jws`
+)
+
+// DetachedJWSSigner signs requests by creating a JWS over the request
+// body, and sending it with the payload removed in the Detached-JWS
+// header.
+type DetachedJWSSigner struct {
+	key jwk.Key
+	alg jwa.SignatureAlgorithm
+}
+
+// AttachedJWSSigner signs requests by replacing the request body
+// with a compact JWS that contains the original body as its payload.
+// Requests without a body are signed in the same manner as
+// DetachedJWSSigner.
+type AttachedJWSSigner struct {
+	key jwk.Key
+	alg jwa.SignatureAlgorithm
+}
+
+// NewDetachedJWSSigner creates a new signer using the JWK in `key`.
+func NewDetachedJWSSigner(key *gnap.Key) (*DetachedJWSSigner, error) {
+	jwkey, alg, err := jwsSigningKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &DetachedJWSSigner{
+		key: jwkey,
+		alg: alg,
+	}, nil
+}
+
+// NewAttachedJWSSigner creates a new signer using the JWK in `key`.
+func NewAttachedJWSSigner(key *gnap.Key) (*AttachedJWSSigner, error) {
+	jwkey, alg, err := jwsSigningKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &AttachedJWSSigner{
+		key: jwkey,
+		alg: alg,
+	}, nil
+}
+
+func jwsSigningKey(key *gnap.Key) (jwk.Key, jwa.SignatureAlgorithm, error) {
+	jwkey, err := signingKey(key)
+	if err != nil {
+		return nil, "", errors.Wrap(err, `invalid key`)
+	}
+
+	alg, err := signatureAlgorithm(jwkey, jwa.RS256)
+	if err != nil {
+		return nil, "", errors.Wrap(err, `failed to determine signature algorithm`)
+	}
+	return jwkey, alg, nil
+}
+
+func (s *DetachedJWSSigner) Sign(req *http.Request) error {
+	body, err := readBody(req)
+	if err != nil {
+		return errors.Wrap(err, `failed to read request body`)
+	}
+
+	return signDetachedJWS(req, body, s.alg, s.key)
+}
+
+func (s *AttachedJWSSigner) Sign(req *http.Request) error {
+	body, err := readBody(req)
+	if err != nil {
+		return errors.Wrap(err, `failed to read request body`)
+	}
+
+	if len(body) == 0 {
+		return signDetachedJWS(req, body, s.alg, s.key)
+	}
+
+	signed, err := signJWS(req, body, attachedJWSType, s.alg, s.key)
+	if err != nil {
+		return err
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(signed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(signed)), nil
+	}
+	req.ContentLength = int64(len(signed))
+	req.Header.Set(`Content-Type`, `application/jose`)
+	return nil
+}
+
+func signDetachedJWS(req *http.Request, body []byte, alg jwa.SignatureAlgorithm, key jwk.Key) error {
+	signed, err := signJWS(req, body, detachedJWSType, alg, key)
+	if err != nil {
+		return err
+	}
+
+	protected, _, signature, err := jws.SplitCompact(signed)
+	if err != nil {
+		return errors.Wrap(err, `failed to split JWS`)
+	}
+
+	req.Header.Set(`Detached-JWS`, string(protected)+`..`+string(signature))
+	return nil
+}
+
+func signJWS(req *http.Request, body []byte, typ string, alg jwa.SignatureAlgorithm, key jwk.Key) ([]byte, error) {
+	hdrs := jws.NewHeaders()
+	//nolint:errcheck
+	hdrs.Set(jws.TypeKey, typ)
+	//nolint:errcheck
+	hdrs.Set(`htm`, req.Method)
+	//nolint:errcheck
+	hdrs.Set(`uri`, targetURI(req))
+	//nolint:errcheck
+	hdrs.Set(`created`, time.Now().Unix())
+	if ath, ok := accessTokenHash(req); ok {
+		//nolint:errcheck
+		hdrs.Set(`ath`, ath)
+	}
+
+	signed, err := jws.Sign(body, alg, key, jws.WithHeaders(hdrs))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to sign request`)
+	}
+	return signed, nil
+}
+
+// VerifyDetachedJWS verifies the Detached-JWS header in the request
+// against the JWK in `key`.
+//
+// The `htm`, `uri`, `created`, and `ath` parameters in the protected
+// header are checked against the request.
+func VerifyDetachedJWS(req *http.Request, key *gnap.Key) error {
+	body, err := readBody(req)
+	if err != nil {
+		return errors.Wrap(err, `failed to read request body`)
+	}
+
+	return verifyDetachedJWS(req, body, key)
+}
+
+func verifyDetachedJWS(req *http.Request, body []byte, key *gnap.Key) error {
+	detached := req.Header.Get(`Detached-JWS`)
+	if detached == "" {
+		return errors.New(`missing Detached-JWS header`)
+	}
+
+	parts := strings.Split(detached, `.`)
+	if len(parts) != 3 || parts[1] != "" {
+		return errors.New(`invalid Detached-JWS header`)
+	}
+
+	compact := parts[0] + `.` + base64.RawURLEncoding.EncodeToString(body) + `.` + parts[2]
+	if _, err := verifyJWS(req, []byte(compact), detachedJWSType, key); err != nil {
+		return errors.Wrap(err, `failed to verify Detached-JWS header`)
+	}
+	return nil
+}
+
+// VerifyAttachedJWS verifies the JWS sent as the request body against
+// the JWK in `key`. Upon success, the request body is replaced with
+// the JWS payload, so that subsequent handlers can read the original
+// message. Requests without a body are verified using the
+// Detached-JWS header.
+func VerifyAttachedJWS(req *http.Request, key *gnap.Key) error {
+	body, err := readBody(req)
+	if err != nil {
+		return errors.Wrap(err, `failed to read request body`)
+	}
+
+	if len(body) == 0 {
+		return verifyDetachedJWS(req, body, key)
+	}
+
+	payload, err := verifyJWS(req, bytes.TrimSpace(body), attachedJWSType, key)
+	if err != nil {
+		return errors.Wrap(err, `failed to verify JWS`)
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(payload))
+	req.ContentLength = int64(len(payload))
+	req.Header.Set(`Content-Type`, `application/json`)
+	return nil
+}
+
+func verifyJWS(req *http.Request, compact []byte, typ string, key *gnap.Key) ([]byte, error) {
+	jwkey, err := verificationKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, `invalid key`)
+	}
+
+	msg, err := jws.Parse(compact)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse JWS`)
+	}
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return nil, errors.New(`expected exactly one signature`)
+	}
+	hdrs := sigs[0].ProtectedHeaders()
+
+	alg := hdrs.Algorithm()
+	if err := CheckAlgorithm(alg, jwkey); err != nil {
+		return nil, err
+	}
+
+	payload, err := jws.Verify(compact, alg, jwkey)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to verify signature`)
+	}
+
+	if v := hdrs.Type(); v != typ {
+		return nil, errors.Errorf(`invalid "typ" header: expected %q, got %q`, typ, v)
+	}
+
+	if err := checkStringHeader(hdrs, `htm`, req.Method); err != nil {
+		return nil, err
+	}
+	if err := checkStringHeader(hdrs, `uri`, targetURI(req)); err != nil {
+		return nil, err
+	}
+	if ath, ok := accessTokenHash(req); ok {
+		if err := checkStringHeader(hdrs, `ath`, ath); err != nil {
+			return nil, err
+		}
+	}
+
+	v, ok := hdrs.Get(`created`)
+	if !ok {
+		return nil, errors.New(`"created" header is required`)
+	}
+	created, err := numericValue(v)
+	if err != nil {
+		return nil, errors.Wrap(err, `invalid "created" header`)
+	}
+	if err := checkCreated(created); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+func checkStringHeader(hdrs jws.Headers, name, expected string) error {
+	v, ok := hdrs.Get(name)
+	if !ok {
+		return errors.Errorf(`%q header is required`, name)
+	}
+	if s, _ := v.(string); s != expected {
+		return errors.Errorf(`%q header does not match the request`, name)
+	}
+	return nil
+}
+
+// CheckAlgorithm makes sure that the algorithm specified in a JWS
+// header is compatible with the key, so that the choice of algorithm
+// cannot be abused by an attacker
+func CheckAlgorithm(alg jwa.SignatureAlgorithm, key jwk.Key) error {
+	var ok bool
+	switch alg {
+	case jwa.RS256, jwa.RS384, jwa.RS512, jwa.PS256, jwa.PS384, jwa.PS512:
+		ok = key.KeyType() == jwa.RSA
+	case jwa.ES256, jwa.ES384, jwa.ES512, jwa.ES256K:
+		ok = key.KeyType() == jwa.EC
+	case jwa.EdDSA:
+		ok = key.KeyType() == jwa.OKP
+	case jwa.HS256, jwa.HS384, jwa.HS512:
+		ok = key.KeyType() == jwa.OctetSeq
+	}
+	if !ok {
+		return errors.Errorf(`algorithm %q cannot be used with key type %q`, alg, key.KeyType())
+	}
+
+	if v := key.Algorithm(); v != "" && v != alg.String() {
+		return errors.Errorf(`algorithm %q does not match key algorithm %q`, alg, v)
+	}
+	return nil
+}
+
+// accessTokenHash computes the `ath` value for the access token
+// sent in the Authorization header of the request, if any
+func accessTokenHash(req *http.Request) (string, bool) {
+	token, ok := accessToken(req)
+	if !ok {
+		return "", false
+	}
+	return tokenHash(token), true
+}
+
+// tokenHash computes the base64url encoded SHA-256 hash of an
+// access token, as used in the `ath` parameter
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// accessToken extracts the access token from the Authorization
+// header of the request
+func accessToken(req *http.Request) (string, bool) {
+	v := req.Header.Get(`Authorization`)
+	i := strings.IndexByte(v, ' ')
+	if i < 0 {
+		return "", false
+	}
+
+	switch scheme := v[:i]; {
+	case strings.EqualFold(scheme, `GNAP`), strings.EqualFold(scheme, `DPoP`), strings.EqualFold(scheme, `Bearer`):
+		token := strings.TrimSpace(v[i+1:])
+		return token, token != ""
+	}
+	return "", false
+}
+
+func numericValue(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return 0, errors.Errorf(`expected a number, got %T`, v)
+	}
+}
@@ -0,0 +1,112 @@
+package proof
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/pkg/errors"
+)
+
+// MutualTLSSigner is the Signer for the "mtls" proofing method.
+// Proof of possession is established by the TLS layer, therefore
+// Sign does not modify the request. Use TLSCertificate to configure
+// the client certificate of the HTTP client.
+type MutualTLSSigner struct{}
+
+func (MutualTLSSigner) Sign(*http.Request) error {
+	return nil
+}
+
+// TLSCertificate creates a tls.Certificate from the `cert` field and
+// the JWK (which must be a private key) in `key`
+func TLSCertificate(key *gnap.Key) (tls.Certificate, error) {
+	if key == nil {
+		return tls.Certificate{}, errors.New(`key must be non-nil`)
+	}
+
+	cert, err := parseCertificate(key.Cert())
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, `invalid "cert" field`)
+	}
+
+	jwkey, err := signingKey(key)
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, `invalid key`)
+	}
+
+	var privkey interface{}
+	if err := jwkey.Raw(&privkey); err != nil {
+		return tls.Certificate{}, errors.Wrap(err, `failed to obtain private key`)
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{cert.Raw},
+		PrivateKey:  privkey,
+		Leaf:        cert,
+	}, nil
+}
+
+// CertificateThumbprint computes the value of the `cert#S256` field
+// for the given certificate
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyMutualTLS verifies that the certificate presented by the peer
+// matches the certificate described by `key`. If the `cert#S256`
+// field is present the thumbprint of the certificate is compared,
+// otherwise the certificate is compared against the `cert` field.
+func VerifyMutualTLS(req *http.Request, key *gnap.Key) error {
+	if key == nil {
+		return errors.New(`key must be non-nil`)
+	}
+
+	if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return errors.New(`no client certificate was presented`)
+	}
+	peer := req.TLS.PeerCertificates[0]
+
+	if v := key.CertS256(); v != "" {
+		if subtle.ConstantTimeCompare([]byte(CertificateThumbprint(peer)), []byte(v)) != 1 {
+			return errors.New(`client certificate does not match "cert#S256"`)
+		}
+		return nil
+	}
+
+	if v := key.Cert(); v != "" {
+		cert, err := parseCertificate(v)
+		if err != nil {
+			return errors.Wrap(err, `invalid "cert" field`)
+		}
+		if !bytes.Equal(cert.Raw, peer.Raw) {
+			return errors.New(`client certificate does not match "cert"`)
+		}
+		return nil
+	}
+
+	return errors.New(`either "cert" or "cert#S256" is required`)
+}
+
+func parseCertificate(v string) (*x509.Certificate, error) {
+	if v == "" {
+		return nil, errors.New(`certificate is not available`)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to decode certificate`)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse certificate`)
+	}
+	return cert, nil
+}
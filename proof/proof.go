@@ -0,0 +1,247 @@
+// Package proof implements the key proofing methods used by GNAP
+// clients to prove possession of their keys, along with the
+// verification routines used by authorization servers and
+// resource servers.
+package proof
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// AcceptableSkew is the maximum difference allowed between the
+// creation time of a proof and the current time
+const AcceptableSkew = 5 * time.Minute
+
+// Signer adds proof of possession of a key to outgoing HTTP requests.
+//
+// Sign is called after the request has been fully constructed,
+// including the Authorization header, if any.
+type Signer interface {
+	Sign(*http.Request) error
+}
+
+// Verifier verifies the proof of possession of a key in incoming
+// HTTP requests
+type Verifier interface {
+	Verify(*http.Request, *gnap.Key) error
+}
+
+// VerifierFunc is a Verifier represented as a function
+type VerifierFunc func(*http.Request, *gnap.Key) error
+
+func (fn VerifierFunc) Verify(req *http.Request, key *gnap.Key) error {
+	return fn(req, key)
+}
+
+// NewSigner creates a Signer that implements the proofing method
+// specified in the `proof` field of the key
+func NewSigner(key *gnap.Key) (Signer, error) {
+	form, err := proofForm(key)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to determine proofing method`)
+	}
+
+	switch form {
+	case gnap.HTTPSig:
+		return NewHTTPSigSigner(key)
+	case gnap.DetachedJWS:
+		return NewDetachedJWSSigner(key)
+	case gnap.AttachedJWS:
+		return NewAttachedJWSSigner(key)
+	case gnap.Dpop:
+		return NewDPoPSigner(key)
+	case gnap.MutualTLS:
+		return MutualTLSSigner{}, nil
+	default:
+		return nil, errors.Errorf(`unsupported proofing method %q`, form)
+	}
+}
+
+// Verify verifies the proof of possession in the request, using
+// the proofing method specified in the `proof` field of the key.
+//
+// DPoP proofs are not checked for replays: use NewVerifier with a
+// ReplayCache for that.
+func Verify(req *http.Request, key *gnap.Key) error {
+	return verify(req, key, NewDPoPVerifier(nil))
+}
+
+// NewVerifier creates a Verifier that supports the same proofing
+// methods as Verify, and rejects DPoP proofs that have already been
+// seen by `cache`, such as a MemoryReplayCache.
+func NewVerifier(cache ReplayCache) Verifier {
+	dpop := NewDPoPVerifier(cache)
+	return VerifierFunc(func(req *http.Request, key *gnap.Key) error {
+		return verify(req, key, dpop)
+	})
+}
+
+func verify(req *http.Request, key *gnap.Key, dpop *DPoPVerifier) error {
+	form, err := proofForm(key)
+	if err != nil {
+		return errors.Wrap(err, `failed to determine proofing method`)
+	}
+
+	switch form {
+	case gnap.HTTPSig:
+		return VerifyHTTPSig(req, key)
+	case gnap.DetachedJWS:
+		return VerifyDetachedJWS(req, key)
+	case gnap.AttachedJWS:
+		return VerifyAttachedJWS(req, key)
+	case gnap.Dpop:
+		return dpop.Verify(req, key)
+	case gnap.MutualTLS:
+		return VerifyMutualTLS(req, key)
+	default:
+		return errors.Errorf(`unsupported proofing method %q`, form)
+	}
+}
+
+func proofForm(key *gnap.Key) (gnap.ProofForm, error) {
+	if key == nil {
+		return "", errors.New(`key must be non-nil`)
+	}
+	form := key.Proof()
+	if form == nil {
+		return "", errors.New(`field "proof" is required`)
+	}
+	return *form, nil
+}
+
+func signingKey(key *gnap.Key) (jwk.Key, error) {
+	if key == nil {
+		return nil, errors.New(`key must be non-nil`)
+	}
+	jwkey := key.JWK()
+	if jwkey == nil {
+		return nil, errors.New(`field "jwk" is required`)
+	}
+	return jwkey, nil
+}
+
+// verificationKey returns the key to be used for verification. Private
+// keys are converted to their public counterparts, so that the same
+// gnap.Key can be used on both sides (e.g. in tests)
+func verificationKey(key *gnap.Key) (jwk.Key, error) {
+	jwkey, err := signingKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if jwkey.KeyType() == jwa.OctetSeq {
+		return jwkey, nil
+	}
+
+	pub, err := jwk.PublicKeyOf(jwkey)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to obtain public key`)
+	}
+	return pub, nil
+}
+
+type curver interface {
+	Crv() jwa.EllipticCurveAlgorithm
+}
+
+// signatureAlgorithm determines the JWS algorithm to be used with
+// the given key. If the key specifies an `alg`, it is used as is.
+// Otherwise a default algorithm is chosen based on the key type.
+// `rsaAlg` specifies the default algorithm for RSA keys.
+func signatureAlgorithm(key jwk.Key, rsaAlg jwa.SignatureAlgorithm) (jwa.SignatureAlgorithm, error) {
+	if v := key.Algorithm(); v != "" {
+		var alg jwa.SignatureAlgorithm
+		if err := alg.Accept(v); err != nil {
+			return "", errors.Wrapf(err, `invalid algorithm %q`, v)
+		}
+		return alg, nil
+	}
+
+	switch key.KeyType() {
+	case jwa.RSA:
+		return rsaAlg, nil
+	case jwa.EC:
+		if v, ok := key.(curver); ok {
+			switch v.Crv() {
+			case jwa.P256:
+				return jwa.ES256, nil
+			case jwa.P384:
+				return jwa.ES384, nil
+			case jwa.P521:
+				return jwa.ES512, nil
+			}
+		}
+	case jwa.OKP:
+		if v, ok := key.(curver); ok && v.Crv() == jwa.Ed25519 {
+			return jwa.EdDSA, nil
+		}
+	case jwa.OctetSeq:
+		return jwa.HS256, nil
+	}
+	return "", errors.Errorf(`unable to determine signature algorithm for key type %q`, key.KeyType())
+}
+
+// checkCreated makes sure that the creation time of a proof, in
+// seconds since the epoch, is within AcceptableSkew of the current time
+func checkCreated(created int64) error {
+	d := time.Since(time.Unix(created, 0))
+	if d < 0 {
+		d = -d
+	}
+	if d > AcceptableSkew {
+		return errors.New(`proof creation time is outside of the acceptable window`)
+	}
+	return nil
+}
+
+// readBody reads the entire request body, and replaces it so that
+// it can be read again by subsequent handlers
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if req.GetBody != nil {
+		rdr, err := req.GetBody()
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to obtain request body`)
+		}
+		defer rdr.Close()
+		return ioutil.ReadAll(rdr)
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to read request body`)
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	return buf, nil
+}
+
+// targetURI returns the full URI of the request. For incoming
+// requests the scheme and host are reconstructed from the request
+func targetURI(req *http.Request) string {
+	if req.URL.IsAbs() {
+		u := *req.URL
+		if u.Path == "" && u.RawPath == "" {
+			// An empty path is sent as "/" over the wire
+			u.Path = "/"
+		}
+		return u.String()
+	}
+
+	scheme := `http`
+	if req.TLS != nil {
+		scheme = `https`
+	}
+	return scheme + `://` + req.Host + req.URL.RequestURI()
+}
@@ -0,0 +1,268 @@
+package proof_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/gnap/proof"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/stretchr/testify/assert"
+)
+
+func newKey(t *testing.T, form gnap.ProofForm, raw interface{}) *gnap.Key {
+	t.Helper()
+
+	jwkey, err := jwk.New(raw)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		t.FailNow()
+	}
+	//nolint:errcheck
+	jwkey.Set(jwk.KeyIDKey, `gnap-test-key`)
+
+	var key gnap.Key
+	key.SetJWK(jwkey)
+	key.SetProof(&form)
+	return &key
+}
+
+func testKeys(t *testing.T, form gnap.ProofForm) map[string]*gnap.Key {
+	t.Helper()
+
+	rsakey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+		t.FailNow()
+	}
+	eckey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		t.FailNow()
+	}
+	_, edkey, err := ed25519.GenerateKey(rand.Reader)
+	if !assert.NoError(t, err, `ed25519.GenerateKey should succeed`) {
+		t.FailNow()
+	}
+
+	return map[string]*gnap.Key{
+		`RSA`:     newKey(t, form, rsakey),
+		`ECDSA`:   newKey(t, form, eckey),
+		`Ed25519`: newKey(t, form, edkey),
+	}
+}
+
+func TestProof(t *testing.T) {
+	const body = `{"access_token":{"access":["dolphin-metadata"]}}`
+	for _, form := range []gnap.ProofForm{gnap.HTTPSig, gnap.DetachedJWS, gnap.AttachedJWS, gnap.Dpop} {
+		form := form
+		t.Run(string(form), func(t *testing.T) {
+			for name, key := range testKeys(t, form) {
+				key := key
+				t.Run(name, func(t *testing.T) {
+					signer, err := proof.NewSigner(key)
+					if !assert.NoError(t, err, `proof.NewSigner should succeed`) {
+						return
+					}
+
+					newRequest := func(t *testing.T, method, body string) *http.Request {
+						t.Helper()
+						var rdr io.Reader
+						if body != "" {
+							rdr = strings.NewReader(body)
+						}
+						req, err := http.NewRequest(method, `https://server.example.com/gnap`, rdr)
+						if !assert.NoError(t, err, `http.NewRequest should succeed`) {
+							t.FailNow()
+						}
+						req.Header.Set(`Authorization`, `GNAP 80UPRY5NM33OMUKMKSKU`)
+						if !assert.NoError(t, signer.Sign(req), `Sign should succeed`) {
+							t.FailNow()
+						}
+						return req
+					}
+
+					t.Run("Valid", func(t *testing.T) {
+						req := newRequest(t, http.MethodPost, body)
+						if !assert.NoError(t, proof.Verify(req, key), `Verify should succeed`) {
+							return
+						}
+						received, err := ioutil.ReadAll(req.Body)
+						if !assert.NoError(t, err, `reading body should succeed`) {
+							return
+						}
+						if !assert.Equal(t, body, string(received), `body should be readable after verification`) {
+							return
+						}
+					})
+					t.Run("Valid Without Body", func(t *testing.T) {
+						req := newRequest(t, http.MethodDelete, "")
+						if !assert.NoError(t, proof.Verify(req, key), `Verify should succeed`) {
+							return
+						}
+					})
+					t.Run("Tampered Body", func(t *testing.T) {
+						if form == gnap.Dpop {
+							t.Skip(`DPoP proofs do not cover the request body`)
+						}
+						req := newRequest(t, http.MethodPost, body)
+						req.GetBody = nil
+						req.Body = ioutil.NopCloser(strings.NewReader(`{"access_token":{"access":["walrus-metadata"]}}`))
+						if !assert.Error(t, proof.Verify(req, key), `Verify should fail`) {
+							return
+						}
+					})
+					t.Run("Tampered Method", func(t *testing.T) {
+						req := newRequest(t, http.MethodPost, body)
+						req.Method = http.MethodPatch
+						if !assert.Error(t, proof.Verify(req, key), `Verify should fail`) {
+							return
+						}
+					})
+					t.Run("Tampered Token", func(t *testing.T) {
+						req := newRequest(t, http.MethodPost, body)
+						req.Header.Set(`Authorization`, `GNAP OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0`)
+						if !assert.Error(t, proof.Verify(req, key), `Verify should fail`) {
+							return
+						}
+					})
+				})
+			}
+		})
+	}
+}
+
+func TestDPoPReplay(t *testing.T) {
+	for name, key := range testKeys(t, gnap.Dpop) {
+		key := key
+		t.Run(name, func(t *testing.T) {
+			signer, err := proof.NewDPoPSigner(key)
+			if !assert.NoError(t, err, `proof.NewDPoPSigner should succeed`) {
+				return
+			}
+
+			req, err := http.NewRequest(http.MethodGet, `https://resource.example.com/photos?page=2`, nil)
+			if !assert.NoError(t, err, `http.NewRequest should succeed`) {
+				return
+			}
+			req.Header.Set(`Authorization`, `GNAP OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0`)
+			if !assert.NoError(t, signer.Sign(req), `Sign should succeed`) {
+				return
+			}
+
+			v := proof.NewDPoPVerifier(proof.NewMemoryReplayCache())
+			if !assert.NoError(t, v.Verify(req, key), `first Verify should succeed`) {
+				return
+			}
+			if !assert.Error(t, v.Verify(req, key), `second Verify should fail`) {
+				return
+			}
+
+			generic := proof.NewVerifier(proof.NewMemoryReplayCache())
+			if !assert.NoError(t, generic.Verify(req, key), `first Verify should succeed`) {
+				return
+			}
+			if !assert.Error(t, generic.Verify(req, key), `second Verify should fail`) {
+				return
+			}
+		})
+	}
+}
+
+func TestHTTPSigCreated(t *testing.T) {
+	key := testKeys(t, gnap.HTTPSig)[`ECDSA`]
+	signer, err := proof.NewSigner(key)
+	if !assert.NoError(t, err, `proof.NewSigner should succeed`) {
+		return
+	}
+
+	createdRx := regexp.MustCompile(`;created=\d+`)
+	testcases := []struct {
+		Name    string
+		Created string
+		Error   string
+	}{
+		{
+			Name:    "Missing",
+			Created: ``,
+			Error:   `"created" is required`,
+		},
+		{
+			Name:    "Stale",
+			Created: `;created=` + strconv.FormatInt(time.Now().Add(-2*proof.AcceptableSkew).Unix(), 10),
+			Error:   `outside of the acceptable window`,
+		},
+		{
+			Name:    "Future",
+			Created: `;created=` + strconv.FormatInt(time.Now().Add(2*proof.AcceptableSkew).Unix(), 10),
+			Error:   `outside of the acceptable window`,
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, `https://server.example.com/gnap`, nil)
+			if !assert.NoError(t, err, `http.NewRequest should succeed`) {
+				return
+			}
+			if !assert.NoError(t, signer.Sign(req), `Sign should succeed`) {
+				return
+			}
+			req.Header.Set(`Signature-Input`, createdRx.ReplaceAllString(req.Header.Get(`Signature-Input`), tc.Created))
+
+			err = proof.VerifyHTTPSig(req, key)
+			if !assert.Error(t, err, `VerifyHTTPSig should fail`) {
+				return
+			}
+			if !assert.Contains(t, err.Error(), tc.Error, `error should mention the created parameter`) {
+				return
+			}
+		})
+	}
+}
+
+func TestHTTPSigContentDigest(t *testing.T) {
+	key := testKeys(t, gnap.HTTPSig)[`ECDSA`]
+	signer, err := proof.NewSigner(key)
+	if !assert.NoError(t, err, `proof.NewSigner should succeed`) {
+		return
+	}
+
+	const body = `{"access_token":{"access":["photos"]}}`
+	sum := sha256.Sum256([]byte(body))
+	sha256Digest := `sha-256=:` + base64.StdEncoding.EncodeToString(sum[:]) + `:`
+	wrongDigest := `sha-512=:` + base64.StdEncoding.EncodeToString(make([]byte, 64)) + `:`
+
+	// every supported digest must match, regardless of the order in
+	// which they appear in the header
+	for _, header := range []string{sha256Digest + `, ` + wrongDigest, wrongDigest + `, ` + sha256Digest} {
+		for i := 0; i < 10; i++ {
+			req, err := http.NewRequest(http.MethodPost, `https://server.example.com/gnap`, strings.NewReader(body))
+			if !assert.NoError(t, err, `http.NewRequest should succeed`) {
+				return
+			}
+			if !assert.NoError(t, signer.Sign(req), `Sign should succeed`) {
+				return
+			}
+			req.Header.Set(`Content-Digest`, header)
+
+			err = proof.VerifyHTTPSig(req, key)
+			if !assert.Error(t, err, `VerifyHTTPSig should fail`) {
+				return
+			}
+			if !assert.Contains(t, err.Error(), `digest mismatch for "sha-512"`, `error should mention the wrong digest`) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package rs
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownReference is returned by registries when an access
+// reference is not registered
+var ErrUnknownReference = errors.New(`unknown access reference`)
+
+// Registry resolves access references (the string form of access
+// entries) into the access that they stand for
+type Registry interface {
+	LookupAccess(ctx context.Context, ref gnap.ResourceAccessReference) (*gnap.ResourceAccess, error)
+}
+
+// RegistryMap is a Registry backed by a map
+type RegistryMap map[gnap.ResourceAccessReference]gnap.ResourceAccess
+
+func (m RegistryMap) LookupAccess(_ context.Context, ref gnap.ResourceAccessReference) (*gnap.ResourceAccess, error) {
+	access, ok := m[ref]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownReference, `access reference %q`, ref)
+	}
+	return &access, nil
+}
+
+// Decision is the result of matching the access required by a request
+// against the access granted to a token
+type Decision struct {
+	// Allowed is true when the request is allowed
+	Allowed bool
+
+	// Entry is the granted access entry that allowed the request
+	Entry gnap.AccessEntry
+
+	// Access is the access that allowed the request. When Entry is a
+	// reference, this is the access that the reference resolved to,
+	// or nil if it could not be resolved. It is nil when the request
+	// is not allowed
+	Access *gnap.ResourceAccess
+}
+
+// Matcher decides whether the access granted to a token covers the
+// access required by a request.
+//
+// A granted entry covers the required access when the types are
+// equal, and each of the actions, locations and datatypes of the
+// required access is included in the granted entry. Locations are
+// compared as URL prefixes, so that access granted to
+// `https://example.com/photos/` covers `https://example.com/photos/1`.
+// Fields that are omitted in the granted entry are not restricted,
+// and fields that are omitted in the required access are not checked.
+//
+// Granted entries that are references are resolved using the
+// registry. References that are not registered never match.
+type Matcher struct {
+	registry Registry
+}
+
+// NewMatcher creates a new Matcher. Use WithRegistry to resolve
+// access references.
+func NewMatcher(options ...MatcherOption) *Matcher {
+	var registry Registry
+	for _, option := range options {
+		switch option.Ident() {
+		case identRegistry{}:
+			registry = option.Value().(Registry)
+		}
+	}
+
+	return &Matcher{
+		registry: registry,
+	}
+}
+
+// Match matches `required` against each entry in `granted`, and
+// returns a Decision with the first entry that covers it
+func (m *Matcher) Match(ctx context.Context, granted []gnap.AccessEntry, required *gnap.ResourceAccess) (*Decision, error) {
+	if required == nil {
+		return nil, errors.New(`required access must not be nil`)
+	}
+
+	for _, entry := range granted {
+		access, err := m.resolve(ctx, entry)
+		if err != nil {
+			return nil, err
+		}
+		if access != nil && covers(access, required) {
+			return &Decision{
+				Allowed: true,
+				Entry:   entry,
+				Access:  access,
+			}, nil
+		}
+	}
+	return &Decision{}, nil
+}
+
+// MatchReference matches the access that `ref` stands for against
+// `granted`. Granted entries that are the same reference match
+// without consulting the registry.
+func (m *Matcher) MatchReference(ctx context.Context, granted []gnap.AccessEntry, ref gnap.ResourceAccessReference) (*Decision, error) {
+	if ref == "" {
+		return nil, errors.New(`access reference must not be empty`)
+	}
+
+	required, err := m.resolve(ctx, gnap.NewAccessReference(ref))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range granted {
+		if entry.IsReference() && entry.Reference() == ref {
+			return &Decision{
+				Allowed: true,
+				Entry:   entry,
+				Access:  required,
+			}, nil
+		}
+	}
+
+	if required == nil {
+		return nil, errors.Wrapf(ErrUnknownReference, `access reference %q`, ref)
+	}
+	return m.Match(ctx, granted, required)
+}
+
+// resolve returns the ResourceAccess object for `entry`. It returns
+// nil if `entry` is a reference that cannot be resolved
+func (m *Matcher) resolve(ctx context.Context, entry gnap.AccessEntry) (*gnap.ResourceAccess, error) {
+	if !entry.IsReference() {
+		return entry.Object(), nil
+	}
+
+	if m.registry == nil {
+		return nil, nil
+	}
+
+	access, err := m.registry.LookupAccess(ctx, entry.Reference())
+	if err != nil {
+		if errors.Is(err, ErrUnknownReference) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, `failed to resolve access reference`)
+	}
+	return access, nil
+}
+
+// Authorize matches `required` against the access of the token that
+// was validated by Middleware. If `required` does not specify any
+// locations, the URL of the request is used.
+func (m *Matcher) Authorize(r *http.Request, required *gnap.ResourceAccess) (*Decision, error) {
+	if required == nil {
+		return nil, errors.New(`required access must not be nil`)
+	}
+
+	if len(required.Locations()) == 0 {
+		var withLocation gnap.ResourceAccess
+		withLocation.SetType(required.Type())
+		withLocation.AddActions(required.Actions()...)
+		withLocation.AddDataTypes(required.DataTypes()...)
+		if v := required.Identifier(); v != "" {
+			withLocation.SetIdentifier(v)
+		}
+		withLocation.AddLocations(requestURL(r))
+		required = &withLocation
+	}
+	return m.Match(r.Context(), AccessFromContext(r.Context()), required)
+}
+
+func covers(granted, required *gnap.ResourceAccess) bool {
+	if granted.Type() != required.Type() {
+		return false
+	}
+
+	if v := granted.Identifier(); v != "" && v != required.Identifier() {
+		return false
+	}
+
+	if !containsAll(granted.Actions(), required.Actions()) {
+		return false
+	}
+
+	if !containsAll(granted.DataTypes(), required.DataTypes()) {
+		return false
+	}
+
+	if locations := granted.Locations(); len(locations) > 0 {
+		for _, location := range required.Locations() {
+			if !matchAnyLocation(locations, location) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// containsAll reports whether each element of `required` is in
+// `granted`. An empty `granted` does not restrict anything
+func containsAll(granted, required []string) bool {
+	if len(granted) == 0 {
+		return true
+	}
+
+	for _, v := range required {
+		var found bool
+		for _, g := range granted {
+			if g == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAnyLocation(granted []string, location string) bool {
+	for _, g := range granted {
+		if matchLocation(g, location) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLocation reports whether `location` is `granted`, or is below
+// it. Paths are compared by segment, so that `/photos` does not match
+// `/photos-private`.
+//
+// Paths containing dot segments or encoded slashes, in either their
+// literal or percent-encoded form, never match: they could escape the
+// granted location depending on how the request is routed
+func matchLocation(granted, location string) bool {
+	gu, err := url.Parse(granted)
+	if err != nil {
+		return false
+	}
+	lu, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+
+	if !strings.EqualFold(gu.Scheme, lu.Scheme) || !strings.EqualFold(gu.Host, lu.Host) {
+		return false
+	}
+
+	gpath, ok := locationPath(gu)
+	if !ok {
+		return false
+	}
+	lpath, ok := locationPath(lu)
+	if !ok {
+		return false
+	}
+
+	if !strings.HasPrefix(lpath, gpath) {
+		return false
+	}
+	return len(lpath) == len(gpath) || strings.HasSuffix(gpath, "/") || lpath[len(gpath)] == '/'
+}
+
+// locationPath returns the decoded path of `u`, or false if the path
+// is ambiguous
+func locationPath(u *url.URL) (string, bool) {
+	if strings.Contains(strings.ToLower(u.EscapedPath()), `%2f`) {
+		return "", false
+	}
+
+	p := u.Path
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "." || segment == ".." {
+			return "", false
+		}
+	}
+
+	if p == "" {
+		p = "/"
+	}
+	return p, true
+}
+
+func requestURL(r *http.Request) string {
+	u := url.URL{
+		Scheme:  `http`,
+		Host:    r.Host,
+		Path:    r.URL.Path,
+		RawPath: r.URL.RawPath,
+	}
+	if r.TLS != nil {
+		u.Scheme = `https`
+	}
+	return u.String()
+}
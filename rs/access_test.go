@@ -0,0 +1,208 @@
+package rs_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/gnap/rs"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAccess(typ string, actions, locations, datatypes []string, identifier string) gnap.ResourceAccess {
+	var ra gnap.ResourceAccess
+	ra.SetType(typ)
+	ra.AddActions(actions...)
+	ra.AddLocations(locations...)
+	ra.AddDataTypes(datatypes...)
+	if identifier != "" {
+		ra.SetIdentifier(identifier)
+	}
+	return ra
+}
+
+func TestMatcher(t *testing.T) {
+	granted := []gnap.AccessEntry{
+		gnap.NewAccessEntry(newAccess(`photo-api`, []string{`read`}, []string{`https://server.example.net/photos/`}, []string{`metadata`, `images`}, ``)),
+		gnap.NewAccessEntry(newAccess(`photo-api`, []string{`read`, `write`}, []string{`https://server.example.net/albums`}, nil, `album-1`)),
+		gnap.NewAccessReference(`video-read`),
+		gnap.NewAccessReference(`album-admin`),
+	}
+
+	ctx := context.Background()
+	m := rs.NewMatcher(rs.WithRegistry(rs.RegistryMap{
+		`photo-read`:  newAccess(`photo-api`, []string{`read`}, nil, nil, ``),
+		`photo-write`: newAccess(`photo-api`, []string{`write`}, []string{`https://server.example.net/photos/1`}, nil, ``),
+		`video-read`:  newAccess(`video-api`, []string{`read`}, nil, nil, ``),
+	}))
+
+	testcases := []struct {
+		Name     string
+		Required gnap.ResourceAccess
+		Allowed  bool
+		Index    int
+	}{
+		{
+			Name:     "Type and Action",
+			Required: newAccess(`photo-api`, []string{`read`}, nil, nil, ``),
+			Allowed:  true,
+			Index:    0,
+		},
+		{
+			Name:     "Location Prefix",
+			Required: newAccess(`photo-api`, []string{`read`}, []string{`https://server.example.net/photos/1`}, []string{`images`}, ``),
+			Allowed:  true,
+			Index:    0,
+		},
+		{
+			Name:     "Location Segment",
+			Required: newAccess(`photo-api`, []string{`read`}, []string{`https://server.example.net/albums/1`}, nil, `album-1`),
+			Allowed:  true,
+			Index:    1,
+		},
+		{
+			Name:     "Location Partial Segment",
+			Required: newAccess(`photo-api`, []string{`read`}, []string{`https://server.example.net/albums-private`}, nil, `album-1`),
+		},
+		{
+			Name:     "Location Dot Segment",
+			Required: newAccess(`photo-api`, []string{`read`}, []string{`https://server.example.net/photos/../admin`}, nil, ``),
+		},
+		{
+			Name:     "Location Encoded Dot Segment",
+			Required: newAccess(`photo-api`, []string{`read`}, []string{`https://server.example.net/photos/%2e%2e/admin`}, nil, ``),
+		},
+		{
+			Name:     "Location Encoded Slash",
+			Required: newAccess(`photo-api`, []string{`read`}, []string{`https://server.example.net/photos/..%2Fadmin`}, nil, ``),
+		},
+		{
+			Name:     "Location Other Host",
+			Required: newAccess(`photo-api`, []string{`read`}, []string{`https://other.example.net/photos/1`}, nil, ``),
+		},
+		{
+			Name:     "Action",
+			Required: newAccess(`photo-api`, []string{`write`}, []string{`https://server.example.net/photos/1`}, nil, ``),
+		},
+		{
+			Name:     "DataType",
+			Required: newAccess(`photo-api`, []string{`read`}, []string{`https://server.example.net/photos/1`}, []string{`location`}, ``),
+		},
+		{
+			Name:     "Identifier",
+			Required: newAccess(`photo-api`, []string{`write`}, []string{`https://server.example.net/albums`}, nil, `album-2`),
+		},
+		{
+			Name:     "Granted Reference",
+			Required: newAccess(`video-api`, []string{`read`}, []string{`https://server.example.net/videos/1`}, nil, ``),
+			Allowed:  true,
+			Index:    2,
+		},
+		{
+			Name:     "Type",
+			Required: newAccess(`audio-api`, []string{`read`}, nil, nil, ``),
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			decision, err := m.Match(ctx, granted, &tc.Required)
+			if !assert.NoError(t, err, `Match should succeed`) {
+				return
+			}
+			if !assert.Equal(t, tc.Allowed, decision.Allowed, `decision should match`) {
+				return
+			}
+			if !tc.Allowed {
+				if !assert.Nil(t, decision.Access, `access should be nil`) {
+					return
+				}
+				return
+			}
+			if !assert.Equal(t, granted[tc.Index], decision.Entry, `matching entry should be returned`) {
+				return
+			}
+			if !assert.NotNil(t, decision.Access, `matching access should be returned`) {
+				return
+			}
+		})
+	}
+
+	t.Run("Reference", func(t *testing.T) {
+		decision, err := m.MatchReference(ctx, granted, `photo-read`)
+		if !assert.NoError(t, err, `MatchReference should succeed`) {
+			return
+		}
+		if !assert.True(t, decision.Allowed, `request should be allowed`) {
+			return
+		}
+
+		decision, err = m.MatchReference(ctx, granted, `photo-write`)
+		if !assert.NoError(t, err, `MatchReference should succeed`) {
+			return
+		}
+		if !assert.False(t, decision.Allowed, `request should not be allowed`) {
+			return
+		}
+
+		decision, err = m.MatchReference(ctx, granted, `album-admin`)
+		if !assert.NoError(t, err, `MatchReference should succeed`) {
+			return
+		}
+		if !assert.True(t, decision.Allowed, `request should be allowed by the same reference`) {
+			return
+		}
+
+		_, err = m.MatchReference(ctx, granted, `unknown`)
+		if !assert.True(t, errors.Is(err, rs.ErrUnknownReference), `error should be rs.ErrUnknownReference`) {
+			return
+		}
+
+		// the zero value of AccessEntry is not a reference to ""
+		withEmpty := append([]gnap.AccessEntry{{}}, granted...)
+		if _, err := m.MatchReference(ctx, withEmpty, ``); !assert.Error(t, err, `empty reference should be rejected`) {
+			return
+		}
+	})
+	t.Run("Authorize", func(t *testing.T) {
+		middleware := rs.New(rs.ResolverFunc(func(_ context.Context, value string) (*rs.Token, error) {
+			return &rs.Token{Value: value, Access: granted, Flags: []gnap.AccessTokenAttribute{gnap.Bearer}}, nil
+		}))
+		required := newAccess(`photo-api`, []string{`read`}, nil, nil, ``)
+
+		var decision *rs.Decision
+		h := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var err error
+			decision, err = m.Authorize(r, &required)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+
+		paths := map[string]bool{
+			`/photos/1`:            true,
+			`/videos/1`:            false,
+			`/photos/../admin`:     false,
+			`/photos/%2e%2e/admin`: false,
+			`/photos/%2E%2E/admin`: false,
+			`/photos/..%2fadmin`:   false,
+			`/photos/./1`:          false,
+		}
+		for path, allowed := range paths {
+			req := httptest.NewRequest(http.MethodGet, `https://server.example.net`+path, nil)
+			req.Header.Set(`Authorization`, `GNAP bearer`)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if !assert.Equal(t, http.StatusOK, w.Code, `request should succeed`) {
+				return
+			}
+			if !assert.Equal(t, allowed, decision.Allowed, `decision for %s should match`, path) {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package rs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/gnap/proof"
+	"github.com/pkg/errors"
+)
+
+// DefaultCacheTTL is the default duration for which Introspector
+// caches introspection results. Caching is disabled by default, so that
+// a token revoked at the authorization server is rejected right away
+const DefaultCacheTTL = time.Duration(0)
+
+// maxResponseSize is the maximum size of introspection responses
+const maxResponseSize = 1 << 20
+
+// Introspector is a Resolver that looks up access tokens using the
+// token introspection endpoint of the authorization server. Results
+// for active tokens are cached when WithCacheTTL is given.
+type Introspector struct {
+	endpoint       string
+	httpcl         *http.Client
+	resourceServer string
+	signer         proof.Signer
+	ttl            time.Duration
+
+	mu    sync.Mutex
+	cache map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	token   *Token
+	expires time.Time
+}
+
+// NewIntrospector creates a new Introspector that sends introspection
+// requests to `endpoint`.
+func NewIntrospector(endpoint string, options ...IntrospectOption) *Introspector {
+	httpcl := http.DefaultClient
+	ttl := DefaultCacheTTL
+	var resourceServer string
+	var signer proof.Signer
+	for _, option := range options {
+		switch option.Ident() {
+		case identCacheTTL{}:
+			ttl = option.Value().(time.Duration)
+		case identHTTPClient{}:
+			httpcl = option.Value().(*http.Client)
+		case identResourceServer{}:
+			resourceServer = option.Value().(string)
+		case identSigner{}:
+			signer = option.Value().(proof.Signer)
+		}
+	}
+
+	return &Introspector{
+		endpoint:       endpoint,
+		httpcl:         httpcl,
+		resourceServer: resourceServer,
+		signer:         signer,
+		ttl:            ttl,
+		cache:          make(map[string]*cacheEntry),
+	}
+}
+
+func (i *Introspector) Resolve(ctx context.Context, value string) (*Token, error) {
+	if token, ok := i.lookup(value); ok {
+		return copyToken(token)
+	}
+
+	res, err := i.introspect(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if active := res.Active(); active == nil || !*active {
+		return nil, ErrInvalidToken
+	}
+
+	token := &Token{
+		Value:  value,
+		Access: res.Access(),
+		Key:    res.Key(),
+		Flags:  res.Flags(),
+	}
+	if v := res.IssuedAt(); v != nil {
+		token.IssuedAt = time.Unix(*v, 0)
+	}
+	if v := res.ExpiresAt(); v != nil {
+		token.ExpiresAt = time.Unix(*v, 0)
+	}
+
+	cached, err := copyToken(token)
+	if err != nil {
+		return nil, err
+	}
+	i.store(cached)
+	return token, nil
+}
+
+// copyToken creates a deep copy of the token, so that callers cannot
+// modify the tokens held by the cache. The copy is made by encoding
+// the token to JSON and back, which also covers the access entries
+// and the key that are otherwise shared by pointer.
+func copyToken(t *Token) (*Token, error) {
+	buf, err := json.Marshal(t)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to encode token`)
+	}
+
+	var dup Token
+	if err := json.Unmarshal(buf, &dup); err != nil {
+		return nil, errors.Wrap(err, `failed to decode token`)
+	}
+	return &dup, nil
+}
+
+func (i *Introspector) introspect(ctx context.Context, value string) (*gnap.IntrospectionResponse, error) {
+	payload := gnap.NewIntrospectionRequest(value)
+	if i.resourceServer != "" {
+		payload.SetResourceServer(i.resourceServer)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to encode introspection request`)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create introspection request`)
+	}
+	req.Header.Set(`Content-Type`, `application/json`)
+	req.Header.Set(`Accept`, `application/json`)
+
+	if i.signer != nil {
+		if err := i.signer.Sign(req); err != nil {
+			return nil, errors.Wrap(err, `failed to sign introspection request`)
+		}
+	}
+
+	res, err := i.httpcl.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to send introspection request`)
+	}
+	defer res.Body.Close()
+
+	buf, err := ioutil.ReadAll(io.LimitReader(res.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to read introspection response`)
+	}
+	if len(buf) > maxResponseSize {
+		return nil, errors.New(`introspection response too large`)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf(`unexpected HTTP status %d from introspection endpoint`, res.StatusCode)
+	}
+
+	var ires gnap.IntrospectionResponse
+	if err := json.Unmarshal(buf, &ires); err != nil {
+		return nil, errors.Wrap(err, `failed to decode introspection response`)
+	}
+	return &ires, nil
+}
+
+func (i *Introspector) lookup(value string) (*Token, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	entry, ok := i.cache[value]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(i.cache, value)
+		return nil, false
+	}
+	return entry.token, true
+}
+
+func (i *Introspector) store(token *Token) {
+	if i.ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+	expires := now.Add(i.ttl)
+	if !token.ExpiresAt.IsZero() && token.ExpiresAt.Before(expires) {
+		expires = token.ExpiresAt
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for k, v := range i.cache {
+		if now.After(v.expires) {
+			delete(i.cache, k)
+		}
+	}
+	i.cache[token.Value] = &cacheEntry{
+		token:   token,
+		expires: expires,
+	}
+}
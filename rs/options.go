@@ -0,0 +1,105 @@
+package rs
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lestrrat-go/gnap/proof"
+	"github.com/lestrrat-go/option"
+)
+
+type identCacheTTL struct{}
+type identHTTPClient struct{}
+type identRegistry struct{}
+type identResourceServer struct{}
+type identSigner struct{}
+type identVerifier struct{}
+
+type Option interface {
+	option.Interface
+	rsOption()
+}
+
+type rsOption struct {
+	option.Interface
+}
+
+func (*rsOption) rsOption() {}
+
+// IntrospectOption is an option that can be passed to NewIntrospector
+type IntrospectOption interface {
+	option.Interface
+	introspectOption()
+}
+
+type introspectOption struct {
+	option.Interface
+}
+
+func (*introspectOption) introspectOption() {}
+
+// MatcherOption is an option that can be passed to NewMatcher
+type MatcherOption interface {
+	option.Interface
+	matcherOption()
+}
+
+type matcherOption struct {
+	option.Interface
+}
+
+func (*matcherOption) matcherOption() {}
+
+// WithCacheTTL specifies how long introspection results are cached.
+// Results are never cached beyond the expiration of the token, but a
+// token revoked at the authorization server stays accepted until its
+// cached result expires, so keep it short. A value of 0 disables
+// caching. The default is DefaultCacheTTL, which disables caching
+func WithCacheTTL(v time.Duration) IntrospectOption {
+	return &introspectOption{
+		option.New(identCacheTTL{}, v),
+	}
+}
+
+// WithHTTPClient specifies the HTTP client used to send introspection
+// requests
+func WithHTTPClient(v *http.Client) IntrospectOption {
+	return &introspectOption{
+		option.New(identHTTPClient{}, v),
+	}
+}
+
+// WithResourceServer specifies the identifier of the resource server,
+// which is sent in the `resource_server` field of introspection
+// requests
+func WithResourceServer(v string) IntrospectOption {
+	return &introspectOption{
+		option.New(identResourceServer{}, v),
+	}
+}
+
+// WithSigner specifies the signer used to prove possession of the
+// key of the resource server in introspection requests
+func WithSigner(v proof.Signer) IntrospectOption {
+	return &introspectOption{
+		option.New(identSigner{}, v),
+	}
+}
+
+// WithVerifier specifies the verifier used to check the key proofing
+// of requests made with bound access tokens. The default verifier is
+// created by proof.NewVerifier with a proof.MemoryReplayCache. Custom
+// verifiers are responsible for their own replay protection
+func WithVerifier(v proof.Verifier) Option {
+	return &rsOption{
+		option.New(identVerifier{}, v),
+	}
+}
+
+// WithRegistry specifies the registry used to resolve access
+// references
+func WithRegistry(v Registry) MatcherOption {
+	return &matcherOption{
+		option.New(identRegistry{}, v),
+	}
+}
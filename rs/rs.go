@@ -0,0 +1,187 @@
+// Package rs implements the parts of a GNAP resource server that
+// validate access tokens presented by clients
+package rs
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/gnap/proof"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidToken is returned by resolvers when the access token is
+// unknown, expired, or has been revoked
+var ErrInvalidToken = errors.New(`invalid access token`)
+
+// Token describes an access token that has been resolved by the
+// resource server
+type Token struct {
+	// Value is the value of the access token
+	Value string
+
+	// Access is the access that was granted to the token
+	Access []gnap.AccessEntry
+
+	// Key is the key that the token is bound to. Requests made with
+	// the token must be signed with this key. It is nil for bearer
+	// tokens, which must have the "bearer" flag set.
+	Key *gnap.Key
+
+	// Flags are the attributes of the token, as reported by the
+	// authorization server
+	Flags []gnap.AccessTokenAttribute
+
+	// IssuedAt is the time the token was issued, if known
+	IssuedAt time.Time
+
+	// ExpiresAt is the time the token expires. The zero value means
+	// that the token does not expire
+	ExpiresAt time.Time
+}
+
+// IsBearer returns true if the token has the "bearer" flag
+func (t *Token) IsBearer() bool {
+	for _, flag := range t.Flags {
+		if flag == gnap.Bearer {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolver looks up the information associated with access tokens.
+// Implementations must return ErrInvalidToken (possibly wrapped)
+// when the token is not valid.
+type Resolver interface {
+	Resolve(ctx context.Context, value string) (*Token, error)
+}
+
+// ResolverFunc is a Resolver represented as a function
+type ResolverFunc func(context.Context, string) (*Token, error)
+
+func (fn ResolverFunc) Resolve(ctx context.Context, value string) (*Token, error) {
+	return fn(ctx, value)
+}
+
+// Middleware validates the access tokens sent to a resource server
+type Middleware struct {
+	resolver Resolver
+	verifier proof.Verifier
+}
+
+type contextKey struct{}
+
+// New creates a new Middleware, which uses `resolver` to look up the
+// access tokens presented by clients
+func New(resolver Resolver, options ...Option) *Middleware {
+	var verifier proof.Verifier
+	for _, option := range options {
+		switch option.Ident() {
+		case identVerifier{}:
+			verifier = option.Value().(proof.Verifier)
+		}
+	}
+
+	if verifier == nil {
+		verifier = proof.NewVerifier(proof.NewMemoryReplayCache())
+	}
+
+	return &Middleware{
+		resolver: resolver,
+		verifier: verifier,
+	}
+}
+
+// Wrap returns an http.Handler that calls `h` only when the request
+// carries a valid access token in the Authorization header, using
+// the GNAP scheme. If the token is bound to a key, the request must
+// be signed with that key. Tokens without a key are only accepted if
+// they have the "bearer" flag.
+//
+// The resolved token is available to `h` through TokenFromContext
+// and AccessFromContext. Requests that fail validation are rejected
+// with HTTP 401, and a WWW-Authenticate header.
+func (m *Middleware) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value, ok := gnapToken(r)
+		if !ok {
+			unauthorized(w, "")
+			return
+		}
+
+		token, err := m.Authenticate(r, value)
+		if err != nil {
+			unauthorized(w, `invalid_token`)
+			return
+		}
+
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, token)))
+	})
+}
+
+// Authenticate resolves the access token `value`, and verifies that
+// it can be used for the request.
+func (m *Middleware) Authenticate(r *http.Request, value string) (*Token, error) {
+	token, err := m.resolver.Resolve(r.Context(), value)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to resolve access token`)
+	}
+
+	if !token.ExpiresAt.IsZero() && time.Now().After(token.ExpiresAt) {
+		return nil, errors.Wrap(ErrInvalidToken, `access token has expired`)
+	}
+
+	// a token without a key is only accepted when it is explicitly
+	// marked as a bearer token, so that a resolver that fails to
+	// report the key does not turn a bound token into a bearer token
+	if token.Key == nil {
+		if !token.IsBearer() {
+			return nil, errors.Wrap(ErrInvalidToken, `access token is not bound to a key, and is not a bearer token`)
+		}
+		return token, nil
+	}
+
+	if err := m.verifier.Verify(r, token.Key); err != nil {
+		return nil, errors.Wrap(err, `failed to verify key proofing`)
+	}
+	return token, nil
+}
+
+// TokenFromContext returns the access token that was validated by
+// Middleware, or nil if there is none
+func TokenFromContext(ctx context.Context) *Token {
+	token, _ := ctx.Value(contextKey{}).(*Token)
+	return token
+}
+
+// AccessFromContext returns the access granted to the access token
+// that was validated by Middleware
+func AccessFromContext(ctx context.Context) []gnap.AccessEntry {
+	if token := TokenFromContext(ctx); token != nil {
+		return token.Access
+	}
+	return nil
+}
+
+func gnapToken(r *http.Request) (string, bool) {
+	v := r.Header.Get(`Authorization`)
+	if len(v) < 5 || !strings.EqualFold(v[:5], `GNAP `) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(v[5:])
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter, code string) {
+	challenge := `GNAP`
+	if code != "" {
+		challenge += ` error="` + code + `"`
+	}
+	w.Header().Set(`WWW-Authenticate`, challenge)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
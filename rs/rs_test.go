@@ -0,0 +1,355 @@
+package rs_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/gnap/proof"
+	"github.com/lestrrat-go/gnap/rs"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/stretchr/testify/assert"
+)
+
+func newKey(t *testing.T, form gnap.ProofForm) *gnap.Key {
+	t.Helper()
+
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		t.FailNow()
+	}
+	jwkey, err := jwk.New(raw)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		t.FailNow()
+	}
+
+	var key gnap.Key
+	key.SetProof(&form)
+	key.SetJWK(jwkey)
+	return &key
+}
+
+// newCertKey creates a self-signed client certificate, and the mtls
+// key that refers to it by thumbprint
+func newCertKey(t *testing.T) (*gnap.Key, tls.Certificate) {
+	t.Helper()
+
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		t.FailNow()
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: `gnap-client`},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &raw.PublicKey, raw)
+	if !assert.NoError(t, err, `x509.CreateCertificate should succeed`) {
+		t.FailNow()
+	}
+	cert, err := x509.ParseCertificate(der)
+	if !assert.NoError(t, err, `x509.ParseCertificate should succeed`) {
+		t.FailNow()
+	}
+
+	form := gnap.MutualTLS
+	var key gnap.Key
+	key.SetProof(&form)
+	key.SetCertS256(proof.CertificateThumbprint(cert))
+	return &key, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: raw}
+}
+
+func photoAccess() []gnap.AccessEntry {
+	var ra gnap.ResourceAccess
+	ra.SetType(`photo-api`)
+	ra.AddActions(`read`)
+	return []gnap.AccessEntry{gnap.NewAccessEntry(ra)}
+}
+
+func TestMiddleware(t *testing.T) {
+	tokens := make(map[string]*rs.Token)
+	resolver := rs.ResolverFunc(func(_ context.Context, value string) (*rs.Token, error) {
+		token, ok := tokens[value]
+		if !ok {
+			return nil, rs.ErrInvalidToken
+		}
+		return token, nil
+	})
+
+	m := rs.New(resolver)
+	srv := httptest.NewUnstartedServer(m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		access := rs.AccessFromContext(r.Context())
+		if len(access) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(access[0].Object().Type()))
+	})))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	// send sends a request with the given token, signed by `signer`.
+	// `certs` are presented as client certificates
+	send := func(t *testing.T, token string, signer proof.Signer, certs ...tls.Certificate) *http.Response {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, srv.URL+`/photos`, nil)
+		if !assert.NoError(t, err, `http.NewRequest should succeed`) {
+			t.FailNow()
+		}
+		if token != "" {
+			req.Header.Set(`Authorization`, `GNAP `+token)
+		}
+		if signer != nil {
+			if !assert.NoError(t, signer.Sign(req), `Sign should succeed`) {
+				t.FailNow()
+			}
+		}
+
+		transport := srv.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.Certificates = certs
+		res, err := (&http.Client{Transport: transport}).Do(req)
+		if !assert.NoError(t, err, `request should succeed`) {
+			t.FailNow()
+		}
+		return res
+	}
+
+	assertStatus := func(t *testing.T, res *http.Response, expected int) bool {
+		t.Helper()
+		defer res.Body.Close()
+
+		if !assert.Equal(t, expected, res.StatusCode, `status code should match`) {
+			return false
+		}
+		if expected == http.StatusUnauthorized {
+			return assert.True(t, strings.HasPrefix(res.Header.Get(`WWW-Authenticate`), `GNAP`), `WWW-Authenticate header should be set`)
+		}
+
+		body, _ := ioutil.ReadAll(res.Body)
+		return assert.Equal(t, `photo-api`, string(body), `access should be available to the handler`)
+	}
+
+	for _, form := range []gnap.ProofForm{gnap.HTTPSig, gnap.DetachedJWS, gnap.Dpop} {
+		form := form
+		t.Run(string(form), func(t *testing.T) {
+			key := newKey(t, form)
+			signer, err := proof.NewSigner(key)
+			if !assert.NoError(t, err, `proof.NewSigner should succeed`) {
+				return
+			}
+
+			value := `bound-` + string(form)
+			tokens[value] = &rs.Token{
+				Value:  value,
+				Access: photoAccess(),
+				Key:    key,
+			}
+
+			t.Run("Signed", func(t *testing.T) {
+				assertStatus(t, send(t, value, signer), http.StatusOK)
+			})
+			t.Run("Unsigned", func(t *testing.T) {
+				assertStatus(t, send(t, value, nil), http.StatusUnauthorized)
+			})
+			t.Run("Wrong Key", func(t *testing.T) {
+				other, err := proof.NewSigner(newKey(t, form))
+				if !assert.NoError(t, err, `proof.NewSigner should succeed`) {
+					return
+				}
+				assertStatus(t, send(t, value, other), http.StatusUnauthorized)
+			})
+		})
+	}
+	t.Run(string(gnap.MutualTLS), func(t *testing.T) {
+		key, cert := newCertKey(t)
+		tokens[`bound-mtls`] = &rs.Token{
+			Value:  `bound-mtls`,
+			Access: photoAccess(),
+			Key:    key,
+		}
+
+		t.Run("Signed", func(t *testing.T) {
+			assertStatus(t, send(t, `bound-mtls`, nil, cert), http.StatusOK)
+		})
+		t.Run("Unsigned", func(t *testing.T) {
+			assertStatus(t, send(t, `bound-mtls`, nil), http.StatusUnauthorized)
+		})
+		t.Run("Wrong Key", func(t *testing.T) {
+			_, other := newCertKey(t)
+			assertStatus(t, send(t, `bound-mtls`, nil, other), http.StatusUnauthorized)
+		})
+	})
+	t.Run("Bearer", func(t *testing.T) {
+		tokens[`bearer`] = &rs.Token{
+			Value:  `bearer`,
+			Access: photoAccess(),
+			Flags:  []gnap.AccessTokenAttribute{gnap.Bearer},
+		}
+		assertStatus(t, send(t, `bearer`, nil), http.StatusOK)
+	})
+	t.Run("Missing Key", func(t *testing.T) {
+		tokens[`keyless`] = &rs.Token{
+			Value:  `keyless`,
+			Access: photoAccess(),
+		}
+		assertStatus(t, send(t, `keyless`, nil), http.StatusUnauthorized)
+	})
+	t.Run("Expired", func(t *testing.T) {
+		tokens[`expired`] = &rs.Token{
+			Value:     `expired`,
+			Access:    photoAccess(),
+			Flags:     []gnap.AccessTokenAttribute{gnap.Bearer},
+			ExpiresAt: time.Now().Add(-time.Minute),
+		}
+		assertStatus(t, send(t, `expired`, nil), http.StatusUnauthorized)
+	})
+	t.Run("Unknown Token", func(t *testing.T) {
+		assertStatus(t, send(t, `unknown`, nil), http.StatusUnauthorized)
+	})
+	t.Run("Missing Token", func(t *testing.T) {
+		assertStatus(t, send(t, ``, nil), http.StatusUnauthorized)
+	})
+}
+
+func TestIntrospector(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		var req gnap.IntrospectionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.ResourceServer() != `photos` {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		res := gnap.NewIntrospectionResponse(req.AccessToken() == `active`)
+		if *res.Active() {
+			res.AddAccess(photoAccess()...)
+			exp := time.Now().Add(time.Hour).Unix()
+			res.SetExpiresAt(&exp)
+		}
+		w.Header().Set(`Content-Type`, `application/json`)
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	t.Run("Active Token", func(t *testing.T) {
+		calls = 0
+		introspector := rs.NewIntrospector(srv.URL, rs.WithResourceServer(`photos`), rs.WithCacheTTL(time.Minute))
+		for i := 0; i < 2; i++ {
+			token, err := introspector.Resolve(ctx, `active`)
+			if !assert.NoError(t, err, `Resolve should succeed`) {
+				return
+			}
+			if !assert.Equal(t, `photo-api`, token.Access[0].Object().Type(), `access should match`) {
+				return
+			}
+			if !assert.WithinDuration(t, time.Now().Add(time.Hour), token.ExpiresAt, time.Minute, `expiration should match`) {
+				return
+			}
+		}
+		if !assert.Equal(t, 1, calls, `result should be cached`) {
+			return
+		}
+	})
+	t.Run("Cached Token Is Copied", func(t *testing.T) {
+		introspector := rs.NewIntrospector(srv.URL, rs.WithResourceServer(`photos`), rs.WithCacheTTL(time.Minute))
+		for i := 0; i < 2; i++ {
+			token, err := introspector.Resolve(ctx, `active`)
+			if !assert.NoError(t, err, `Resolve should succeed`) {
+				return
+			}
+			if !assert.Len(t, token.Access, 1, `access should not be modified by previous callers`) {
+				return
+			}
+			if !assert.Equal(t, []string{`read`}, token.Access[0].Object().Actions(), `actions should not be modified by previous callers`) {
+				return
+			}
+			token.Access[0].Object().AddActions(`write`)
+			token.Access = append(token.Access, gnap.NewAccessReference(`admin`))
+		}
+	})
+	t.Run("Caching Disabled", func(t *testing.T) {
+		calls = 0
+		introspector := rs.NewIntrospector(srv.URL, rs.WithResourceServer(`photos`), rs.WithCacheTTL(0))
+		for i := 0; i < 2; i++ {
+			if _, err := introspector.Resolve(ctx, `active`); !assert.NoError(t, err, `Resolve should succeed`) {
+				return
+			}
+		}
+		if !assert.Equal(t, 2, calls, `result should not be cached`) {
+			return
+		}
+	})
+	t.Run("Caching Disabled By Default", func(t *testing.T) {
+		calls = 0
+		introspector := rs.NewIntrospector(srv.URL, rs.WithResourceServer(`photos`))
+		for i := 0; i < 2; i++ {
+			if _, err := introspector.Resolve(ctx, `active`); !assert.NoError(t, err, `Resolve should succeed`) {
+				return
+			}
+		}
+		if !assert.Equal(t, 2, calls, `result should not be cached`) {
+			return
+		}
+	})
+	t.Run("Inactive Token", func(t *testing.T) {
+		introspector := rs.NewIntrospector(srv.URL, rs.WithResourceServer(`photos`))
+		_, err := introspector.Resolve(ctx, `revoked`)
+		if !assert.True(t, errors.Is(err, rs.ErrInvalidToken), `error should be rs.ErrInvalidToken`) {
+			return
+		}
+	})
+	t.Run("Response Too Large", func(t *testing.T) {
+		large := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(`Content-Type`, `application/json`)
+			w.Write([]byte(`{"active":true,"x":"`))
+			w.Write(bytes.Repeat([]byte(`a`), 1<<20))
+			w.Write([]byte(`"}`))
+		}))
+		defer large.Close()
+
+		introspector := rs.NewIntrospector(large.URL, rs.WithResourceServer(`photos`))
+		_, err := introspector.Resolve(ctx, `active`)
+		if !assert.Error(t, err, `Resolve should fail`) {
+			return
+		}
+		if !assert.False(t, errors.Is(err, rs.ErrInvalidToken), `error should not be rs.ErrInvalidToken`) {
+			return
+		}
+	})
+	t.Run("Unknown Resource Server", func(t *testing.T) {
+		introspector := rs.NewIntrospector(srv.URL, rs.WithResourceServer(`videos`))
+		_, err := introspector.Resolve(ctx, `active`)
+		if !assert.Error(t, err, `Resolve should fail`) {
+			return
+		}
+		if !assert.False(t, errors.Is(err, rs.ErrInvalidToken), `error should not be rs.ErrInvalidToken`) {
+			return
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+)
+
+// ContinueHandler returns the http.Handler for the continuation
+// endpoint (see WithContinueEndpoint). Requests must carry the
+// continuation token in the Authorization header, and be signed with
+// the key that was used for the original grant request.
+//
+// POST continues the grant, and DELETE cancels it. Modifying a grant
+// with PATCH is not supported, and is rejected with a 405 response.
+//
+// Requests for the same grant are serialized within the server
+// process, so that a continuation token can only be used once to
+// obtain access tokens. Deployments running multiple server processes
+// against shared Storage must provide their own coordination.
+func (s *Server) ContinueHandler() http.Handler {
+	return http.HandlerFunc(s.handleContinue)
+}
+
+func (s *Server) handleContinue(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost, http.MethodDelete:
+	default:
+		w.Header().Set(`Allow`, http.MethodPost+`, `+http.MethodDelete)
+		writeError(w, http.StatusMethodNotAllowed, gnap.InvalidRequest)
+		return
+	}
+
+	ctx := r.Context()
+	token, ok := gnapToken(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidContinuation)
+		return
+	}
+
+	grant, err := s.storage.LookupGrantByContinuation(ctx, token)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidContinuation)
+		return
+	}
+
+	var req gnap.ContinueRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, gnap.InvalidRequest)
+		return
+	}
+
+	key, err := s.clientKey(ctx, grant.Request.Client())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidClient)
+		return
+	}
+
+	if err := s.verifier.Verify(r, key); err != nil {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidClient)
+		return
+	}
+
+	// look the grant up again while holding its lock, as a concurrent
+	// request may have consumed the continuation token in the meantime
+	unlock := s.locks.lock(grant.ID)
+	defer unlock()
+
+	grant, err = s.storage.LookupGrantByContinuation(ctx, token)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidContinuation)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := s.storage.DeleteGrant(ctx, grant.ID); err != nil {
+			writeServerError(w)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	switch grant.State {
+	case GrantPending:
+		res := gnap.NewGrantResponse()
+		res.SetContinue(s.continuation(grant))
+		writeResponse(w, http.StatusOK, res)
+	case GrantApproved:
+		if interactionFinish(grant.Request) != nil {
+			if subtle.ConstantTimeCompare([]byte(req.InteractRef()), []byte(grant.InteractRef)) != 1 {
+				writeError(w, http.StatusBadRequest, gnap.InvalidRequest)
+				return
+			}
+		}
+
+		res, err := s.approve(grant)
+		if err != nil {
+			writeServerError(w)
+			return
+		}
+
+		// the grant has been finalized, the continuation token
+		// cannot be used anymore
+		grant.ContinuationToken = ""
+		if err := s.storage.SaveGrant(ctx, grant); err != nil {
+			writeServerError(w)
+			return
+		}
+		writeResponse(w, http.StatusOK, res)
+	case GrantDenied:
+		grant.ContinuationToken = ""
+		grant.UpdatedAt = time.Now()
+		if err := s.storage.SaveGrant(ctx, grant); err != nil {
+			writeServerError(w)
+			return
+		}
+		writeError(w, http.StatusForbidden, gnap.UserDenied)
+	default:
+		writeError(w, http.StatusBadRequest, gnap.InvalidContinuation)
+	}
+}
+
+// gnapToken extracts the token sent with the GNAP scheme in the
+// Authorization header
+func gnapToken(r *http.Request) (string, bool) {
+	v := r.Header.Get(`Authorization`)
+	if len(v) < 5 || !strings.EqualFold(v[:5], `GNAP `) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(v[5:])
+	return token, token != ""
+}
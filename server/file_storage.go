@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// FileStorage is a Storage that keeps grants in a single JSON file.
+// All grants are held in memory, and the file is rewritten whenever a
+// grant is saved or deleted. It is suitable for small deployments
+// running a single server process.
+type FileStorage struct {
+	mu     sync.Mutex
+	path   string
+	memory *MemoryStorage
+}
+
+// NewFileStorage creates a FileStorage backed by the file at `path`.
+// If the file exists, grants are loaded from it.
+func NewFileStorage(path string) (*FileStorage, error) {
+	s := &FileStorage{
+		path:   path,
+		memory: NewMemoryStorage(),
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, errors.Wrapf(err, `failed to read %s`, path)
+	}
+
+	var grants []*Grant
+	if err := json.Unmarshal(buf, &grants); err != nil {
+		return nil, errors.Wrapf(err, `failed to decode %s`, path)
+	}
+	for _, g := range grants {
+		s.memory.grants[g.ID] = g
+	}
+	return s, nil
+}
+
+func (s *FileStorage) SaveGrant(ctx context.Context, g *Grant) error {
+	if g == nil || g.ID == "" {
+		return errors.New(`grant must have an ID`)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// write the file first, so that the grants in memory never
+	// diverge from what is on disk
+	if err := s.flush(g.ID, g); err != nil {
+		return err
+	}
+	return s.memory.SaveGrant(ctx, g)
+}
+
+func (s *FileStorage) LookupGrant(ctx context.Context, id string) (*Grant, error) {
+	return s.memory.LookupGrant(ctx, id)
+}
+
+func (s *FileStorage) LookupGrantByContinuation(ctx context.Context, token string) (*Grant, error) {
+	return s.memory.LookupGrantByContinuation(ctx, token)
+}
+
+func (s *FileStorage) LookupGrantByInteractRef(ctx context.Context, ref string) (*Grant, error) {
+	return s.memory.LookupGrantByInteractRef(ctx, ref)
+}
+
+func (s *FileStorage) LookupGrantByUserCode(ctx context.Context, code string) (*Grant, error) {
+	return s.memory.LookupGrantByUserCode(ctx, code)
+}
+
+func (s *FileStorage) LookupGrantByToken(ctx context.Context, value string) (*Grant, error) {
+	return s.memory.LookupGrantByToken(ctx, value)
+}
+
+func (s *FileStorage) DeleteGrant(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.memory.LookupGrant(ctx, id); err != nil {
+		return err
+	}
+	if err := s.flush(id, nil); err != nil {
+		return err
+	}
+	return s.memory.DeleteGrant(ctx, id)
+}
+
+// flush writes all grants to a temporary file, and then renames it
+// over the original file, so that the file is never left half-written.
+// The grant with the given `id` is replaced by `g`, or omitted if `g`
+// is nil, so that the file can be written before memory is updated.
+func (s *FileStorage) flush(id string, g *Grant) error {
+	s.memory.mu.RLock()
+	grants := make([]*Grant, 0, len(s.memory.grants)+1)
+	for _, stored := range s.memory.grants {
+		if stored.ID != id {
+			grants = append(grants, stored)
+		}
+	}
+	if g != nil {
+		grants = append(grants, g)
+	}
+	sort.Slice(grants, func(i, j int) bool {
+		return grants[i].ID < grants[j].ID
+	})
+	buf, err := json.MarshalIndent(grants, "", "  ")
+	s.memory.mu.RUnlock()
+	if err != nil {
+		return errors.Wrap(err, `failed to encode grants`)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+`.*`)
+	if err != nil {
+		return errors.Wrap(err, `failed to create temporary file`)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, `failed to write grants`)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, `failed to write grants`)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return errors.Wrapf(err, `failed to rename temporary file to %s`, s.path)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/pkg/errors"
+)
+
+// errUnsupportedInteraction is returned by startInteraction when the
+// interaction requested by the client cannot be started. Other errors
+// are failures of the server
+var errUnsupportedInteraction = errors.New(`unsupported interaction`)
+
+// startInteraction moves the grant to the pending state, and creates
+// the response that tells the client how the resource owner should
+// interact with the server
+func (s *Server) startInteraction(r *http.Request, grant *Grant) (*gnap.GrantResponse, error) {
+	if s.continueEndpoint == "" {
+		return nil, errors.New(`continuation endpoint is not configured`)
+	}
+
+	ir := gnap.NewInteractionResponse()
+	var started bool
+	for _, mode := range grant.Request.Interact().Start() {
+		switch mode {
+		case gnap.StartRedirect:
+			if s.interactEndpoint == "" {
+				continue
+			}
+			redirect, err := withQuery(s.interactEndpoint, url.Values{`id`: {grant.ID}})
+			if err != nil {
+				return nil, errors.Wrap(err, `invalid interaction endpoint`)
+			}
+			ir.SetRedirect(redirect)
+			started = true
+		case gnap.StartUserCode:
+			code, err := newUserCode()
+			if err != nil {
+				return nil, errors.Wrap(err, `failed to generate user code`)
+			}
+			uc := gnap.NewUserCode(code)
+			if s.userCodeEndpoint != "" {
+				uc.SetURL(s.userCodeEndpoint)
+			}
+			grant.UserCode = code
+			ir.SetUserCode(uc)
+			started = true
+		}
+	}
+	if !started {
+		return nil, errors.Wrap(errUnsupportedInteraction, `no supported interaction start mode`)
+	}
+
+	if finish := interactionFinish(grant.Request); finish != nil {
+		switch finish.Method() {
+		case gnap.FinishRedirect, gnap.FinishPush:
+		default:
+			return nil, errors.Wrapf(errUnsupportedInteraction, `unsupported interaction finish method %q`, finish.Method())
+		}
+
+		// make sure that the hash can be calculated once the
+		// interaction finishes
+		if !gnap.IsSupportedHashMethod(finish.HashMethod()) {
+			return nil, errors.Wrapf(errUnsupportedInteraction, `unsupported hash method %q`, finish.HashMethod())
+		}
+
+		nonce, err := randomString(16)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to generate server nonce`)
+		}
+		grant.ServerNonce = nonce
+		ir.SetFinish(nonce)
+	}
+
+	token, err := randomString(32)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to generate continuation token`)
+	}
+
+	grant.ContinuationToken = token
+	grant.GrantEndpoint = s.grantEndpoint
+	if grant.GrantEndpoint == "" {
+		grant.GrantEndpoint = requestURI(r)
+	}
+	grant.State = GrantPending
+	grant.UpdatedAt = time.Now()
+
+	res := gnap.NewGrantResponse()
+	res.SetContinue(s.continuation(grant))
+	res.SetInteract(ir)
+	return res, nil
+}
+
+// FinishInteraction records the decision of the resource owner for
+// the pending grant with the given ID. It is meant to be called by the
+// handler of the interaction endpoint (see WithInteractEndpoint).
+//
+// When the grant is approved and the client requested the "redirect"
+// finish method, the returned URI is where the resource owner should
+// be redirected to. It carries the `interact_ref` and `hash` query
+// parameters. Otherwise, the returned URI is empty.
+//
+// When the client requested the "push" finish method, the values are
+// delivered to the client using the Pusher of the server (see
+// WithPusher), and FinishInteraction blocks until the delivery either
+// succeeds or fails. A failed delivery does not revert the approval,
+// as the client may still poll the grant.
+func (s *Server) FinishInteraction(ctx context.Context, id string, approved bool) (string, error) {
+	grant, ref, hash, err := s.recordDecision(ctx, id, approved)
+	if err != nil || !approved {
+		return "", err
+	}
+
+	finish := interactionFinish(grant.Request)
+	if finish == nil {
+		return "", nil
+	}
+
+	switch finish.Method() {
+	case gnap.FinishRedirect:
+		redirect, err := withQuery(finish.URI(), url.Values{
+			`hash`:         {hash},
+			`interact_ref`: {ref},
+		})
+		if err != nil {
+			return "", errors.Wrap(err, `invalid finish URI`)
+		}
+		return redirect, nil
+	case gnap.FinishPush:
+		if err := s.pusher.Push(ctx, finish.URI(), ref, hash); err != nil {
+			return "", errors.Wrap(err, `failed to notify client`)
+		}
+	}
+	return "", nil
+}
+
+// recordDecision moves the pending grant with the given ID to the
+// approved or denied state. It returns the interaction reference and
+// hash that the client should be notified with, if approved.
+//
+// The grant is looked up while holding its lock, so that concurrent
+// decisions and cancellations of the same grant are serialized, and
+// only the first one succeeds. The lock is released before the client
+// is notified, as the client may continue the grant right away
+func (s *Server) recordDecision(ctx context.Context, id string, approved bool) (*Grant, string, string, error) {
+	unlock := s.locks.lock(id)
+	defer unlock()
+
+	grant, err := s.storage.LookupGrant(ctx, id)
+	if err != nil {
+		return nil, "", "", errors.Wrap(err, `failed to lookup grant`)
+	}
+
+	if grant.State != GrantPending {
+		return nil, "", "", errors.Errorf(`grant is not pending interaction (state = %s)`, grant.State)
+	}
+
+	// the user code can only be used once
+	grant.UserCode = ""
+	grant.UpdatedAt = time.Now()
+	if !approved {
+		grant.State = GrantDenied
+		if err := s.storage.SaveGrant(ctx, grant); err != nil {
+			return nil, "", "", errors.Wrap(err, `failed to save grant`)
+		}
+		return grant, "", "", nil
+	}
+
+	ref, err := randomString(16)
+	if err != nil {
+		return nil, "", "", errors.Wrap(err, `failed to generate interaction reference`)
+	}
+	grant.InteractRef = ref
+	grant.State = GrantApproved
+
+	var hash string
+	if finish := interactionFinish(grant.Request); finish != nil {
+		hash, err = gnap.CalculateInteractionHash(finish.HashMethod(), finish.Nonce(), grant.ServerNonce, ref, grant.GrantEndpoint)
+		if err != nil {
+			return nil, "", "", errors.Wrap(err, `failed to calculate interaction hash`)
+		}
+	}
+
+	// the grant must be saved before the client is notified, as the
+	// client may continue the grant right away
+	if err := s.storage.SaveGrant(ctx, grant); err != nil {
+		return nil, "", "", errors.Wrap(err, `failed to save grant`)
+	}
+	return grant, ref, hash, nil
+}
+
+// InteractFunc is called by the interaction handler with the pending
+// grant that the resource owner was redirected for, along with the
+// display information of the client as returned by
+// Server.ClientDisplay. If no pending grant matches, `grant` is nil
+// and `err` is non-nil.
+//
+// The function is responsible for writing the response to `w`,
+// typically a consent page that shows the client and the requested
+// access. Unless the client is pre-registered, `display` is provided
+// by the client itself, so it should not be presented as verified.
+// Once the resource owner has made a decision, call
+// Server.FinishInteraction with the ID of the grant.
+type InteractFunc func(w http.ResponseWriter, r *http.Request, grant *Grant, display *gnap.ClientDisplay, err error)
+
+// InteractHandler returns the http.Handler for the interaction
+// endpoint (see WithInteractEndpoint). The grant ID is read from the
+// `id` form value, which is added to the redirect URI by the server.
+func (s *Server) InteractHandler(fn InteractFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.FormValue(`id`)
+		if id == "" {
+			fn(w, r, nil, nil, errors.New(`grant ID is required`))
+			return
+		}
+
+		grant, err := s.storage.LookupGrant(r.Context(), id)
+		if err != nil {
+			fn(w, r, nil, nil, errors.Wrap(err, `failed to lookup grant`))
+			return
+		}
+
+		if grant.State != GrantPending {
+			fn(w, r, nil, nil, errors.Wrap(ErrGrantNotFound, `grant is not pending interaction`))
+			return
+		}
+
+		display, err := s.ClientDisplay(r.Context(), grant)
+		if err != nil {
+			fn(w, r, nil, nil, err)
+			return
+		}
+		fn(w, r, grant, display, nil)
+	})
+}
+
+// continuation creates the `continue` field for the grant
+func (s *Server) continuation(grant *Grant) *gnap.RequestContinuation {
+	var token gnap.AccessToken
+	token.SetValue(grant.ContinuationToken)
+	return gnap.NewRequestContinuation(token, s.continueEndpoint)
+}
+
+// interactionFinish returns the finish method requested by the client,
+// or nil if the client did not request to be notified
+func interactionFinish(req *gnap.GrantRequest) *gnap.InteractionFinish {
+	interact := req.Interact()
+	if interact == nil {
+		return nil
+	}
+
+	finish := interact.Finish()
+	if len(finish) == 0 {
+		return nil
+	}
+	return finish[0]
+}
+
+func withQuery(uri string, values url.Values) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", errors.Wrapf(err, `failed to parse %q`, uri)
+	}
+
+	q := u.Query()
+	for k, v := range values {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
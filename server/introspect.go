@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/gnap/rs"
+	"github.com/pkg/errors"
+)
+
+// IntrospectHandler returns the http.Handler for the token
+// introspection endpoint. Only the resource servers registered using
+// WithResourceServer may use the endpoint.
+//
+// Tokens that are unknown, expired, or revoked are reported as
+// inactive.
+func (s *Server) IntrospectHandler() http.Handler {
+	return http.HandlerFunc(s.handleIntrospect)
+}
+
+func (s *Server) handleIntrospect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set(`Allow`, http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, gnap.InvalidRequest)
+		return
+	}
+
+	var req gnap.IntrospectionRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, gnap.InvalidRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, gnap.InvalidRequest)
+		return
+	}
+
+	key, ok := s.resourceServers[req.ResourceServer()]
+	if !ok {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidClient)
+		return
+	}
+
+	if err := s.verifier.Verify(r, key); err != nil {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidClient)
+		return
+	}
+
+	token, err := s.Resolve(r.Context(), req.AccessToken())
+	if err != nil {
+		if !errors.Is(err, rs.ErrInvalidToken) {
+			writeServerError(w)
+			return
+		}
+		writeResponse(w, http.StatusOK, gnap.NewIntrospectionResponse(false))
+		return
+	}
+
+	if !token.ExpiresAt.IsZero() && time.Now().After(token.ExpiresAt) {
+		writeResponse(w, http.StatusOK, gnap.NewIntrospectionResponse(false))
+		return
+	}
+
+	res := gnap.NewIntrospectionResponse(true)
+	res.AddAccess(token.Access...)
+	res.SetKey(token.Key)
+	res.AddFlags(token.Flags...)
+	if !token.IssuedAt.IsZero() {
+		iat := token.IssuedAt.Unix()
+		res.SetIssuedAt(&iat)
+	}
+	if !token.ExpiresAt.IsZero() {
+		exp := token.ExpiresAt.Unix()
+		res.SetExpiresAt(&exp)
+	}
+	writeResponse(w, http.StatusOK, res)
+}
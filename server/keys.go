@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/pkg/errors"
+)
+
+// ErrKeyNotFound is returned by key registries when a key reference
+// is not registered
+var ErrKeyNotFound = errors.New(`key not found`)
+
+// KeyRegistry resolves key references sent by pre-registered clients.
+// The returned key must hold the jwk.Key of the client, along with the
+// proofing method that the client uses
+type KeyRegistry interface {
+	LookupKey(ctx context.Context, ref string) (*gnap.Key, error)
+}
+
+// DisplayRegistry provides the display information of pre-registered
+// clients. A KeyRegistry that also implements DisplayRegistry is used
+// by Server.ClientDisplay to describe clients that send key references.
+// If the client has no registered display information, LookupDisplay
+// returns nil without an error
+type DisplayRegistry interface {
+	LookupDisplay(ctx context.Context, ref string) (*gnap.ClientDisplay, error)
+}
+
+// KeyRegistryMap is a KeyRegistry backed by a map
+type KeyRegistryMap map[string]*gnap.Key
+
+func (m KeyRegistryMap) LookupKey(_ context.Context, ref string) (*gnap.Key, error) {
+	key, ok := m[ref]
+	if !ok {
+		return nil, errors.Wrapf(ErrKeyNotFound, `key reference %q`, ref)
+	}
+	return key, nil
+}
+
+// clientKey returns the key of `client`, resolving key references
+// using the key registry
+func (s *Server) clientKey(ctx context.Context, client *gnap.Client) (*gnap.Key, error) {
+	if client == nil || client.Key() == nil {
+		return nil, errors.New(`client key is missing`)
+	}
+
+	key := client.Key()
+	if !key.IsReference() {
+		return key, nil
+	}
+
+	if s.keyRegistry == nil {
+		return nil, errors.Errorf(`cannot resolve key reference %q: no key registry configured`, key.Ref())
+	}
+
+	resolved, err := s.keyRegistry.LookupKey(ctx, key.Ref())
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to resolve key reference`)
+	}
+	return resolved, nil
+}
+
+// ClientDisplay returns the display information that should be shown
+// to the resource owner for the client of `grant`, or nil if there is
+// none.
+//
+// For pre-registered clients, which send a key reference, only the
+// information provided by the key registry (see DisplayRegistry) is
+// used, so that a registered client cannot claim the name or logo of
+// another application. For other clients, the information is taken
+// from the grant request as is, and has not been verified in any way.
+func (s *Server) ClientDisplay(ctx context.Context, grant *Grant) (*gnap.ClientDisplay, error) {
+	if grant.Request == nil || grant.Request.Client() == nil {
+		return nil, nil
+	}
+
+	client := grant.Request.Client()
+	if key := client.Key(); key == nil || !key.IsReference() {
+		return client.Display(), nil
+	}
+
+	registry, ok := s.keyRegistry.(DisplayRegistry)
+	if !ok {
+		return nil, nil
+	}
+
+	display, err := registry.LookupDisplay(ctx, client.Key().Ref())
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to lookup client display`)
+	}
+	return display, nil
+}
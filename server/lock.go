@@ -0,0 +1,44 @@
+package server
+
+import "sync"
+
+// grantLocks serializes state transitions of individual grants within
+// a server process. Locks are created on demand, and discarded once
+// nobody is holding or waiting for them.
+type grantLocks struct {
+	mu    sync.Mutex
+	locks map[string]*grantLock
+}
+
+type grantLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock acquires the lock for the grant with the given ID, and returns
+// the function to release it
+func (l *grantLocks) lock(id string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*grantLock)
+	}
+	gl, ok := l.locks[id]
+	if !ok {
+		gl = &grantLock{}
+		l.locks[id] = gl
+	}
+	gl.refs++
+	l.mu.Unlock()
+
+	gl.Lock()
+	return func() {
+		gl.Unlock()
+
+		l.mu.Lock()
+		gl.refs--
+		if gl.refs == 0 {
+			delete(l.locks, id)
+		}
+		l.mu.Unlock()
+	}
+}
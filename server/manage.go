@@ -0,0 +1,113 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+)
+
+// ManageHandler returns the http.Handler for the token management
+// endpoint (see WithManageEndpoint). Requests must carry the access
+// token in the Authorization header, and be signed with the key of the
+// client that the token was issued to.
+//
+// POST rotates the token, and responds with the new token in the
+// `access_token` field. DELETE revokes the token. As with
+// ContinueHandler, requests for the same grant are serialized within
+// the server process, so that a token can only be rotated once.
+func (s *Server) ManageHandler() http.Handler {
+	return http.HandlerFunc(s.handleManage)
+}
+
+func (s *Server) handleManage(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost, http.MethodDelete:
+	default:
+		w.Header().Set(`Allow`, http.MethodPost+`, `+http.MethodDelete)
+		writeError(w, http.StatusMethodNotAllowed, gnap.InvalidRequest)
+		return
+	}
+
+	ctx := r.Context()
+	value, ok := gnapToken(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidRequest)
+		return
+	}
+
+	grant, err := s.storage.LookupGrantByToken(ctx, value)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidRequest)
+		return
+	}
+
+	key, err := s.clientKey(ctx, grant.Request.Client())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidClient)
+		return
+	}
+
+	if err := s.verifier.Verify(r, key); err != nil {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidClient)
+		return
+	}
+
+	// look the grant up again while holding its lock, as a concurrent
+	// request may have rotated or revoked the token in the meantime
+	unlock := s.locks.lock(grant.ID)
+	defer unlock()
+
+	grant, err = s.storage.LookupGrantByToken(ctx, value)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidRequest)
+		return
+	}
+
+	i := tokenIndex(grant, value)
+	if i < 0 {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidRequest)
+		return
+	}
+
+	var res *gnap.GrantResponse
+	switch r.Method {
+	case http.MethodPost:
+		old := grant.Tokens[i]
+		token, err := s.newToken(old.Access(), old.Label(), old.Flags())
+		if err != nil {
+			writeServerError(w)
+			return
+		}
+		grant.Tokens[i] = token
+		delete(grant.TokenIssuedAt, old.Value())
+		grant.setTokenIssuedAt(token.Value(), time.Now())
+
+		res = gnap.NewGrantResponse()
+		res.SetAccessToken(token)
+	case http.MethodDelete:
+		grant.Tokens = append(grant.Tokens[:i], grant.Tokens[i+1:]...)
+		delete(grant.TokenIssuedAt, value)
+	}
+
+	grant.UpdatedAt = time.Now()
+	if err := s.storage.SaveGrant(ctx, grant); err != nil {
+		writeServerError(w)
+		return
+	}
+
+	if res == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeResponse(w, http.StatusOK, res)
+}
+
+func tokenIndex(grant *Grant, value string) int {
+	for i, token := range grant.Tokens {
+		if token.Value() == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// MemoryStorage is a Storage that keeps grants in memory. It is
+// suitable for tests and single process deployments.
+type MemoryStorage struct {
+	mu     sync.RWMutex
+	grants map[string]*Grant
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		grants: make(map[string]*Grant),
+	}
+}
+
+// copyGrant creates a deep copy of the grant, so that values held by
+// the storage are not modified by callers without calling SaveGrant.
+// The copy is made by encoding the grant to JSON and back, which also
+// covers the request and tokens that are otherwise shared by pointer.
+func copyGrant(g *Grant) (*Grant, error) {
+	buf, err := json.Marshal(g)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to encode grant`)
+	}
+
+	var dup Grant
+	if err := json.Unmarshal(buf, &dup); err != nil {
+		return nil, errors.Wrap(err, `failed to decode grant`)
+	}
+	return &dup, nil
+}
+
+func (s *MemoryStorage) SaveGrant(_ context.Context, g *Grant) error {
+	if g == nil || g.ID == "" {
+		return errors.New(`grant must have an ID`)
+	}
+
+	dup, err := copyGrant(g)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.grants[g.ID] = dup
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStorage) LookupGrant(_ context.Context, id string) (*Grant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	g, ok := s.grants[id]
+	if !ok {
+		return nil, ErrGrantNotFound
+	}
+	return copyGrant(g)
+}
+
+func (s *MemoryStorage) LookupGrantByContinuation(_ context.Context, token string) (*Grant, error) {
+	return s.find(func(g *Grant) bool {
+		return token != "" && g.ContinuationToken == token
+	})
+}
+
+func (s *MemoryStorage) LookupGrantByInteractRef(_ context.Context, ref string) (*Grant, error) {
+	return s.find(func(g *Grant) bool {
+		return ref != "" && g.InteractRef == ref
+	})
+}
+
+func (s *MemoryStorage) LookupGrantByUserCode(_ context.Context, code string) (*Grant, error) {
+	return s.find(func(g *Grant) bool {
+		return code != "" && g.UserCode == code
+	})
+}
+
+func (s *MemoryStorage) LookupGrantByToken(_ context.Context, value string) (*Grant, error) {
+	return s.find(func(g *Grant) bool {
+		if value == "" {
+			return false
+		}
+		for _, token := range g.Tokens {
+			if token.Value() == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (s *MemoryStorage) DeleteGrant(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.grants[id]; !ok {
+		return ErrGrantNotFound
+	}
+	delete(s.grants, id)
+	return nil
+}
+
+func (s *MemoryStorage) find(match func(*Grant) bool) (*Grant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, g := range s.grants {
+		if match(g) {
+			return copyGrant(g)
+		}
+	}
+	return nil, ErrGrantNotFound
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/gnap/proof"
+	"github.com/lestrrat-go/option"
+)
+
+type identContinueEndpoint struct{}
+type identGrantEndpoint struct{}
+type identInteractEndpoint struct{}
+type identKeyRegistry struct{}
+type identManageEndpoint struct{}
+type identPushAttempts struct{}
+type identPushHTTPClient struct{}
+type identPushRetryInterval struct{}
+type identPusher struct{}
+type identResourceServer struct{}
+type identStorage struct{}
+type identTokenLifetime struct{}
+type identUserCodeEndpoint struct{}
+type identUserCodeThrottle struct{}
+type identVerifier struct{}
+
+type Option interface {
+	option.Interface
+	serverOption()
+}
+
+type serverOption struct {
+	option.Interface
+}
+
+func (*serverOption) serverOption() {}
+
+// PushOption is an option that can be passed to NewHTTPPusher
+type PushOption interface {
+	option.Interface
+	pushOption()
+}
+
+type pushOption struct {
+	option.Interface
+}
+
+func (*pushOption) pushOption() {}
+
+// WithContinueEndpoint specifies the URI of the continuation endpoint,
+// which is returned to clients in the `continue` field of grant
+// responses. It is required for grants that need interaction
+func WithContinueEndpoint(v string) Option {
+	return &serverOption{
+		option.New(identContinueEndpoint{}, v),
+	}
+}
+
+// WithGrantEndpoint specifies the URI of the grant endpoint as known
+// to clients, which is used to calculate the interaction hash. If
+// unspecified, the URI is reconstructed from the incoming request
+func WithGrantEndpoint(v string) Option {
+	return &serverOption{
+		option.New(identGrantEndpoint{}, v),
+	}
+}
+
+// WithInteractEndpoint specifies the URI that the resource owner is
+// redirected to in order to interact with the server. The ID of the
+// grant is appended to the URI in the `id` query parameter, and the
+// handler should call Server.FinishInteraction once the resource owner
+// has made a decision. It is required for the "redirect" start mode
+func WithInteractEndpoint(v string) Option {
+	return &serverOption{
+		option.New(identInteractEndpoint{}, v),
+	}
+}
+
+// WithKeyRegistry specifies the registry used to resolve the key
+// references sent by pre-registered clients in the `key` field of the
+// `client` object. Without a registry, key references are rejected
+func WithKeyRegistry(v KeyRegistry) Option {
+	return &serverOption{
+		option.New(identKeyRegistry{}, v),
+	}
+}
+
+// WithManageEndpoint specifies the token management URI, which is
+// returned to clients in the `manage` field of the access tokens
+// issued by the server. If unspecified, tokens cannot be managed.
+// See Server.ManageHandler
+func WithManageEndpoint(v string) Option {
+	return &serverOption{
+		option.New(identManageEndpoint{}, v),
+	}
+}
+
+// WithPusher specifies the Pusher used to notify clients that
+// requested the "push" finish method. If unspecified, an HTTPPusher
+// with the default settings is used
+func WithPusher(v Pusher) Option {
+	return &serverOption{
+		option.New(identPusher{}, v),
+	}
+}
+
+type resourceServer struct {
+	id  string
+	key *gnap.Key
+}
+
+// WithResourceServer registers a resource server that is allowed to
+// use the token introspection endpoint. Introspection requests must
+// specify `id` in the `resource_server` field, and be signed with
+// `key`. The option can be specified multiple times
+func WithResourceServer(id string, key *gnap.Key) Option {
+	return &serverOption{
+		option.New(identResourceServer{}, resourceServer{id: id, key: key}),
+	}
+}
+
+// WithStorage specifies the storage used to persist grants. If
+// unspecified, grants are kept in memory (see NewMemoryStorage)
+func WithStorage(v Storage) Option {
+	return &serverOption{
+		option.New(identStorage{}, v),
+	}
+}
+
+// WithTokenLifetime specifies the lifetime of the access tokens
+// issued by the server. If unspecified, tokens do not expire
+func WithTokenLifetime(v time.Duration) Option {
+	return &serverOption{
+		option.New(identTokenLifetime{}, v),
+	}
+}
+
+// WithUserCodeEndpoint specifies the URI where the resource owner
+// enters user codes, which is returned to clients along with the user
+// code. See Server.UserCodeHandler
+func WithUserCodeEndpoint(v string) Option {
+	return &serverOption{
+		option.New(identUserCodeEndpoint{}, v),
+	}
+}
+
+// WithUserCodeThrottle specifies the UserCodeThrottle that limits
+// failed attempts at the user code endpoint. If unspecified, a
+// MemoryUserCodeThrottle allowing DefaultUserCodeAttempts within
+// DefaultUserCodeWindow is used
+func WithUserCodeThrottle(v UserCodeThrottle) Option {
+	return &serverOption{
+		option.New(identUserCodeThrottle{}, v),
+	}
+}
+
+// WithVerifier specifies the verifier used to check the key proofing
+// of requests sent to the server. The default verifier is created by
+// proof.NewVerifier with a proof.MemoryReplayCache. Custom verifiers
+// are responsible for their own replay protection
+func WithVerifier(v proof.Verifier) Option {
+	return &serverOption{
+		option.New(identVerifier{}, v),
+	}
+}
+
+// WithPushHTTPClient specifies the HTTP client used by HTTPPusher to
+// deliver push messages. It is an option for NewHTTPPusher, and does
+// not apply to anything else in the server
+func WithPushHTTPClient(v *http.Client) PushOption {
+	return &pushOption{
+		option.New(identPushHTTPClient{}, v),
+	}
+}
+
+// WithPushAttempts specifies the maximum number of attempts made by
+// HTTPPusher to deliver a push message
+func WithPushAttempts(v int) PushOption {
+	return &pushOption{
+		option.New(identPushAttempts{}, v),
+	}
+}
+
+// WithPushRetryInterval specifies the interval before the first retry
+// of HTTPPusher. The interval is doubled after each attempt
+func WithPushRetryInterval(v time.Duration) PushOption {
+	return &pushOption{
+		option.New(identPushRetryInterval{}, v),
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+
+	"github.com/lestrrat-go/gnap"
+)
+
+// Decision is the outcome of evaluating a grant request
+type Decision int
+
+const (
+	// Deny rejects the grant request
+	Deny Decision = iota
+	// Approve approves the grant request, and access tokens are
+	// issued for the requested access
+	Approve
+	// Interact defers the decision to the resource owner, who needs
+	// to interact with the server using one of the start modes in the
+	// `interact` field of the request. See Server.FinishInteraction
+	Interact
+)
+
+// Policy decides whether grant requests should be approved. The
+// request has already been validated, and the proof of possession
+// of the client key has been verified when Evaluate is called.
+type Policy interface {
+	Evaluate(context.Context, *gnap.GrantRequest) (Decision, error)
+}
+
+// PolicyFunc is a Policy represented as a function
+type PolicyFunc func(context.Context, *gnap.GrantRequest) (Decision, error)
+
+func (fn PolicyFunc) Evaluate(ctx context.Context, req *gnap.GrantRequest) (Decision, error) {
+	return fn(ctx, req)
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Default values used by HTTPPusher
+const (
+	DefaultPushAttempts      = 3
+	DefaultPushRetryInterval = time.Second
+)
+
+// Pusher delivers the `interact_ref` and `hash` values to clients
+// that requested the "push" finish method
+type Pusher interface {
+	Push(ctx context.Context, uri, interactRef, hash string) error
+}
+
+// HTTPPusher is a Pusher that POSTs the values to the URI specified
+// by the client as a JSON object. Failed deliveries are retried when
+// the request fails, or when the client responds with HTTP 429 or a
+// 5xx status. The interval between attempts is doubled after each
+// attempt.
+type HTTPPusher struct {
+	httpcl   *http.Client
+	attempts int
+	interval time.Duration
+}
+
+type pushMessage struct {
+	InteractRef string `json:"interact_ref"`
+	Hash        string `json:"hash"`
+}
+
+func NewHTTPPusher(options ...PushOption) *HTTPPusher {
+	httpcl := http.DefaultClient
+	attempts := DefaultPushAttempts
+	interval := DefaultPushRetryInterval
+	for _, option := range options {
+		switch option.Ident() {
+		case identPushHTTPClient{}:
+			httpcl = option.Value().(*http.Client)
+		case identPushAttempts{}:
+			attempts = option.Value().(int)
+		case identPushRetryInterval{}:
+			interval = option.Value().(time.Duration)
+		}
+	}
+
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	return &HTTPPusher{
+		httpcl:   httpcl,
+		attempts: attempts,
+		interval: interval,
+	}
+}
+
+func (p *HTTPPusher) Push(ctx context.Context, uri, interactRef, hash string) error {
+	body, err := json.Marshal(pushMessage{
+		InteractRef: interactRef,
+		Hash:        hash,
+	})
+	if err != nil {
+		return errors.Wrap(err, `failed to encode push message`)
+	}
+
+	interval := p.interval
+	for i := 0; ; i++ {
+		retry, err := p.push(ctx, uri, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || i+1 >= p.attempts {
+			return errors.Wrapf(err, `failed to push to %s (attempts = %d)`, uri, i+1)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		interval *= 2
+	}
+}
+
+// push sends a single push request, and reports whether the request
+// should be retried upon failure
+func (p *HTTPPusher) push(ctx context.Context, uri string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.Wrap(err, `failed to create request`)
+	}
+	req.Header.Set(`Content-Type`, `application/json`)
+
+	res, err := p.httpcl.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, errors.Wrap(err, `failed to send request`)
+	}
+	defer res.Body.Close()
+	//nolint:errcheck
+	io.Copy(ioutil.Discard, res.Body)
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return false, nil
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return true, errors.Errorf(`unexpected HTTP status %d`, res.StatusCode)
+	default:
+		return false, errors.Errorf(`unexpected HTTP status %d`, res.StatusCode)
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/lestrrat-go/gnap/rs"
+	"github.com/pkg/errors"
+)
+
+// Resolve looks up an access token issued by the server, so that a
+// resource server running in the same process can validate tokens
+// without introspection. It implements rs.Resolver.
+//
+// Access tokens are bound to the key of the client they were issued
+// to, unless they carry the `bearer` flag.
+func (s *Server) Resolve(ctx context.Context, value string) (*rs.Token, error) {
+	grant, err := s.storage.LookupGrantByToken(ctx, value)
+	if err != nil {
+		if errors.Is(err, ErrGrantNotFound) {
+			return nil, rs.ErrInvalidToken
+		}
+		return nil, errors.Wrap(err, `failed to lookup grant`)
+	}
+
+	i := tokenIndex(grant, value)
+	if i < 0 || grant.State != GrantFinalized {
+		return nil, rs.ErrInvalidToken
+	}
+	token := grant.Tokens[i]
+	issuedAt := grant.tokenIssuedAt(token.Value())
+
+	resolved := &rs.Token{
+		Value:    token.Value(),
+		Access:   token.Access(),
+		Flags:    token.Flags(),
+		IssuedAt: issuedAt,
+	}
+	if !token.IsBearer() {
+		key, err := s.clientKey(ctx, grant.Request.Client())
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to lookup client key`)
+		}
+		resolved.Key = key
+	}
+	if v := token.ExpiresIn(); v != nil {
+		resolved.ExpiresAt = issuedAt.Add(time.Duration(*v) * time.Second)
+	}
+	return resolved, nil
+}
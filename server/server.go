@@ -0,0 +1,375 @@
+// Package server implements a GNAP authorization server
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/gnap/proof"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+// maxRequestSize is the maximum size of request bodies accepted
+// by the server
+const maxRequestSize = 1 << 20
+
+type Server struct {
+	continueEndpoint string
+	grantEndpoint    string
+	interactEndpoint string
+	keyRegistry      KeyRegistry
+	locks            grantLocks
+	manageEndpoint   string
+	policy           Policy
+	pusher           Pusher
+	resourceServers  map[string]*gnap.Key
+	storage          Storage
+	tokenLifetime    time.Duration
+	userCodeEndpoint string
+	userCodeThrottle UserCodeThrottle
+	verifier         proof.Verifier
+}
+
+// New creates a new authorization server, which uses `policy` to
+// decide whether grant requests should be approved.
+func New(policy Policy, options ...Option) *Server {
+	var continueEndpoint string
+	var grantEndpoint string
+	var interactEndpoint string
+	var keyRegistry KeyRegistry
+	var manageEndpoint string
+	var pusher Pusher
+	resourceServers := make(map[string]*gnap.Key)
+	var storage Storage
+	var tokenLifetime time.Duration
+	var userCodeEndpoint string
+	var userCodeThrottle UserCodeThrottle
+	var verifier proof.Verifier
+	for _, option := range options {
+		switch option.Ident() {
+		case identContinueEndpoint{}:
+			continueEndpoint = option.Value().(string)
+		case identGrantEndpoint{}:
+			grantEndpoint = option.Value().(string)
+		case identInteractEndpoint{}:
+			interactEndpoint = option.Value().(string)
+		case identKeyRegistry{}:
+			keyRegistry = option.Value().(KeyRegistry)
+		case identManageEndpoint{}:
+			manageEndpoint = option.Value().(string)
+		case identPusher{}:
+			pusher = option.Value().(Pusher)
+		case identResourceServer{}:
+			v := option.Value().(resourceServer)
+			resourceServers[v.id] = v.key
+		case identStorage{}:
+			storage = option.Value().(Storage)
+		case identTokenLifetime{}:
+			tokenLifetime = option.Value().(time.Duration)
+		case identUserCodeEndpoint{}:
+			userCodeEndpoint = option.Value().(string)
+		case identUserCodeThrottle{}:
+			userCodeThrottle = option.Value().(UserCodeThrottle)
+		case identVerifier{}:
+			verifier = option.Value().(proof.Verifier)
+		}
+	}
+
+	if verifier == nil {
+		verifier = proof.NewVerifier(proof.NewMemoryReplayCache())
+	}
+
+	if pusher == nil {
+		pusher = NewHTTPPusher()
+	}
+
+	if storage == nil {
+		storage = NewMemoryStorage()
+	}
+
+	if userCodeThrottle == nil {
+		userCodeThrottle = NewMemoryUserCodeThrottle(DefaultUserCodeAttempts, DefaultUserCodeWindow)
+	}
+
+	return &Server{
+		continueEndpoint: continueEndpoint,
+		grantEndpoint:    grantEndpoint,
+		interactEndpoint: interactEndpoint,
+		keyRegistry:      keyRegistry,
+		manageEndpoint:   manageEndpoint,
+		policy:           policy,
+		pusher:           pusher,
+		resourceServers:  resourceServers,
+		storage:          storage,
+		tokenLifetime:    tokenLifetime,
+		userCodeEndpoint: userCodeEndpoint,
+		userCodeThrottle: userCodeThrottle,
+		verifier:         verifier,
+	}
+}
+
+// GrantHandler returns the http.Handler for the grant endpoint. One
+// access token is issued for each entry of the `access_token` array of
+// the request. Tokens are always bound to the client key: requests for
+// the `bearer` flag are answered with an "invalid_flag" error
+func (s *Server) GrantHandler() http.Handler {
+	return http.HandlerFunc(s.handleGrant)
+}
+
+func (s *Server) handleGrant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set(`Allow`, http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, gnap.InvalidRequest)
+		return
+	}
+
+	var req gnap.GrantRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, gnap.InvalidRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, gnap.InvalidRequest)
+		return
+	}
+
+	ctx := r.Context()
+	key, err := s.clientKey(ctx, req.Client())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, gnap.InvalidClient)
+		return
+	}
+
+	if err := s.verifier.Verify(r, key); err != nil {
+		writeError(w, http.StatusUnauthorized, gnap.InvalidClient)
+		return
+	}
+
+	if !supportedFlags(&req) {
+		writeError(w, http.StatusBadRequest, gnap.InvalidFlag)
+		return
+	}
+
+	grant, err := s.newGrant(&req)
+	if err != nil {
+		writeServerError(w)
+		return
+	}
+
+	decision, err := s.policy.Evaluate(ctx, &req)
+	if err != nil {
+		writeServerError(w)
+		return
+	}
+
+	switch decision {
+	case Approve:
+		res, err := s.approve(grant)
+		if err != nil {
+			writeServerError(w)
+			return
+		}
+		if err := s.storage.SaveGrant(ctx, grant); err != nil {
+			writeServerError(w)
+			return
+		}
+		writeResponse(w, http.StatusOK, res)
+	case Interact:
+		if req.Interact() == nil {
+			// the resource owner cannot be asked for consent
+			writeError(w, http.StatusForbidden, gnap.RequestDenied)
+			return
+		}
+
+		res, err := s.startInteraction(r, grant)
+		if err != nil {
+			if errors.Is(err, errUnsupportedInteraction) {
+				writeError(w, http.StatusBadRequest, gnap.InvalidRequest)
+				return
+			}
+			writeServerError(w)
+			return
+		}
+		if err := s.storage.SaveGrant(ctx, grant); err != nil {
+			writeServerError(w)
+			return
+		}
+		writeResponse(w, http.StatusOK, res)
+	default:
+		grant.State = GrantDenied
+		grant.UpdatedAt = time.Now()
+		if err := s.storage.SaveGrant(ctx, grant); err != nil {
+			writeServerError(w)
+			return
+		}
+		writeError(w, http.StatusForbidden, gnap.RequestDenied)
+	}
+}
+
+func (s *Server) newGrant(req *gnap.GrantRequest) (*Grant, error) {
+	id, err := randomString(16)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to generate grant ID`)
+	}
+
+	now := time.Now()
+	return &Grant{
+		ID:        id,
+		State:     GrantProcessing,
+		Request:   req,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// approve issues one access token for each entry of the `access_token`
+// array of the grant request, and finalizes the grant. The tokens are
+// returned in the same form, single object or array, as requested
+func (s *Server) approve(grant *Grant) (*gnap.GrantResponse, error) {
+	res := gnap.NewGrantResponse()
+	res.SetMultipleAccessTokens(grant.Request.MultipleAccessTokens())
+	for _, atr := range grant.Request.AccessTokens() {
+		token, err := s.issueToken(atr)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to issue access token`)
+		}
+		grant.Tokens = append(grant.Tokens, token)
+		res.AddAccessTokens(token)
+	}
+
+	now := time.Now()
+	for _, token := range grant.Tokens {
+		grant.setTokenIssuedAt(token.Value(), now)
+	}
+	grant.State = GrantFinalized
+	grant.IssuedAt = now
+	grant.UpdatedAt = now
+	return res, nil
+}
+
+// supportedFlags returns false if the client requested an access token
+// flag that the server cannot honor. Tokens are always bound to the
+// client key, so `bearer` is rejected. `split` only states that the
+// client can receive multiple tokens, and is accepted
+func supportedFlags(req *gnap.GrantRequest) bool {
+	for _, atr := range req.AccessTokens() {
+		for _, flag := range atr.Flags() {
+			if flag != gnap.Split {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// issueToken issues the access token described by `req`. The flags of
+// the request are checked by supportedFlags beforehand
+func (s *Server) issueToken(req *gnap.AccessTokenRequest) (*gnap.AccessToken, error) {
+	return s.newToken(req.Access(), req.Label(), nil)
+}
+
+func (s *Server) newToken(access []gnap.AccessEntry, label string, flags []gnap.AccessTokenAttribute) (*gnap.AccessToken, error) {
+	value, err := randomString(32)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to generate token value`)
+	}
+
+	var token gnap.AccessToken
+	token.SetValue(value)
+	token.AddAccess(access...)
+	token.AddFlags(flags...)
+	if label != "" {
+		token.SetLabel(label)
+	}
+	if s.tokenLifetime > 0 {
+		expiresIn := int64(s.tokenLifetime / time.Second)
+		token.SetExpiresIn(&expiresIn)
+	}
+	if s.manageEndpoint != "" {
+		token.SetManage(s.manageEndpoint)
+	}
+	return &token, nil
+}
+
+// decodeRequest decodes the body of the request into dst. If the
+// body is an attached JWS, the payload is decoded without being
+// verified: the signature is checked later, once the client key
+// is known. The request body is left intact.
+func decodeRequest(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		return errors.Wrap(err, `failed to read request body`)
+	}
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	// empty bodies are allowed, e.g. for continuation requests
+	// sent while polling
+	if len(body) == 0 {
+		return nil
+	}
+
+	payload := body
+	if mt, _, err := mime.ParseMediaType(r.Header.Get(`Content-Type`)); err == nil && mt == `application/jose` {
+		msg, err := jws.Parse(bytes.TrimSpace(body))
+		if err != nil {
+			return errors.Wrap(err, `failed to parse JWS`)
+		}
+		payload = msg.Payload()
+	}
+
+	if err := json.Unmarshal(payload, dst); err != nil {
+		return errors.Wrap(err, `failed to decode request body`)
+	}
+	return nil
+}
+
+// requestURI reconstructs the URI of the request, without the
+// query component
+func requestURI(r *http.Request) string {
+	u := url.URL{
+		Scheme: `http`,
+		Host:   r.Host,
+		Path:   r.URL.Path,
+	}
+	if r.TLS != nil {
+		u.Scheme = `https`
+	}
+	return u.String()
+}
+
+func writeResponse(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.Header().Set(`Cache-Control`, `no-store`)
+	w.WriteHeader(status)
+	//nolint:errcheck
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	res := gnap.NewGrantResponse()
+	res.SetError(gnap.NewError(code))
+	writeResponse(w, status, res)
+}
+
+func writeServerError(w http.ResponseWriter) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
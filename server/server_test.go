@@ -0,0 +1,1223 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/gnap/client"
+	"github.com/lestrrat-go/gnap/proof"
+	"github.com/lestrrat-go/gnap/rs"
+	"github.com/lestrrat-go/gnap/server"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/stretchr/testify/assert"
+)
+
+func newClientKey(t *testing.T, form gnap.ProofForm) *gnap.Key {
+	t.Helper()
+
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		t.FailNow()
+	}
+	jwkey, err := jwk.New(raw)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		t.FailNow()
+	}
+
+	var key gnap.Key
+	key.SetProof(&form)
+	key.SetJWK(jwkey)
+	return &key
+}
+
+func newSignedClient(t *testing.T, endpoint string, key *gnap.Key) *client.Client {
+	t.Helper()
+
+	signer, err := proof.NewSigner(key)
+	if !assert.NoError(t, err, `proof.NewSigner should succeed`) {
+		t.FailNow()
+	}
+	return client.New(client.WithGrantEndpoint(endpoint), client.WithSigner(signer))
+}
+
+func photoAccess() *gnap.AccessTokenRequest {
+	var ra gnap.ResourceAccess
+	ra.SetType(`photo-api`)
+	ra.AddActions(`read`)
+	return gnap.NewAccessTokenRequest().AddAccessObjects(ra)
+}
+
+// decide returns a Policy that always makes the same decision
+func decide(decision server.Decision) server.Policy {
+	return server.PolicyFunc(func(context.Context, *gnap.GrantRequest) (server.Decision, error) {
+		return decision, nil
+	})
+}
+
+// testServer is an authorization server running on an httptest
+// server. Its endpoints are configured relative to URL, and the
+// handlers of the grant, continuation, management, and introspection
+// endpoints are registered. Tests register other handlers using Handle
+type testServer struct {
+	*server.Server
+	URL string
+	mux *http.ServeMux
+}
+
+func newTestServer(t *testing.T, policy server.Policy, options ...server.Option) *testServer {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	options = append([]server.Option{
+		server.WithContinueEndpoint(srv.URL + `/continue`),
+		server.WithInteractEndpoint(srv.URL + `/interact`),
+		server.WithManageEndpoint(srv.URL + `/manage`),
+		server.WithUserCodeEndpoint(srv.URL + `/device`),
+	}, options...)
+	as := server.New(policy, options...)
+	mux.Handle(`/grant`, as.GrantHandler())
+	mux.Handle(`/continue`, as.ContinueHandler())
+	mux.Handle(`/manage`, as.ManageHandler())
+	mux.Handle(`/introspect`, as.IntrospectHandler())
+
+	return &testServer{
+		Server: as,
+		URL:    srv.URL,
+		mux:    mux,
+	}
+}
+
+func (ts *testServer) Handle(pattern string, h http.Handler) {
+	ts.mux.Handle(pattern, h)
+}
+
+func TestGrantHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	policy := server.PolicyFunc(func(_ context.Context, req *gnap.GrantRequest) (server.Decision, error) {
+		for _, atr := range req.AccessTokens() {
+			for _, access := range gnap.AccessObjects(atr.Access()) {
+				if access.Type() == `admin-api` {
+					return server.Deny, nil
+				}
+			}
+		}
+		return server.Approve, nil
+	})
+
+	as := newTestServer(t, policy, server.WithTokenLifetime(time.Hour))
+
+	for _, form := range []gnap.ProofForm{gnap.HTTPSig, gnap.AttachedJWS} {
+		form := form
+		t.Run(string(form), func(t *testing.T) {
+			key := newClientKey(t, form)
+			cl := newSignedClient(t, as.URL+`/grant`, key)
+
+			t.Run("Approve", func(t *testing.T) {
+				res, err := cl.NewGrantRequest().
+					Client(gnap.NewClient(*key)).
+					AddAccessTokens(photoAccess()).
+					Do(ctx)
+				if !assert.NoError(t, err, `Do should succeed`) {
+					return
+				}
+
+				token := res.AccessToken()
+				if !assert.NotNil(t, token, `access token should be issued`) {
+					return
+				}
+				if !assert.NotEmpty(t, token.Value(), `token value should be populated`) {
+					return
+				}
+				if !assert.Equal(t, `photo-api`, token.Access()[0].Object().Type(), `access should match the request`) {
+					return
+				}
+				if !assert.Equal(t, int64(3600), *token.ExpiresIn(), `expires_in should match`) {
+					return
+				}
+			})
+			t.Run("Deny", func(t *testing.T) {
+				var ra gnap.ResourceAccess
+				ra.SetType(`admin-api`)
+				_, err := cl.NewGrantRequest().
+					Client(gnap.NewClient(*key)).
+					AddAccessTokens(gnap.NewAccessTokenRequest().AddAccessObjects(ra)).
+					Do(ctx)
+
+				var serr *client.StatusError
+				if !assert.True(t, errors.As(err, &serr), `error should be a *client.StatusError`) {
+					return
+				}
+				if !assert.Equal(t, `request_denied`, serr.Response.Error().Code(), `error should match`) {
+					return
+				}
+			})
+		})
+	}
+	t.Run("Multiple Tokens", func(t *testing.T) {
+		key := newClientKey(t, gnap.HTTPSig)
+		cl := newSignedClient(t, as.URL+`/grant`, key)
+
+		labels := []string{`photos`, `videos`}
+		req := cl.NewGrantRequest().Client(gnap.NewClient(*key))
+		for _, label := range labels {
+			atr := photoAccess()
+			atr.SetLabel(label)
+			req.AddAccessTokens(atr)
+		}
+		res, err := req.Do(ctx)
+		if !assert.NoError(t, err, `Do should succeed`) {
+			return
+		}
+
+		tokens := res.AccessTokens()
+		if !assert.Len(t, tokens, len(labels), `one token should be issued per request`) {
+			return
+		}
+		for i, token := range tokens {
+			if !assert.Equal(t, labels[i], token.Label(), `label should match the request`) {
+				return
+			}
+		}
+		if !assert.NotEqual(t, tokens[0].Value(), tokens[1].Value(), `token values should be distinct`) {
+			return
+		}
+	})
+	t.Run("Single Token Array", func(t *testing.T) {
+		key := newClientKey(t, gnap.HTTPSig)
+		res, err := newSignedClient(t, as.URL+`/grant`, key).NewGrantRequest().
+			Client(gnap.NewClient(*key)).
+			AddAccessTokens(photoAccess()).
+			MultipleAccessTokens(true).
+			Do(ctx)
+		if !assert.NoError(t, err, `Do should succeed`) {
+			return
+		}
+		if !assert.True(t, res.MultipleAccessTokens(), `tokens should be returned as an array`) {
+			return
+		}
+		if !assert.Len(t, res.AccessTokens(), 1, `one token should be issued`) {
+			return
+		}
+	})
+	t.Run("Bearer Flag", func(t *testing.T) {
+		key := newClientKey(t, gnap.HTTPSig)
+		_, err := newSignedClient(t, as.URL+`/grant`, key).NewGrantRequest().
+			Client(gnap.NewClient(*key)).
+			AddAccessTokens(photoAccess().AddFlags(gnap.Bearer)).
+			Do(ctx)
+
+		var serr *client.StatusError
+		if !assert.True(t, errors.As(err, &serr), `error should be a *client.StatusError`) {
+			return
+		}
+		if !assert.Equal(t, gnap.InvalidFlag, serr.Response.Error().Code(), `error should match`) {
+			return
+		}
+	})
+	t.Run("Split Flag", func(t *testing.T) {
+		key := newClientKey(t, gnap.HTTPSig)
+		res, err := newSignedClient(t, as.URL+`/grant`, key).NewGrantRequest().
+			Client(gnap.NewClient(*key)).
+			AddAccessTokens(photoAccess().AddFlags(gnap.Split)).
+			Do(ctx)
+		if !assert.NoError(t, err, `Do should succeed`) {
+			return
+		}
+		if !assert.NotNil(t, res.AccessToken(), `access token should be issued`) {
+			return
+		}
+	})
+	t.Run("Unsigned Request", func(t *testing.T) {
+		key := newClientKey(t, gnap.HTTPSig)
+		_, err := client.New(client.WithGrantEndpoint(as.URL + `/grant`)).NewGrantRequest().
+			Client(gnap.NewClient(*key)).
+			AddAccessTokens(photoAccess()).
+			Do(ctx)
+
+		var serr *client.StatusError
+		if !assert.True(t, errors.As(err, &serr), `error should be a *client.StatusError`) {
+			return
+		}
+		if !assert.Equal(t, http.StatusUnauthorized, serr.StatusCode, `status code should match`) {
+			return
+		}
+		if !assert.Equal(t, `invalid_client`, serr.Response.Error().Code(), `error should match`) {
+			return
+		}
+	})
+	t.Run("Missing Client", func(t *testing.T) {
+		_, err := client.New(client.WithGrantEndpoint(as.URL + `/grant`)).NewGrantRequest().
+			AddAccessTokens(photoAccess()).
+			Do(ctx)
+
+		var serr *client.StatusError
+		if !assert.True(t, errors.As(err, &serr), `error should be a *client.StatusError`) {
+			return
+		}
+		if !assert.Equal(t, http.StatusBadRequest, serr.StatusCode, `status code should match`) {
+			return
+		}
+	})
+}
+
+func TestInteraction(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	as := newTestServer(t, decide(server.Interact))
+	key := newClientKey(t, gnap.HTTPSig)
+	cl := newSignedClient(t, as.URL+`/grant`, key)
+
+	start := func(t *testing.T, finish *gnap.InteractionFinish) (*gnap.GrantResponse, string) {
+		t.Helper()
+
+		interact := gnap.NewInteractionRequest(gnap.StartRedirect)
+		if finish != nil {
+			interact.AddFinish(finish)
+		}
+		res, err := cl.NewGrantRequest().
+			Client(gnap.NewClient(*key)).
+			AddAccessTokens(photoAccess()).
+			Interact(interact).
+			Do(ctx)
+		if !assert.NoError(t, err, `Do should succeed`) {
+			t.FailNow()
+		}
+		if !assert.NotNil(t, res.Continue(), `continuation should be returned`) {
+			t.FailNow()
+		}
+
+		u, err := url.Parse(res.Interact().Redirect())
+		if !assert.NoError(t, err, `redirect should be a valid URL`) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, `/interact`, u.Path, `redirect should point to the interaction endpoint`) {
+			t.FailNow()
+		}
+		return res, u.Query().Get(`id`)
+	}
+
+	t.Run("Redirect Finish", func(t *testing.T) {
+		finish := gnap.NewInteractionFinish(gnap.FinishRedirect, `client-nonce`, `https://client.example.com/finish`)
+		finish.SetHashMethod(gnap.HashSHA256)
+		res, id := start(t, finish)
+		if !assert.NotEmpty(t, res.Interact().Finish(), `server nonce should be returned`) {
+			return
+		}
+
+		pending, err := cl.NewContinueRequest(res.Continue()).Do(ctx)
+		if !assert.NoError(t, err, `continuing a pending grant should succeed`) {
+			return
+		}
+		if !assert.Nil(t, pending.AccessToken(), `access token should not be issued yet`) {
+			return
+		}
+
+		redirect, err := as.FinishInteraction(ctx, id, true)
+		if !assert.NoError(t, err, `FinishInteraction should succeed`) {
+			return
+		}
+		u, err := url.Parse(redirect)
+		if !assert.NoError(t, err, `redirect should be a valid URL`) {
+			return
+		}
+		if !assert.Equal(t, `client.example.com`, u.Host, `redirect should point to the client`) {
+			return
+		}
+
+		ref := u.Query().Get(`interact_ref`)
+		if !assert.NoError(t, cl.VerifyInteractionHash(finish, res.Interact().Finish(), ref, u.Query().Get(`hash`)), `hash should be valid`) {
+			return
+		}
+
+		if _, err := cl.NewContinueRequest(res.Continue()).InteractRef(`bogus`).Do(ctx); !assert.Error(t, err, `invalid interact_ref should be rejected`) {
+			return
+		}
+
+		approved, err := cl.NewContinueRequest(res.Continue()).InteractRef(ref).Do(ctx)
+		if !assert.NoError(t, err, `continuation should succeed`) {
+			return
+		}
+		if !assert.NotNil(t, approved.AccessToken(), `access token should be issued`) {
+			return
+		}
+
+		if _, err := cl.NewContinueRequest(res.Continue()).InteractRef(ref).Do(ctx); !assert.Error(t, err, `continuation token should not be reusable`) {
+			return
+		}
+	})
+	t.Run("Missing Continue Endpoint", func(t *testing.T) {
+		misconfigured := newTestServer(t, decide(server.Interact), server.WithContinueEndpoint(``))
+		cl := newSignedClient(t, misconfigured.URL+`/grant`, key)
+		_, err := cl.NewGrantRequest().
+			Client(gnap.NewClient(*key)).
+			AddAccessTokens(photoAccess()).
+			Interact(gnap.NewInteractionRequest(gnap.StartRedirect)).
+			Do(ctx)
+
+		var serr *client.StatusError
+		if !assert.True(t, errors.As(err, &serr), `error should be a *client.StatusError`) {
+			return
+		}
+		if !assert.Equal(t, http.StatusInternalServerError, serr.StatusCode, `status code should match`) {
+			return
+		}
+	})
+	t.Run("Denied", func(t *testing.T) {
+		res, id := start(t, nil)
+		redirect, err := as.FinishInteraction(ctx, id, false)
+		if !assert.NoError(t, err, `FinishInteraction should succeed`) {
+			return
+		}
+		if !assert.Empty(t, redirect, `redirect should be empty`) {
+			return
+		}
+
+		_, err = cl.NewContinueRequest(res.Continue()).Do(ctx)
+		var serr *client.StatusError
+		if !assert.True(t, errors.As(err, &serr), `error should be a *client.StatusError`) {
+			return
+		}
+		if !assert.Equal(t, `user_denied`, serr.Response.Error().Code(), `error should match`) {
+			return
+		}
+	})
+	t.Run("Cancel", func(t *testing.T) {
+		res, id := start(t, nil)
+		if !assert.NoError(t, cl.NewCancelGrant(res.Continue()).Do(ctx), `cancel should succeed`) {
+			return
+		}
+		if _, err := as.FinishInteraction(ctx, id, true); !assert.Error(t, err, `grant should be deleted`) {
+			return
+		}
+	})
+	t.Run("Wrong Key", func(t *testing.T) {
+		res, _ := start(t, nil)
+		other := newSignedClient(t, as.URL+`/grant`, newClientKey(t, gnap.HTTPSig))
+		if _, err := other.NewContinueRequest(res.Continue()).Do(ctx); !assert.Error(t, err, `continuation with another key should fail`) {
+			return
+		}
+	})
+	t.Run("Modify", func(t *testing.T) {
+		res, _ := start(t, nil)
+		_, err := cl.NewModifyGrant(res.Continue()).AddAccessTokens(photoAccess()).Do(ctx)
+		var serr *client.StatusError
+		if !assert.True(t, errors.As(err, &serr), `error should be a *client.StatusError`) {
+			return
+		}
+		if !assert.Equal(t, http.StatusMethodNotAllowed, serr.StatusCode, `status should be 405`) {
+			return
+		}
+		if !assert.Equal(t, `POST, DELETE`, serr.Header.Get(`Allow`), `Allow header should list supported methods`) {
+			return
+		}
+	})
+}
+
+// slowStorage delays writes, to widen the window for races between
+// concurrent requests
+type slowStorage struct {
+	server.Storage
+}
+
+func (s slowStorage) SaveGrant(ctx context.Context, g *server.Grant) error {
+	time.Sleep(10 * time.Millisecond)
+	return s.Storage.SaveGrant(ctx, g)
+}
+
+func TestConcurrentContinuation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	as := newTestServer(t, decide(server.Interact), server.WithStorage(slowStorage{server.NewMemoryStorage()}))
+	key := newClientKey(t, gnap.HTTPSig)
+	cl := newSignedClient(t, as.URL+`/grant`, key)
+
+	res, err := cl.NewGrantRequest().
+		Client(gnap.NewClient(*key)).
+		AddAccessTokens(photoAccess()).
+		Interact(gnap.NewInteractionRequest(gnap.StartRedirect)).
+		Do(ctx)
+	if !assert.NoError(t, err, `Do should succeed`) {
+		return
+	}
+	u, err := url.Parse(res.Interact().Redirect())
+	if !assert.NoError(t, err, `redirect should be a valid URL`) {
+		return
+	}
+	if _, err := as.FinishInteraction(ctx, u.Query().Get(`id`), true); !assert.NoError(t, err, `FinishInteraction should succeed`) {
+		return
+	}
+
+	const count = 10
+	var wg sync.WaitGroup
+	issued := make(chan *gnap.AccessToken, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			approved, err := cl.NewContinueRequest(res.Continue()).Do(ctx)
+			if err == nil && approved.AccessToken() != nil {
+				issued <- approved.AccessToken()
+			}
+		}()
+	}
+	wg.Wait()
+	close(issued)
+
+	if !assert.Len(t, issued, 1, `only one continuation should be approved`) {
+		return
+	}
+}
+
+func TestConcurrentInteraction(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	as := newTestServer(t, decide(server.Interact), server.WithStorage(slowStorage{server.NewMemoryStorage()}))
+	key := newClientKey(t, gnap.HTTPSig)
+	cl := newSignedClient(t, as.URL+`/grant`, key)
+
+	res, err := cl.NewGrantRequest().
+		Client(gnap.NewClient(*key)).
+		AddAccessTokens(photoAccess()).
+		Interact(gnap.NewInteractionRequest(gnap.StartRedirect)).
+		Do(ctx)
+	if !assert.NoError(t, err, `Do should succeed`) {
+		return
+	}
+	u, err := url.Parse(res.Interact().Redirect())
+	if !assert.NoError(t, err, `redirect should be a valid URL`) {
+		return
+	}
+	id := u.Query().Get(`id`)
+
+	const count = 10
+	var wg sync.WaitGroup
+	decided := make(chan bool, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(approved bool) {
+			defer wg.Done()
+			if _, err := as.FinishInteraction(ctx, id, approved); err == nil {
+				decided <- approved
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+	close(decided)
+
+	if !assert.Len(t, decided, 1, `only one decision should be recorded`) {
+		return
+	}
+
+	// the recorded decision is the one the client sees
+	approved := <-decided
+	cont, err := cl.NewContinueRequest(res.Continue()).Do(ctx)
+	if approved {
+		if !assert.NoError(t, err, `continuation should succeed`) {
+			return
+		}
+		if !assert.NotNil(t, cont.AccessToken(), `access token should be issued`) {
+			return
+		}
+	} else if !assert.Error(t, err, `continuation should fail`) {
+		return
+	}
+}
+
+func TestPushFinish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	as := newTestServer(t, decide(server.Interact))
+	key := newClientKey(t, gnap.HTTPSig)
+	cl := newSignedClient(t, as.URL+`/grant`, key)
+
+	type result struct {
+		res *gnap.GrantResponse
+		err error
+	}
+	results := make(chan result, 1)
+	push := cl.NewPushFinishHandler(func(res *gnap.GrantResponse, err error) {
+		results <- result{res: res, err: err}
+	})
+	clientSrv := httptest.NewServer(push)
+	defer clientSrv.Close()
+
+	finish := gnap.NewInteractionFinish(gnap.FinishPush, `client-nonce`, clientSrv.URL)
+	res, err := cl.NewGrantRequest().
+		Client(gnap.NewClient(*key)).
+		AddAccessTokens(photoAccess()).
+		Interact(gnap.NewInteractionRequest(gnap.StartRedirect).AddFinish(finish)).
+		Do(ctx)
+	if !assert.NoError(t, err, `Do should succeed`) {
+		return
+	}
+	if !assert.NoError(t, push.Register(finish, res), `Register should succeed`) {
+		return
+	}
+
+	u, err := url.Parse(res.Interact().Redirect())
+	if !assert.NoError(t, err, `redirect should be a valid URL`) {
+		return
+	}
+	redirect, err := as.FinishInteraction(ctx, u.Query().Get(`id`), true)
+	if !assert.NoError(t, err, `FinishInteraction should succeed`) {
+		return
+	}
+	if !assert.Empty(t, redirect, `redirect should be empty`) {
+		return
+	}
+
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal(`timed out waiting for push finish`)
+	case r := <-results:
+		if !assert.NoError(t, r.err, `continuation should succeed`) {
+			return
+		}
+		if !assert.NotNil(t, r.res.AccessToken(), `access token should be issued`) {
+			return
+		}
+	}
+}
+
+func TestHTTPPusher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var attempts int
+	var lastBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch r.URL.Path {
+		case `/unavailable`:
+			if attempts < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case `/rejected`:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		lastBody = nil
+		if err := json.NewDecoder(r.Body).Decode(&lastBody); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	pusher := server.NewHTTPPusher(server.WithPushRetryInterval(time.Millisecond))
+	t.Run("Retry", func(t *testing.T) {
+		attempts = 0
+		if !assert.NoError(t, pusher.Push(ctx, srv.URL+`/unavailable`, `ref`, `hash`), `Push should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 3, attempts, `push should be attempted 3 times`) {
+			return
+		}
+		if !assert.Equal(t, map[string]interface{}{"interact_ref": "ref", "hash": "hash"}, lastBody, `body should match`) {
+			return
+		}
+	})
+	t.Run("No Retry", func(t *testing.T) {
+		attempts = 0
+		if !assert.Error(t, pusher.Push(ctx, srv.URL+`/rejected`, `ref`, `hash`), `Push should fail`) {
+			return
+		}
+		if !assert.Equal(t, 1, attempts, `push should be attempted once`) {
+			return
+		}
+	})
+}
+
+func TestUserCode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	as := newTestServer(t, decide(server.Interact))
+
+	var entered *server.Grant
+	as.Handle(`/device`, as.UserCodeHandler(func(w http.ResponseWriter, _ *http.Request, grant *server.Grant, err error) {
+		entered = grant
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	key := newClientKey(t, gnap.HTTPSig)
+	cl := newSignedClient(t, as.URL+`/grant`, key)
+
+	grant, err := cl.NewGrantRequest().
+		Client(gnap.NewClient(*key)).
+		AddAccessTokens(photoAccess()).
+		DoUserCode(ctx)
+	if !assert.NoError(t, err, `DoUserCode should succeed`) {
+		return
+	}
+
+	uc := grant.UserCode()
+	if !assert.NotNil(t, uc, `user code should be returned`) {
+		return
+	}
+	if !assert.Equal(t, as.URL+`/device`, uc.URL(), `user code URL should match`) {
+		return
+	}
+
+	t.Run("Unknown Code", func(t *testing.T) {
+		res, err := http.PostForm(as.URL+`/device`, url.Values{`code`: {`XXXX-XXXX`}})
+		if !assert.NoError(t, err, `request should succeed`) {
+			return
+		}
+		res.Body.Close()
+		if !assert.Equal(t, http.StatusNotFound, res.StatusCode, `status code should match`) {
+			return
+		}
+	})
+	t.Run("Approve", func(t *testing.T) {
+		// codes are matched regardless of case and dashes
+		code := strings.ToLower(uc.Code()[:4] + `-` + uc.Code()[4:])
+		res, err := http.PostForm(as.URL+`/device`, url.Values{`code`: {code}})
+		if !assert.NoError(t, err, `request should succeed`) {
+			return
+		}
+		res.Body.Close()
+		if !assert.Equal(t, http.StatusOK, res.StatusCode, `status code should match`) {
+			return
+		}
+		if !assert.NotNil(t, entered, `grant should be found`) {
+			return
+		}
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			//nolint:errcheck
+			as.FinishInteraction(ctx, entered.ID, true)
+		}()
+
+		approved, err := grant.Wait(ctx, client.WithPollInterval(10*time.Millisecond))
+		if !assert.NoError(t, err, `Wait should succeed`) {
+			return
+		}
+		if !assert.NotNil(t, approved.AccessToken(), `access token should be issued`) {
+			return
+		}
+	})
+}
+
+func TestUserCodeThrottle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const attempts = 3
+	as := newTestServer(t, decide(server.Interact), server.WithUserCodeThrottle(server.NewMemoryUserCodeThrottle(attempts, time.Hour)))
+	as.Handle(`/device`, as.UserCodeHandler(func(w http.ResponseWriter, _ *http.Request, _ *server.Grant, err error) {
+		switch {
+		case errors.Is(err, server.ErrTooManyAttempts):
+			w.WriteHeader(http.StatusTooManyRequests)
+		case err != nil:
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+
+	key := newClientKey(t, gnap.HTTPSig)
+	grant, err := newSignedClient(t, as.URL+`/grant`, key).NewGrantRequest().
+		Client(gnap.NewClient(*key)).
+		AddAccessTokens(photoAccess()).
+		DoUserCode(ctx)
+	if !assert.NoError(t, err, `DoUserCode should succeed`) {
+		return
+	}
+
+	enter := func(code string) int {
+		res, err := http.PostForm(as.URL+`/device`, url.Values{`code`: {code}})
+		if !assert.NoError(t, err, `request should succeed`) {
+			t.FailNow()
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	for i := 0; i < attempts; i++ {
+		if !assert.Equal(t, http.StatusNotFound, enter(`XXXX-XXXX`), `unknown code should be rejected`) {
+			return
+		}
+	}
+	if !assert.Equal(t, http.StatusTooManyRequests, enter(grant.UserCode().Code()), `attempts should be refused once the limit is reached`) {
+		return
+	}
+}
+
+func TestManageToken(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	as := newTestServer(t, decide(server.Approve))
+	key := newClientKey(t, gnap.HTTPSig)
+	cl := newSignedClient(t, as.URL+`/grant`, key)
+
+	res, err := cl.NewGrantRequest().
+		Client(gnap.NewClient(*key)).
+		AddAccessTokens(photoAccess()).
+		Do(ctx)
+	if !assert.NoError(t, err, `Do should succeed`) {
+		return
+	}
+	token := res.AccessToken()
+	if !assert.Equal(t, as.URL+`/manage`, token.Manage(), `manage URI should be populated`) {
+		return
+	}
+
+	t.Run("Wrong Key", func(t *testing.T) {
+		other := newSignedClient(t, as.URL+`/grant`, newClientKey(t, gnap.HTTPSig))
+		if _, err := other.NewRotateToken(token).Do(ctx); !assert.Error(t, err, `rotation with another key should fail`) {
+			return
+		}
+	})
+
+	var rotated *gnap.AccessToken
+	t.Run("Rotate", func(t *testing.T) {
+		rotated, err = cl.NewRotateToken(token).Do(ctx)
+		if !assert.NoError(t, err, `Do should succeed`) {
+			return
+		}
+		if !assert.NotEqual(t, token.Value(), rotated.Value(), `token value should change`) {
+			return
+		}
+		if !assert.Equal(t, token.Access(), rotated.Access(), `access should be preserved`) {
+			return
+		}
+		if _, err := cl.NewRotateToken(token).Do(ctx); !assert.Error(t, err, `old token should be invalid`) {
+			return
+		}
+	})
+	t.Run("Revoke", func(t *testing.T) {
+		if !assert.NoError(t, cl.NewRevokeToken(rotated).Do(ctx), `Do should succeed`) {
+			return
+		}
+		if err := cl.NewRevokeToken(rotated).Do(ctx); !assert.Error(t, err, `token should be revoked`) {
+			return
+		}
+	})
+}
+
+func TestRotationIssuedAt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	as := newTestServer(t, decide(server.Approve), server.WithTokenLifetime(time.Hour))
+	key := newClientKey(t, gnap.HTTPSig)
+	cl := newSignedClient(t, as.URL+`/grant`, key)
+
+	req := cl.NewGrantRequest().Client(gnap.NewClient(*key))
+	for _, label := range []string{`photos`, `videos`} {
+		atr := photoAccess()
+		atr.SetLabel(label)
+		req.AddAccessTokens(atr)
+	}
+	res, err := req.Do(ctx)
+	if !assert.NoError(t, err, `Do should succeed`) {
+		return
+	}
+	tokens := res.AccessTokens()
+	if !assert.Len(t, tokens, 2, `two tokens should be issued`) {
+		return
+	}
+
+	before, err := as.Resolve(ctx, tokens[1].Value())
+	if !assert.NoError(t, err, `Resolve should succeed`) {
+		return
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	rotated, err := cl.NewRotateToken(tokens[0]).Do(ctx)
+	if !assert.NoError(t, err, `rotation should succeed`) {
+		return
+	}
+
+	after, err := as.Resolve(ctx, tokens[1].Value())
+	if !assert.NoError(t, err, `Resolve should succeed`) {
+		return
+	}
+	if !assert.True(t, before.IssuedAt.Equal(after.IssuedAt), `issue time of the other token should not change`) {
+		return
+	}
+	if !assert.True(t, before.ExpiresAt.Equal(after.ExpiresAt), `expiry of the other token should not change`) {
+		return
+	}
+
+	resolved, err := as.Resolve(ctx, rotated.Value())
+	if !assert.NoError(t, err, `Resolve should succeed`) {
+		return
+	}
+	if !assert.True(t, resolved.IssuedAt.After(before.IssuedAt), `rotated token should have a later issue time`) {
+		return
+	}
+}
+
+func TestConcurrentRotation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	as := newTestServer(t, decide(server.Approve), server.WithStorage(slowStorage{server.NewMemoryStorage()}))
+	key := newClientKey(t, gnap.HTTPSig)
+	cl := newSignedClient(t, as.URL+`/grant`, key)
+
+	res, err := cl.NewGrantRequest().
+		Client(gnap.NewClient(*key)).
+		AddAccessTokens(photoAccess()).
+		Do(ctx)
+	if !assert.NoError(t, err, `Do should succeed`) {
+		return
+	}
+	token := res.AccessToken()
+
+	const count = 10
+	var wg sync.WaitGroup
+	rotated := make(chan *gnap.AccessToken, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := cl.NewRotateToken(token).Do(ctx); err == nil {
+				rotated <- v
+			}
+		}()
+	}
+	wg.Wait()
+	close(rotated)
+
+	if !assert.Len(t, rotated, 1, `only one rotation should succeed`) {
+		return
+	}
+	if !assert.NoError(t, cl.NewRevokeToken(<-rotated).Do(ctx), `rotated token should be valid`) {
+		return
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	as := newTestServer(t, decide(server.Approve), server.WithTokenLifetime(time.Hour))
+	key := newClientKey(t, gnap.HTTPSig)
+	cl := newSignedClient(t, as.URL+`/grant`, key)
+	res, err := cl.NewGrantRequest().
+		Client(gnap.NewClient(*key)).
+		AddAccessTokens(photoAccess()).
+		Do(ctx)
+	if !assert.NoError(t, err, `Do should succeed`) {
+		return
+	}
+
+	t.Run("Issued Token", func(t *testing.T) {
+		token, err := as.Resolve(ctx, res.AccessToken().Value())
+		if !assert.NoError(t, err, `Resolve should succeed`) {
+			return
+		}
+		if !assert.Equal(t, `photo-api`, token.Access[0].Object().Type(), `access should match`) {
+			return
+		}
+		if !assert.NotNil(t, token.Key, `token should be bound to the client key`) {
+			return
+		}
+		if !assert.WithinDuration(t, time.Now().Add(time.Hour), token.ExpiresAt, time.Minute, `expiration should match`) {
+			return
+		}
+	})
+	t.Run("Unknown Token", func(t *testing.T) {
+		_, err := as.Resolve(ctx, `unknown`)
+		if !assert.True(t, errors.Is(err, rs.ErrInvalidToken), `error should be rs.ErrInvalidToken`) {
+			return
+		}
+	})
+}
+
+func TestIntrospection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rsKey := newClientKey(t, gnap.HTTPSig)
+	as := newTestServer(t, decide(server.Approve),
+		server.WithResourceServer(`photos`, rsKey),
+		server.WithTokenLifetime(time.Hour),
+	)
+	key := newClientKey(t, gnap.HTTPSig)
+	cl := newSignedClient(t, as.URL+`/grant`, key)
+	res, err := cl.NewGrantRequest().
+		Client(gnap.NewClient(*key)).
+		AddAccessTokens(photoAccess()).
+		Do(ctx)
+	if !assert.NoError(t, err, `Do should succeed`) {
+		return
+	}
+	token := res.AccessToken()
+
+	signer, err := proof.NewSigner(rsKey)
+	if !assert.NoError(t, err, `proof.NewSigner should succeed`) {
+		return
+	}
+
+	t.Run("Active Token", func(t *testing.T) {
+		introspector := rs.NewIntrospector(as.URL+`/introspect`,
+			rs.WithResourceServer(`photos`),
+			rs.WithSigner(signer),
+		)
+		resolved, err := introspector.Resolve(ctx, token.Value())
+		if !assert.NoError(t, err, `Resolve should succeed`) {
+			return
+		}
+		if !assert.Equal(t, `photo-api`, resolved.Access[0].Object().Type(), `access should match`) {
+			return
+		}
+		if !assert.NotNil(t, resolved.Key, `token should be bound to the client key`) {
+			return
+		}
+		if !assert.WithinDuration(t, time.Now().Add(time.Hour), resolved.ExpiresAt, time.Minute, `expiration should match`) {
+			return
+		}
+	})
+	t.Run("Unsigned Request", func(t *testing.T) {
+		introspector := rs.NewIntrospector(as.URL+`/introspect`, rs.WithResourceServer(`photos`))
+		_, err := introspector.Resolve(ctx, token.Value())
+		if !assert.Error(t, err, `Resolve should fail`) {
+			return
+		}
+		if !assert.False(t, errors.Is(err, rs.ErrInvalidToken), `error should not be rs.ErrInvalidToken`) {
+			return
+		}
+	})
+	t.Run("Revoked Token", func(t *testing.T) {
+		if !assert.NoError(t, cl.NewRevokeToken(token).Do(ctx), `Do should succeed`) {
+			return
+		}
+		introspector := rs.NewIntrospector(as.URL+`/introspect`,
+			rs.WithResourceServer(`photos`),
+			rs.WithSigner(signer),
+		)
+		_, err := introspector.Resolve(ctx, token.Value())
+		if !assert.True(t, errors.Is(err, rs.ErrInvalidToken), `error should be rs.ErrInvalidToken`) {
+			return
+		}
+	})
+}
+
+func TestKeyReference(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := newClientKey(t, gnap.HTTPSig)
+	as := newTestServer(t, decide(server.Approve), server.WithKeyRegistry(server.KeyRegistryMap{
+		`client-1`: key,
+	}))
+	cl := newSignedClient(t, as.URL+`/grant`, key)
+
+	t.Run("Registered Key", func(t *testing.T) {
+		res, err := cl.NewGrantRequest().
+			Client(gnap.NewClient(*gnap.NewKeyReference(`client-1`))).
+			AddAccessTokens(photoAccess()).
+			Do(ctx)
+		if !assert.NoError(t, err, `Do should succeed`) {
+			return
+		}
+
+		token, err := as.Resolve(ctx, res.AccessToken().Value())
+		if !assert.NoError(t, err, `Resolve should succeed`) {
+			return
+		}
+		if !assert.Equal(t, key, token.Key, `token should be bound to the registered key`) {
+			return
+		}
+	})
+	t.Run("Unknown Reference", func(t *testing.T) {
+		_, err := cl.NewGrantRequest().
+			Client(gnap.NewClient(*gnap.NewKeyReference(`client-2`))).
+			AddAccessTokens(photoAccess()).
+			Do(ctx)
+		if !assert.Equal(t, gnap.InvalidClient, gnap.ErrorCode(err), `error should be invalid_client`) {
+			return
+		}
+	})
+	t.Run("Wrong Key", func(t *testing.T) {
+		other := newSignedClient(t, as.URL+`/grant`, newClientKey(t, gnap.HTTPSig))
+		_, err := other.NewGrantRequest().
+			Client(gnap.NewClient(*gnap.NewKeyReference(`client-1`))).
+			AddAccessTokens(photoAccess()).
+			Do(ctx)
+		if !assert.Equal(t, gnap.InvalidClient, gnap.ErrorCode(err), `error should be invalid_client`) {
+			return
+		}
+	})
+}
+
+// displayRegistry is a KeyRegistry that also provides the display
+// information of the registered clients
+type displayRegistry struct {
+	server.KeyRegistryMap
+	displays map[string]*gnap.ClientDisplay
+}
+
+func (r displayRegistry) LookupDisplay(_ context.Context, ref string) (*gnap.ClientDisplay, error) {
+	return r.displays[ref], nil
+}
+
+func TestInteractHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := newClientKey(t, gnap.HTTPSig)
+
+	registered := gnap.NewClientDisplay()
+	registered.SetName(`Registered Client`)
+	registered.SetURI(`https://example.com/registered`)
+
+	as := newTestServer(t, decide(server.Interact),
+		server.WithKeyRegistry(displayRegistry{
+			KeyRegistryMap: server.KeyRegistryMap{
+				`client-1`: key,
+				`client-2`: key,
+			},
+			displays: map[string]*gnap.ClientDisplay{
+				`client-1`: registered,
+			},
+		}),
+	)
+	cl := newSignedClient(t, as.URL+`/grant`, key)
+
+	consent := template.Must(template.New(`consent`).Parse(`{{ with . }}{{ .Name }} ({{ .URI }}){{ else }}unknown client{{ end }}`))
+	as.Handle(`/interact`, as.InteractHandler(func(w http.ResponseWriter, r *http.Request, grant *server.Grant, display *gnap.ClientDisplay, err error) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		//nolint:errcheck
+		consent.Execute(w, display)
+	}))
+
+	start := func(t *testing.T, c *gnap.Client) string {
+		t.Helper()
+
+		res, err := cl.NewGrantRequest().
+			Client(c).
+			AddAccessTokens(photoAccess()).
+			Interact(gnap.NewInteractionRequest(gnap.StartRedirect)).
+			Do(ctx)
+		if !assert.NoError(t, err, `Do should succeed`) {
+			t.FailNow()
+		}
+		return res.Interact().Redirect()
+	}
+
+	get := func(t *testing.T, uri string) (int, string) {
+		t.Helper()
+
+		res, err := http.Get(uri)
+		if !assert.NoError(t, err, `http.Get should succeed`) {
+			t.FailNow()
+		}
+		defer res.Body.Close()
+
+		body, err := ioutil.ReadAll(res.Body)
+		if !assert.NoError(t, err, `reading the body should succeed`) {
+			t.FailNow()
+		}
+		return res.StatusCode, string(body)
+	}
+
+	spoofed := gnap.NewClientDisplay()
+	spoofed.SetName(`My Client Display Name`)
+	spoofed.SetURI(`https://example.net/client`)
+
+	t.Run("With Display", func(t *testing.T) {
+		c := gnap.NewClient(*key)
+		c.SetDisplay(spoofed)
+
+		status, body := get(t, start(t, c))
+		if !assert.Equal(t, http.StatusOK, status, `status should be 200`) {
+			return
+		}
+		if !assert.Equal(t, `My Client Display Name (https://example.net/client)`, body, `consent page should show the client`) {
+			return
+		}
+	})
+	t.Run("Registered Client", func(t *testing.T) {
+		c := gnap.NewClient(*gnap.NewKeyReference(`client-1`))
+		c.SetDisplay(spoofed)
+
+		status, body := get(t, start(t, c))
+		if !assert.Equal(t, http.StatusOK, status, `status should be 200`) {
+			return
+		}
+		if !assert.Equal(t, `Registered Client (https://example.com/registered)`, body, `consent page should show the registered display`) {
+			return
+		}
+	})
+	t.Run("Registered Client Without Display", func(t *testing.T) {
+		c := gnap.NewClient(*gnap.NewKeyReference(`client-2`))
+		c.SetDisplay(spoofed)
+
+		status, body := get(t, start(t, c))
+		if !assert.Equal(t, http.StatusOK, status, `status should be 200`) {
+			return
+		}
+		if !assert.Equal(t, `unknown client`, body, `consent page should not show the display sent by the client`) {
+			return
+		}
+	})
+	t.Run("Without Display", func(t *testing.T) {
+		status, body := get(t, start(t, gnap.NewClient(*key)))
+		if !assert.Equal(t, http.StatusOK, status, `status should be 200`) {
+			return
+		}
+		if !assert.Equal(t, `unknown client`, body, `consent page should not show a client`) {
+			return
+		}
+	})
+	t.Run("Unknown Grant", func(t *testing.T) {
+		status, _ := get(t, as.URL+`/interact?id=unknown`)
+		if !assert.Equal(t, http.StatusNotFound, status, `status should be 404`) {
+			return
+		}
+	})
+	t.Run("Finished Grant", func(t *testing.T) {
+		redirect := start(t, gnap.NewClient(*key))
+		u, err := url.Parse(redirect)
+		if !assert.NoError(t, err, `redirect should be a valid URL`) {
+			return
+		}
+		if _, err := as.FinishInteraction(ctx, u.Query().Get(`id`), false); !assert.NoError(t, err, `FinishInteraction should succeed`) {
+			return
+		}
+		status, _ := get(t, redirect)
+		if !assert.Equal(t, http.StatusNotFound, status, `status should be 404`) {
+			return
+		}
+	})
+}
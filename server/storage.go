@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/pkg/errors"
+)
+
+// ErrGrantNotFound is returned by Storage implementations when the
+// requested grant does not exist
+var ErrGrantNotFound = errors.New(`grant not found`)
+
+// GrantState describes where a grant is in its lifecycle
+type GrantState string
+
+const (
+	// GrantProcessing is the state of a grant request that is being
+	// evaluated by the server
+	GrantProcessing GrantState = "processing"
+	// GrantPending is the state of a grant that is waiting for the
+	// resource owner to interact with the server
+	GrantPending GrantState = "pending"
+	// GrantApproved is the state of a grant that has been approved,
+	// but whose access tokens have not been issued yet
+	GrantApproved GrantState = "approved"
+	// GrantDenied is the state of a grant that has been denied
+	GrantDenied GrantState = "denied"
+	// GrantFinalized is the state of a grant whose access tokens
+	// have been issued, or which has been revoked
+	GrantFinalized GrantState = "finalized"
+)
+
+// Grant is the record of a grant request kept by the server
+type Grant struct {
+	ID                string              `json:"id"`
+	State             GrantState          `json:"state"`
+	Request           *gnap.GrantRequest  `json:"request,omitempty"`
+	ContinuationToken string              `json:"continuation_token,omitempty"`
+	InteractRef       string              `json:"interact_ref,omitempty"`
+	UserCode          string              `json:"user_code,omitempty"`
+	ServerNonce       string              `json:"server_nonce,omitempty"`
+	GrantEndpoint     string              `json:"grant_endpoint,omitempty"`
+	Tokens            []*gnap.AccessToken `json:"tokens,omitempty"`
+	IssuedAt          time.Time           `json:"issued_at"`
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
+
+	// TokenIssuedAt records when each access token was issued, keyed
+	// by token value. Tokens rotated after the grant was finalized
+	// have a later time than IssuedAt
+	TokenIssuedAt map[string]time.Time `json:"token_issued_at,omitempty"`
+}
+
+// setTokenIssuedAt records the time the token with the given value
+// was issued
+func (g *Grant) setTokenIssuedAt(value string, t time.Time) {
+	if g.TokenIssuedAt == nil {
+		g.TokenIssuedAt = make(map[string]time.Time)
+	}
+	g.TokenIssuedAt[value] = t
+}
+
+// tokenIssuedAt returns the time the token with the given value was
+// issued. Grants saved before issue times were recorded per token fall
+// back to the time the grant was finalized
+func (g *Grant) tokenIssuedAt(value string) time.Time {
+	if t, ok := g.TokenIssuedAt[value]; ok {
+		return t
+	}
+	return g.IssuedAt
+}
+
+// Display returns the display information sent by the client, or nil
+// if the client did not send any.
+//
+// The information is asserted by the client itself, and is not
+// verified. Consent pages should use Server.ClientDisplay instead,
+// which prefers the information registered for pre-registered clients.
+func (g *Grant) Display() *gnap.ClientDisplay {
+	if g.Request == nil || g.Request.Client() == nil {
+		return nil
+	}
+	return g.Request.Client().Display()
+}
+
+// Storage persists grants. Grants are primarily keyed by their ID, but
+// can also be looked up by the values handed out to clients.
+//
+// Implementations must return ErrGrantNotFound (possibly wrapped) when
+// the grant does not exist.
+type Storage interface {
+	// SaveGrant creates or replaces the grant with the same ID
+	SaveGrant(context.Context, *Grant) error
+	// LookupGrant returns the grant with the given ID
+	LookupGrant(ctx context.Context, id string) (*Grant, error)
+	// LookupGrantByContinuation returns the grant associated with the
+	// given continuation access token
+	LookupGrantByContinuation(ctx context.Context, token string) (*Grant, error)
+	// LookupGrantByInteractRef returns the grant associated with the
+	// given interaction reference
+	LookupGrantByInteractRef(ctx context.Context, ref string) (*Grant, error)
+	// LookupGrantByUserCode returns the grant associated with the
+	// given user code
+	LookupGrantByUserCode(ctx context.Context, code string) (*Grant, error)
+	// LookupGrantByToken returns the grant which issued the access
+	// token with the given value
+	LookupGrantByToken(ctx context.Context, value string) (*Grant, error)
+	// DeleteGrant removes the grant with the given ID
+	DeleteGrant(ctx context.Context, id string) error
+}
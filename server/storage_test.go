@@ -0,0 +1,214 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/gnap"
+	"github.com/lestrrat-go/gnap/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func newGrant() *server.Grant {
+	var token gnap.AccessToken
+	token.SetValue(`token-value`)
+	token.AddAccess(photoAccess().Access()...)
+
+	now := time.Now().Truncate(time.Second)
+	return &server.Grant{
+		ID:                `grant-id`,
+		State:             server.GrantPending,
+		Request:           gnap.NewGrantRequest().AddAccessTokens(photoAccess()),
+		ContinuationToken: `continuation-token`,
+		InteractRef:       `interact-ref`,
+		UserCode:          `BCDFGHJK`,
+		Tokens:            []*gnap.AccessToken{&token},
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+}
+
+func testStorage(t *testing.T, storage server.Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	if !assert.NoError(t, storage.SaveGrant(ctx, newGrant()), `SaveGrant should succeed`) {
+		return
+	}
+
+	lookups := map[string]func() (*server.Grant, error){
+		"ID": func() (*server.Grant, error) {
+			return storage.LookupGrant(ctx, `grant-id`)
+		},
+		"Continuation": func() (*server.Grant, error) {
+			return storage.LookupGrantByContinuation(ctx, `continuation-token`)
+		},
+		"InteractRef": func() (*server.Grant, error) {
+			return storage.LookupGrantByInteractRef(ctx, `interact-ref`)
+		},
+		"UserCode": func() (*server.Grant, error) {
+			return storage.LookupGrantByUserCode(ctx, `BCDFGHJK`)
+		},
+		"Token": func() (*server.Grant, error) {
+			return storage.LookupGrantByToken(ctx, `token-value`)
+		},
+	}
+	for name, lookup := range lookups {
+		lookup := lookup
+		t.Run("Lookup By "+name, func(t *testing.T) {
+			g, err := lookup()
+			if !assert.NoError(t, err, `lookup should succeed`) {
+				return
+			}
+			if !assert.Equal(t, `grant-id`, g.ID, `grant ID should match`) {
+				return
+			}
+			if !assert.Equal(t, server.GrantPending, g.State, `state should match`) {
+				return
+			}
+		})
+	}
+
+	t.Run("Not Found", func(t *testing.T) {
+		_, err := storage.LookupGrantByContinuation(ctx, `unknown`)
+		if !assert.True(t, errors.Is(err, server.ErrGrantNotFound), `error should be ErrGrantNotFound`) {
+			return
+		}
+	})
+
+	t.Run("Isolation", func(t *testing.T) {
+		g, err := storage.LookupGrant(ctx, `grant-id`)
+		if !assert.NoError(t, err, `LookupGrant should succeed`) {
+			return
+		}
+		g.Tokens[0].SetValue(`modified-token-value`)
+		g.Request.AccessTokens()[0].SetLabel(`modified-label`)
+
+		g, err = storage.LookupGrant(ctx, `grant-id`)
+		if !assert.NoError(t, err, `LookupGrant should succeed`) {
+			return
+		}
+		if !assert.Equal(t, `token-value`, g.Tokens[0].Value(), `stored token should not be modified`) {
+			return
+		}
+		if !assert.Empty(t, g.Request.AccessTokens()[0].Label(), `stored request should not be modified`) {
+			return
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		g, err := storage.LookupGrant(ctx, `grant-id`)
+		if !assert.NoError(t, err, `LookupGrant should succeed`) {
+			return
+		}
+		g.State = server.GrantFinalized
+		if !assert.NoError(t, storage.SaveGrant(ctx, g), `SaveGrant should succeed`) {
+			return
+		}
+
+		g, err = storage.LookupGrant(ctx, `grant-id`)
+		if !assert.NoError(t, err, `LookupGrant should succeed`) {
+			return
+		}
+		if !assert.Equal(t, server.GrantFinalized, g.State, `state should be updated`) {
+			return
+		}
+	})
+}
+
+func TestMemoryStorage(t *testing.T) {
+	storage := server.NewMemoryStorage()
+	testStorage(t, storage)
+
+	ctx := context.Background()
+	if !assert.NoError(t, storage.DeleteGrant(ctx, `grant-id`), `DeleteGrant should succeed`) {
+		return
+	}
+	_, err := storage.LookupGrant(ctx, `grant-id`)
+	if !assert.True(t, errors.Is(err, server.ErrGrantNotFound), `grant should be deleted`) {
+		return
+	}
+}
+
+func TestFileStorage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `grants.json`)
+
+	storage, err := server.NewFileStorage(path)
+	if !assert.NoError(t, err, `NewFileStorage should succeed`) {
+		return
+	}
+	testStorage(t, storage)
+
+	t.Run("Reload", func(t *testing.T) {
+		ctx := context.Background()
+		reloaded, err := server.NewFileStorage(path)
+		if !assert.NoError(t, err, `NewFileStorage should succeed`) {
+			return
+		}
+
+		g, err := reloaded.LookupGrantByToken(ctx, `token-value`)
+		if !assert.NoError(t, err, `LookupGrantByToken should succeed`) {
+			return
+		}
+		if !assert.Equal(t, server.GrantFinalized, g.State, `state should match`) {
+			return
+		}
+		if !assert.Equal(t, `photo-api`, g.Request.AccessTokens()[0].Access()[0].Object().Type(), `request should be restored`) {
+			return
+		}
+
+		if !assert.NoError(t, reloaded.DeleteGrant(ctx, `grant-id`), `DeleteGrant should succeed`) {
+			return
+		}
+		reloaded, err = server.NewFileStorage(path)
+		if !assert.NoError(t, err, `NewFileStorage should succeed`) {
+			return
+		}
+		_, err = reloaded.LookupGrant(ctx, `grant-id`)
+		if !assert.True(t, errors.Is(err, server.ErrGrantNotFound), `grant should be deleted`) {
+			return
+		}
+	})
+}
+
+func TestFileStorageWriteError(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), `grants`)
+	if !assert.NoError(t, os.Mkdir(dir, 0700), `os.Mkdir should succeed`) {
+		return
+	}
+
+	storage, err := server.NewFileStorage(filepath.Join(dir, `grants.json`))
+	if !assert.NoError(t, err, `NewFileStorage should succeed`) {
+		return
+	}
+	if !assert.NoError(t, storage.SaveGrant(ctx, newGrant()), `SaveGrant should succeed`) {
+		return
+	}
+
+	// remove the directory, so that the file can no longer be written
+	if !assert.NoError(t, os.RemoveAll(dir), `os.RemoveAll should succeed`) {
+		return
+	}
+
+	g := newGrant()
+	g.State = server.GrantFinalized
+	if !assert.Error(t, storage.SaveGrant(ctx, g), `SaveGrant should fail`) {
+		return
+	}
+	if !assert.Error(t, storage.DeleteGrant(ctx, `grant-id`), `DeleteGrant should fail`) {
+		return
+	}
+
+	g, err = storage.LookupGrant(ctx, `grant-id`)
+	if !assert.NoError(t, err, `LookupGrant should succeed`) {
+		return
+	}
+	if !assert.Equal(t, server.GrantPending, g.State, `grant in memory should not be modified`) {
+		return
+	}
+}
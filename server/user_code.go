@@ -0,0 +1,194 @@
+package server
+
+import (
+	"crypto/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultUserCodeAttempts is the number of failed user code attempts
+// that the default UserCodeThrottle allows within
+// DefaultUserCodeWindow
+const DefaultUserCodeAttempts = 10
+
+// DefaultUserCodeWindow is the duration over which the default
+// UserCodeThrottle counts failed user code attempts
+const DefaultUserCodeWindow = 10 * time.Minute
+
+// ErrTooManyAttempts is passed to UserCodeFunc when the resource owner
+// made too many failed user code attempts
+var ErrTooManyAttempts = errors.New(`too many failed user code attempts`)
+
+// userCodeAlphabet is the set of characters used in user codes. Vowels
+// are excluded to avoid forming words, and so are characters that are
+// easily confused with digits.
+const userCodeAlphabet = `BCDFGHJKLMNPQRSTVWXZ`
+
+const userCodeLength = 8
+
+// newUserCode generates a user code that is easy to type
+func newUserCode() (string, error) {
+	// bytes at or above the largest multiple of len(userCodeAlphabet)
+	// are discarded, so that the characters are uniformly distributed
+	const limit = 256 - 256%len(userCodeAlphabet)
+
+	code := make([]byte, 0, userCodeLength)
+	buf := make([]byte, userCodeLength)
+	for len(code) < userCodeLength {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			code = append(code, userCodeAlphabet[int(b)%len(userCodeAlphabet)])
+			if len(code) == userCodeLength {
+				break
+			}
+		}
+	}
+	return string(code), nil
+}
+
+// normalizeUserCode converts a user code entered by the resource owner
+// to the generated form, ignoring case, spaces, and dashes
+func normalizeUserCode(v string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(v))
+}
+
+// UserCodeThrottle limits the number of failed user code attempts, so
+// that user codes cannot be guessed by brute force
+type UserCodeThrottle interface {
+	// Allow returns false if the sender of `r` must not make another
+	// attempt
+	Allow(r *http.Request) bool
+	// Fail records a failed attempt made by the sender of `r`
+	Fail(r *http.Request)
+}
+
+// MemoryUserCodeThrottle is a UserCodeThrottle that counts failed
+// attempts in memory, keyed by the remote address of the request.
+// Servers behind a proxy should use their own UserCodeThrottle.
+type MemoryUserCodeThrottle struct {
+	max      int
+	window   time.Duration
+	mu       sync.Mutex
+	attempts map[string]*userCodeAttempts
+}
+
+type userCodeAttempts struct {
+	count int
+	reset time.Time
+}
+
+// NewMemoryUserCodeThrottle creates a MemoryUserCodeThrottle that
+// allows `max` failed attempts per remote address within `window`
+func NewMemoryUserCodeThrottle(max int, window time.Duration) *MemoryUserCodeThrottle {
+	return &MemoryUserCodeThrottle{
+		max:      max,
+		window:   window,
+		attempts: make(map[string]*userCodeAttempts),
+	}
+}
+
+func (t *MemoryUserCodeThrottle) Allow(r *http.Request) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune(time.Now())
+	a, ok := t.attempts[remoteHost(r)]
+	return !ok || a.count < t.max
+}
+
+func (t *MemoryUserCodeThrottle) Fail(r *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.prune(now)
+	host := remoteHost(r)
+	a, ok := t.attempts[host]
+	if !ok {
+		a = &userCodeAttempts{reset: now.Add(t.window)}
+		t.attempts[host] = a
+	}
+	a.count++
+}
+
+// prune removes the attempts whose window has passed. The caller must
+// hold the lock
+func (t *MemoryUserCodeThrottle) prune(now time.Time) {
+	for k, v := range t.attempts {
+		if now.After(v.reset) {
+			delete(t.attempts, k)
+		}
+	}
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// UserCodeFunc is called by the user code handler with the pending
+// grant that matches the user code entered by the resource owner. If
+// no pending grant matches, `grant` is nil and `err` is non-nil.
+//
+// The function is responsible for writing the response to `w`,
+// typically a page where the resource owner approves or denies the
+// grant. Once the resource owner has made a decision, call
+// Server.FinishInteraction with the ID of the grant.
+type UserCodeFunc func(w http.ResponseWriter, r *http.Request, grant *Grant, err error)
+
+// UserCodeHandler returns the http.Handler for the user code endpoint
+// (see WithUserCodeEndpoint). The user code is read from the `code`
+// form value, and matched against the pending grants.
+//
+// Failed attempts are counted by the UserCodeThrottle of the server
+// (see WithUserCodeThrottle). Once the throttle refuses further
+// attempts, `fn` is called with ErrTooManyAttempts.
+func (s *Server) UserCodeHandler(fn UserCodeFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.userCodeThrottle.Allow(r) {
+			fn(w, r, nil, ErrTooManyAttempts)
+			return
+		}
+
+		code := normalizeUserCode(r.FormValue(`code`))
+		if code == "" {
+			fn(w, r, nil, errors.New(`user code is required`))
+			return
+		}
+
+		grant, err := s.storage.LookupGrantByUserCode(r.Context(), code)
+		if err != nil {
+			if errors.Is(err, ErrGrantNotFound) {
+				s.userCodeThrottle.Fail(r)
+			}
+			fn(w, r, nil, errors.Wrap(err, `failed to lookup grant`))
+			return
+		}
+
+		if grant.State != GrantPending {
+			s.userCodeThrottle.Fail(r)
+			fn(w, r, nil, errors.Wrap(ErrGrantNotFound, `grant is not pending interaction`))
+			return
+		}
+		fn(w, r, grant, nil)
+	})
+}
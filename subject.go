@@ -0,0 +1,105 @@
+package gnap
+
+import (
+	"github.com/pkg/errors"
+)
+
+// NewOpaqueSubjectID creates a SubjectIdentifier in the `opaque` format
+func NewOpaqueSubjectID(id string) *SubjectIdentifier {
+	v := NewSubjectIdentifier(SubIDOpaque)
+	v.SetID(id)
+	return v
+}
+
+// NewEmailSubjectID creates a SubjectIdentifier in the `email` format
+func NewEmailSubjectID(email string) *SubjectIdentifier {
+	v := NewSubjectIdentifier(SubIDEmail)
+	v.SetEmail(email)
+	return v
+}
+
+// NewIssSubSubjectID creates a SubjectIdentifier in the `iss_sub`
+// format
+func NewIssSubSubjectID(iss, sub string) *SubjectIdentifier {
+	v := NewSubjectIdentifier(SubIDIssSub)
+	v.SetIss(iss)
+	v.SetSub(sub)
+	return v
+}
+
+// NewDIDSubjectID creates a SubjectIdentifier in the `did` format
+func NewDIDSubjectID(url string) *SubjectIdentifier {
+	v := NewSubjectIdentifier(SubIDDID)
+	v.SetURL(url)
+	return v
+}
+
+// validateFormat checks that the fields required by the format of
+// the identifier are present
+func (c *SubjectIdentifier) validateFormat() error {
+	var missing string
+	switch c.Format() {
+	case SubIDAccount:
+		if c.uri == nil {
+			missing = "uri"
+		}
+	case SubIDAliases:
+		if len(c.identifiers) == 0 {
+			missing = "identifiers"
+		}
+		for _, v := range c.identifiers {
+			if err := v.Validate(); err != nil {
+				return errors.Wrap(err, `invalid identifier in "identifiers"`)
+			}
+		}
+	case SubIDDID:
+		if c.url == nil {
+			missing = "url"
+		}
+	case SubIDEmail:
+		if c.email == nil {
+			missing = "email"
+		}
+	case SubIDIssSub:
+		if c.iss == nil {
+			missing = "iss"
+		} else if c.sub == nil {
+			missing = "sub"
+		}
+	case SubIDOpaque:
+		if c.id == nil {
+			missing = "id"
+		}
+	case SubIDPhoneNumber:
+		if c.phoneNumber == nil {
+			missing = "phone_number"
+		}
+	}
+
+	if missing != "" {
+		return errors.Errorf(`field %q is required for format %q`, missing, c.Format())
+	}
+	return nil
+}
+
+// SubjectID returns the first subject identifier in the given format,
+// or nil if there is none
+func (c *SubjectResponse) SubjectID(format SubjectIDFormat) *SubjectIdentifier {
+	for i := range c.subIDs {
+		if c.subIDs[i].Format() == format {
+			return &c.subIDs[i]
+		}
+	}
+	return nil
+}
+
+// Assertion returns the first assertion in the given format, or nil
+// if there is none
+func (c *SubjectResponse) Assertion(format AssertionFormat) *Assertion {
+	for i := range c.assertions {
+		if c.assertions[i].Format() == format {
+			return &c.assertions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,457 @@
+package gnap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/lestrrat-go/iter/mapiter"
+	"github.com/pkg/errors"
+)
+
+// SubjectIdentifier identifies a subject. The fields that are used depend on the format
+type SubjectIdentifier struct {
+	email       *string
+	format      *SubjectIDFormat
+	id          *string
+	identifiers []SubjectIdentifier
+	iss         *string
+	phoneNumber *string
+	sub         *string
+	uri         *string
+	url         *string
+	extraFields map[string]interface{}
+}
+
+func NewSubjectIdentifier(format SubjectIDFormat) *SubjectIdentifier {
+	return &SubjectIdentifier{
+		format: &format,
+	}
+}
+
+func (c *SubjectIdentifier) Validate() error {
+	if c.format == nil {
+		return errors.Errorf(`field "format" is required`)
+	}
+	if err := c.validateFormat(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *SubjectIdentifier) Get(key string) (interface{}, bool) {
+	switch key {
+	case "email":
+		if c.email == nil {
+			return nil, false
+		}
+		return c.email, true
+	case "format":
+		if c.format == nil {
+			return nil, false
+		}
+		return c.format, true
+	case "id":
+		if c.id == nil {
+			return nil, false
+		}
+		return c.id, true
+	case "identifiers":
+		if len(c.identifiers) == 0 {
+			return nil, false
+		}
+		return c.identifiers, true
+	case "iss":
+		if c.iss == nil {
+			return nil, false
+		}
+		return c.iss, true
+	case "phone_number":
+		if c.phoneNumber == nil {
+			return nil, false
+		}
+		return c.phoneNumber, true
+	case "sub":
+		if c.sub == nil {
+			return nil, false
+		}
+		return c.sub, true
+	case "uri":
+		if c.uri == nil {
+			return nil, false
+		}
+		return c.uri, true
+	case "url":
+		if c.url == nil {
+			return nil, false
+		}
+		return c.url, true
+	default:
+		if c.extraFields == nil {
+			return nil, false
+		}
+		v, ok := c.extraFields[key]
+		return v, ok
+	}
+}
+
+func (c *SubjectIdentifier) Set(key string, value interface{}) error {
+	switch key {
+	case "email":
+		if v, ok := value.(string); ok {
+			c.email = &v
+		} else if value == nil {
+			c.email = nil
+		} else {
+			return errors.Errorf(`invalid type for "email" (%T)`, value)
+		}
+	case "format":
+		if v, ok := value.(*SubjectIDFormat); ok {
+			c.format = v
+		} else {
+			return errors.Errorf(`invalid type for "format" (%T)`, value)
+		}
+	case "id":
+		if v, ok := value.(string); ok {
+			c.id = &v
+		} else if value == nil {
+			c.id = nil
+		} else {
+			return errors.Errorf(`invalid type for "id" (%T)`, value)
+		}
+	case "identifiers":
+		if v, ok := value.([]SubjectIdentifier); ok {
+			c.identifiers = v
+		} else {
+			return errors.Errorf(`invalid type for "identifiers" (%T)`, value)
+		}
+	case "iss":
+		if v, ok := value.(string); ok {
+			c.iss = &v
+		} else if value == nil {
+			c.iss = nil
+		} else {
+			return errors.Errorf(`invalid type for "iss" (%T)`, value)
+		}
+	case "phone_number":
+		if v, ok := value.(string); ok {
+			c.phoneNumber = &v
+		} else if value == nil {
+			c.phoneNumber = nil
+		} else {
+			return errors.Errorf(`invalid type for "phone_number" (%T)`, value)
+		}
+	case "sub":
+		if v, ok := value.(string); ok {
+			c.sub = &v
+		} else if value == nil {
+			c.sub = nil
+		} else {
+			return errors.Errorf(`invalid type for "sub" (%T)`, value)
+		}
+	case "uri":
+		if v, ok := value.(string); ok {
+			c.uri = &v
+		} else if value == nil {
+			c.uri = nil
+		} else {
+			return errors.Errorf(`invalid type for "uri" (%T)`, value)
+		}
+	case "url":
+		if v, ok := value.(string); ok {
+			c.url = &v
+		} else if value == nil {
+			c.url = nil
+		} else {
+			return errors.Errorf(`invalid type for "url" (%T)`, value)
+		}
+	default:
+		if c.extraFields == nil {
+			c.extraFields = make(map[string]interface{})
+		}
+		c.extraFields[key] = value
+	}
+	return nil
+}
+
+func (c *SubjectIdentifier) SetEmail(v string) {
+	c.email = &v
+}
+
+func (c *SubjectIdentifier) Email() string {
+	if c.email == nil {
+		return ""
+	}
+	return *(c.email)
+}
+
+func (c *SubjectIdentifier) SetFormat(v SubjectIDFormat) {
+	c.format = &v
+}
+
+func (c *SubjectIdentifier) Format() SubjectIDFormat {
+	if c.format == nil {
+		return ""
+	}
+	return *(c.format)
+}
+
+func (c *SubjectIdentifier) SetID(v string) {
+	c.id = &v
+}
+
+func (c *SubjectIdentifier) ID() string {
+	if c.id == nil {
+		return ""
+	}
+	return *(c.id)
+}
+
+func (c *SubjectIdentifier) AddIdentifiers(v ...SubjectIdentifier) *SubjectIdentifier {
+	c.identifiers = append(c.identifiers, v...)
+	return c
+}
+
+func (c *SubjectIdentifier) Identifiers() []SubjectIdentifier {
+	return c.identifiers
+}
+
+func (c *SubjectIdentifier) SetIss(v string) {
+	c.iss = &v
+}
+
+func (c *SubjectIdentifier) Iss() string {
+	if c.iss == nil {
+		return ""
+	}
+	return *(c.iss)
+}
+
+func (c *SubjectIdentifier) SetPhoneNumber(v string) {
+	c.phoneNumber = &v
+}
+
+func (c *SubjectIdentifier) PhoneNumber() string {
+	if c.phoneNumber == nil {
+		return ""
+	}
+	return *(c.phoneNumber)
+}
+
+func (c *SubjectIdentifier) SetSub(v string) {
+	c.sub = &v
+}
+
+func (c *SubjectIdentifier) Sub() string {
+	if c.sub == nil {
+		return ""
+	}
+	return *(c.sub)
+}
+
+func (c *SubjectIdentifier) SetURI(v string) {
+	c.uri = &v
+}
+
+func (c *SubjectIdentifier) URI() string {
+	if c.uri == nil {
+		return ""
+	}
+	return *(c.uri)
+}
+
+func (c *SubjectIdentifier) SetURL(v string) {
+	c.url = &v
+}
+
+func (c *SubjectIdentifier) URL() string {
+	if c.url == nil {
+		return ""
+	}
+	return *(c.url)
+}
+
+func (c SubjectIdentifier) MarshalJSON() ([]byte, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	buf.WriteByte('{')
+	var i int
+	for iter := c.Iterate(ctx); iter.Next(ctx); {
+		pair := iter.Pair()
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		i++
+		buf.WriteString(strconv.Quote(pair.Key.(string)))
+		buf.WriteByte(':')
+		if err := enc.Encode(pair.Value); err != nil {
+			return nil, errors.Wrapf(err, `failed to encode %s`, pair.Key.(string))
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (c *SubjectIdentifier) UnmarshalJSON(data []byte) error {
+	c.email = nil
+	c.format = nil
+	c.id = nil
+	c.identifiers = nil
+	c.iss = nil
+	c.phoneNumber = nil
+	c.sub = nil
+	c.uri = nil
+	c.url = nil
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return errors.Wrap(err, `error reading token`)
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok != '{' {
+			return errors.Errorf(`expected '{', but got '%c'`, tok)
+		}
+	default:
+		return errors.Errorf(`expected '{', but got '%c'`, tok)
+	}
+LOOP:
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return errors.Wrap(err, `error reading token`)
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			if tok == '}' { // End of object
+				break LOOP
+			}
+			return errors.Errorf(`unexpected delimiter '%c'`, tok)
+		case string:
+			switch tok {
+			case "email":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading email`)
+				}
+				c.email = &tmp
+			case "format":
+				if err := dec.Decode(&(c.format)); err != nil {
+					return errors.Wrap(err, `error reading format`)
+				}
+			case "id":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading id`)
+				}
+				c.id = &tmp
+			case "identifiers":
+				if err := dec.Decode(&(c.identifiers)); err != nil {
+					return errors.Wrap(err, `error reading identifiers`)
+				}
+			case "iss":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading iss`)
+				}
+				c.iss = &tmp
+			case "phone_number":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading phone_number`)
+				}
+				c.phoneNumber = &tmp
+			case "sub":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading sub`)
+				}
+				c.sub = &tmp
+			case "uri":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading uri`)
+				}
+				c.uri = &tmp
+			case "url":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading url`)
+				}
+				c.url = &tmp
+			default:
+				var tmp interface{}
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrapf(err, `error reading %s`, tok)
+				}
+				if c.extraFields == nil {
+					c.extraFields = map[string]interface{}{}
+				}
+				c.extraFields[tok] = tmp
+			}
+		}
+	}
+	return nil
+}
+
+func (c *SubjectIdentifier) makePairs() []*mapiter.Pair {
+	var pairs []*mapiter.Pair
+	if tmp := c.email; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "email", Value: *tmp})
+	}
+	if tmp := c.format; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "format", Value: *tmp})
+	}
+	if tmp := c.id; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "id", Value: *tmp})
+	}
+	if tmp := c.identifiers; len(tmp) > 0 {
+		pairs = append(pairs, &mapiter.Pair{Key: "identifiers", Value: tmp})
+	}
+	if tmp := c.iss; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "iss", Value: *tmp})
+	}
+	if tmp := c.phoneNumber; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "phone_number", Value: *tmp})
+	}
+	if tmp := c.sub; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "sub", Value: *tmp})
+	}
+	if tmp := c.uri; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "uri", Value: *tmp})
+	}
+	if tmp := c.url; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "url", Value: *tmp})
+	}
+	var extraKeys []string
+	for k := range c.extraFields {
+		extraKeys = append(extraKeys, k)
+	}
+	for _, k := range extraKeys {
+		pairs = append(pairs, &mapiter.Pair{Key: k, Value: c.extraFields[k]})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.(string) < pairs[j].Key.(string)
+	})
+	return pairs
+}
+
+func (c *SubjectIdentifier) Iterate(ctx context.Context) mapiter.Iterator {
+	pairs := c.makePairs()
+	ch := make(chan *mapiter.Pair, len(pairs))
+	go func(ctx context.Context, ch chan *mapiter.Pair, pairs []*mapiter.Pair) {
+		defer close(ch)
+		for _, pair := range pairs {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- pair:
+			}
+		}
+	}(ctx, ch, pairs)
+	return mapiter.New(ch)
+}
@@ -11,10 +11,11 @@ import (
 	"github.com/pkg/errors"
 )
 
+// SubjectRequest describes the formats of the subject information requested by the client
 type SubjectRequest struct {
-	assertions  []string
-	subIDs      []string
-	extraFields map[string]interface{}
+	assertionFormats []AssertionFormat
+	subIDFormats     []SubjectIDFormat
+	extraFields      map[string]interface{}
 }
 
 func NewSubjectRequest() *SubjectRequest {
@@ -27,16 +28,16 @@ func (c *SubjectRequest) Validate() error {
 
 func (c *SubjectRequest) Get(key string) (interface{}, bool) {
 	switch key {
-	case "assertions":
-		if len(c.assertions) == 0 {
+	case "assertion_formats":
+		if len(c.assertionFormats) == 0 {
 			return nil, false
 		}
-		return c.assertions, true
-	case "sub_i_ds":
-		if len(c.subIDs) == 0 {
+		return c.assertionFormats, true
+	case "sub_id_formats":
+		if len(c.subIDFormats) == 0 {
 			return nil, false
 		}
-		return c.subIDs, true
+		return c.subIDFormats, true
 	default:
 		if c.extraFields == nil {
 			return nil, false
@@ -48,17 +49,17 @@ func (c *SubjectRequest) Get(key string) (interface{}, bool) {
 
 func (c *SubjectRequest) Set(key string, value interface{}) error {
 	switch key {
-	case "assertions":
-		if v, ok := value.([]string); ok {
-			c.assertions = v
+	case "assertion_formats":
+		if v, ok := value.([]AssertionFormat); ok {
+			c.assertionFormats = v
 		} else {
-			return errors.Errorf(`invalid type for "assertions" (%T)`, value)
+			return errors.Errorf(`invalid type for "assertion_formats" (%T)`, value)
 		}
-	case "sub_i_ds":
-		if v, ok := value.([]string); ok {
-			c.subIDs = v
+	case "sub_id_formats":
+		if v, ok := value.([]SubjectIDFormat); ok {
+			c.subIDFormats = v
 		} else {
-			return errors.Errorf(`invalid type for "sub_i_ds" (%T)`, value)
+			return errors.Errorf(`invalid type for "sub_id_formats" (%T)`, value)
 		}
 	default:
 		if c.extraFields == nil {
@@ -69,22 +70,22 @@ func (c *SubjectRequest) Set(key string, value interface{}) error {
 	return nil
 }
 
-func (c *SubjectRequest) AddAssertions(v ...string) *SubjectRequest {
-	c.assertions = append(c.assertions, v...)
+func (c *SubjectRequest) AddAssertionFormats(v ...AssertionFormat) *SubjectRequest {
+	c.assertionFormats = append(c.assertionFormats, v...)
 	return c
 }
 
-func (c *SubjectRequest) Assertions() []string {
-	return c.assertions
+func (c *SubjectRequest) AssertionFormats() []AssertionFormat {
+	return c.assertionFormats
 }
 
-func (c *SubjectRequest) AddSubIDs(v ...string) *SubjectRequest {
-	c.subIDs = append(c.subIDs, v...)
+func (c *SubjectRequest) AddSubIDFormats(v ...SubjectIDFormat) *SubjectRequest {
+	c.subIDFormats = append(c.subIDFormats, v...)
 	return c
 }
 
-func (c *SubjectRequest) SubIDs() []string {
-	return c.subIDs
+func (c *SubjectRequest) SubIDFormats() []SubjectIDFormat {
+	return c.subIDFormats
 }
 
 func (c SubjectRequest) MarshalJSON() ([]byte, error) {
@@ -111,8 +112,8 @@ func (c SubjectRequest) MarshalJSON() ([]byte, error) {
 }
 
 func (c *SubjectRequest) UnmarshalJSON(data []byte) error {
-	c.assertions = nil
-	c.subIDs = nil
+	c.assertionFormats = nil
+	c.subIDFormats = nil
 	dec := json.NewDecoder(bytes.NewReader(data))
 	tok, err := dec.Token()
 	if err != nil {
@@ -140,13 +141,13 @@ LOOP:
 			return errors.Errorf(`unexpected delimiter '%c'`, tok)
 		case string:
 			switch tok {
-			case "assertions":
-				if err := dec.Decode(&(c.assertions)); err != nil {
-					return errors.Wrap(err, `error reading assertions`)
+			case "assertion_formats":
+				if err := dec.Decode(&(c.assertionFormats)); err != nil {
+					return errors.Wrap(err, `error reading assertion_formats`)
 				}
-			case "sub_i_ds":
-				if err := dec.Decode(&(c.subIDs)); err != nil {
-					return errors.Wrap(err, `error reading sub_i_ds`)
+			case "sub_id_formats":
+				if err := dec.Decode(&(c.subIDFormats)); err != nil {
+					return errors.Wrap(err, `error reading sub_id_formats`)
 				}
 			default:
 				var tmp interface{}
@@ -165,11 +166,11 @@ LOOP:
 
 func (c *SubjectRequest) makePairs() []*mapiter.Pair {
 	var pairs []*mapiter.Pair
-	if tmp := c.assertions; len(tmp) > 0 {
-		pairs = append(pairs, &mapiter.Pair{Key: "assertions", Value: tmp})
+	if tmp := c.assertionFormats; len(tmp) > 0 {
+		pairs = append(pairs, &mapiter.Pair{Key: "assertion_formats", Value: tmp})
 	}
-	if tmp := c.subIDs; len(tmp) > 0 {
-		pairs = append(pairs, &mapiter.Pair{Key: "sub_i_ds", Value: tmp})
+	if tmp := c.subIDFormats; len(tmp) > 0 {
+		pairs = append(pairs, &mapiter.Pair{Key: "sub_id_formats", Value: tmp})
 	}
 	var extraKeys []string
 	for k := range c.extraFields {
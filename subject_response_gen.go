@@ -0,0 +1,237 @@
+package gnap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/lestrrat-go/iter/mapiter"
+	"github.com/pkg/errors"
+)
+
+// SubjectResponse holds the subject information returned by the authorization server
+type SubjectResponse struct {
+	assertions  []Assertion
+	subIDs      []SubjectIdentifier
+	updatedAt   *string
+	extraFields map[string]interface{}
+}
+
+func NewSubjectResponse() *SubjectResponse {
+	return &SubjectResponse{}
+}
+
+func (c *SubjectResponse) Validate() error {
+	return nil
+}
+
+func (c *SubjectResponse) Get(key string) (interface{}, bool) {
+	switch key {
+	case "assertions":
+		if len(c.assertions) == 0 {
+			return nil, false
+		}
+		return c.assertions, true
+	case "sub_ids":
+		if len(c.subIDs) == 0 {
+			return nil, false
+		}
+		return c.subIDs, true
+	case "updated_at":
+		if c.updatedAt == nil {
+			return nil, false
+		}
+		return c.updatedAt, true
+	default:
+		if c.extraFields == nil {
+			return nil, false
+		}
+		v, ok := c.extraFields[key]
+		return v, ok
+	}
+}
+
+func (c *SubjectResponse) Set(key string, value interface{}) error {
+	switch key {
+	case "assertions":
+		if v, ok := value.([]Assertion); ok {
+			c.assertions = v
+		} else {
+			return errors.Errorf(`invalid type for "assertions" (%T)`, value)
+		}
+	case "sub_ids":
+		if v, ok := value.([]SubjectIdentifier); ok {
+			c.subIDs = v
+		} else {
+			return errors.Errorf(`invalid type for "sub_ids" (%T)`, value)
+		}
+	case "updated_at":
+		if v, ok := value.(string); ok {
+			c.updatedAt = &v
+		} else if value == nil {
+			c.updatedAt = nil
+		} else {
+			return errors.Errorf(`invalid type for "updated_at" (%T)`, value)
+		}
+	default:
+		if c.extraFields == nil {
+			c.extraFields = make(map[string]interface{})
+		}
+		c.extraFields[key] = value
+	}
+	return nil
+}
+
+func (c *SubjectResponse) AddAssertions(v ...Assertion) *SubjectResponse {
+	c.assertions = append(c.assertions, v...)
+	return c
+}
+
+func (c *SubjectResponse) Assertions() []Assertion {
+	return c.assertions
+}
+
+func (c *SubjectResponse) AddSubIDs(v ...SubjectIdentifier) *SubjectResponse {
+	c.subIDs = append(c.subIDs, v...)
+	return c
+}
+
+func (c *SubjectResponse) SubIDs() []SubjectIdentifier {
+	return c.subIDs
+}
+
+func (c *SubjectResponse) SetUpdatedAt(v string) {
+	c.updatedAt = &v
+}
+
+func (c *SubjectResponse) UpdatedAt() string {
+	if c.updatedAt == nil {
+		return ""
+	}
+	return *(c.updatedAt)
+}
+
+func (c SubjectResponse) MarshalJSON() ([]byte, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	buf.WriteByte('{')
+	var i int
+	for iter := c.Iterate(ctx); iter.Next(ctx); {
+		pair := iter.Pair()
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		i++
+		buf.WriteString(strconv.Quote(pair.Key.(string)))
+		buf.WriteByte(':')
+		if err := enc.Encode(pair.Value); err != nil {
+			return nil, errors.Wrapf(err, `failed to encode %s`, pair.Key.(string))
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (c *SubjectResponse) UnmarshalJSON(data []byte) error {
+	c.assertions = nil
+	c.subIDs = nil
+	c.updatedAt = nil
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return errors.Wrap(err, `error reading token`)
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok != '{' {
+			return errors.Errorf(`expected '{', but got '%c'`, tok)
+		}
+	default:
+		return errors.Errorf(`expected '{', but got '%c'`, tok)
+	}
+LOOP:
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return errors.Wrap(err, `error reading token`)
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			if tok == '}' { // End of object
+				break LOOP
+			}
+			return errors.Errorf(`unexpected delimiter '%c'`, tok)
+		case string:
+			switch tok {
+			case "assertions":
+				if err := dec.Decode(&(c.assertions)); err != nil {
+					return errors.Wrap(err, `error reading assertions`)
+				}
+			case "sub_ids":
+				if err := dec.Decode(&(c.subIDs)); err != nil {
+					return errors.Wrap(err, `error reading sub_ids`)
+				}
+			case "updated_at":
+				var tmp string
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrap(err, `error reading updated_at`)
+				}
+				c.updatedAt = &tmp
+			default:
+				var tmp interface{}
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrapf(err, `error reading %s`, tok)
+				}
+				if c.extraFields == nil {
+					c.extraFields = map[string]interface{}{}
+				}
+				c.extraFields[tok] = tmp
+			}
+		}
+	}
+	return nil
+}
+
+func (c *SubjectResponse) makePairs() []*mapiter.Pair {
+	var pairs []*mapiter.Pair
+	if tmp := c.assertions; len(tmp) > 0 {
+		pairs = append(pairs, &mapiter.Pair{Key: "assertions", Value: tmp})
+	}
+	if tmp := c.subIDs; len(tmp) > 0 {
+		pairs = append(pairs, &mapiter.Pair{Key: "sub_ids", Value: tmp})
+	}
+	if tmp := c.updatedAt; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "updated_at", Value: *tmp})
+	}
+	var extraKeys []string
+	for k := range c.extraFields {
+		extraKeys = append(extraKeys, k)
+	}
+	for _, k := range extraKeys {
+		pairs = append(pairs, &mapiter.Pair{Key: k, Value: c.extraFields[k]})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.(string) < pairs[j].Key.(string)
+	})
+	return pairs
+}
+
+func (c *SubjectResponse) Iterate(ctx context.Context) mapiter.Iterator {
+	pairs := c.makePairs()
+	ch := make(chan *mapiter.Pair, len(pairs))
+	go func(ctx context.Context, ch chan *mapiter.Pair, pairs []*mapiter.Pair) {
+		defer close(ch)
+		for _, pair := range pairs {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- pair:
+			}
+		}
+	}(ctx, ch, pairs)
+	return mapiter.New(ch)
+}
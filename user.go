@@ -0,0 +1,15 @@
+package gnap
+
+// NewUserReference creates a User that refers to a user known to the
+// authorization server. It is encoded as a plain string
+func NewUserReference(ref string) *User {
+	var user User
+	user.SetRef(ref)
+	return &user
+}
+
+// IsReference returns true if the user is only described by a
+// reference
+func (c *User) IsReference() bool {
+	return c.Ref() != "" && len(c.SubIDs()) == 0 && len(c.Assertions()) == 0
+}
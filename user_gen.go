@@ -0,0 +1,246 @@
+package gnap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/lestrrat-go/iter/mapiter"
+	"github.com/pkg/errors"
+)
+
+// User describes the resource owner, as known to the client
+type User struct {
+	assertions  []Assertion
+	ref         *string
+	subIDs      []SubjectIdentifier
+	extraFields map[string]interface{}
+}
+
+func NewUser() *User {
+	return &User{}
+}
+
+func (c *User) Validate() error {
+	if c.ref != nil && len(c.makePairs()) > 1 {
+		return errors.Errorf(`field "ref" must not be combined with other fields`)
+	}
+	return nil
+}
+
+func (c *User) Get(key string) (interface{}, bool) {
+	switch key {
+	case "assertions":
+		if len(c.assertions) == 0 {
+			return nil, false
+		}
+		return c.assertions, true
+	case "ref":
+		if c.ref == nil {
+			return nil, false
+		}
+		return c.ref, true
+	case "sub_ids":
+		if len(c.subIDs) == 0 {
+			return nil, false
+		}
+		return c.subIDs, true
+	default:
+		if c.extraFields == nil {
+			return nil, false
+		}
+		v, ok := c.extraFields[key]
+		return v, ok
+	}
+}
+
+func (c *User) Set(key string, value interface{}) error {
+	switch key {
+	case "assertions":
+		if v, ok := value.([]Assertion); ok {
+			c.assertions = v
+		} else {
+			return errors.Errorf(`invalid type for "assertions" (%T)`, value)
+		}
+	case "ref":
+		if v, ok := value.(string); ok {
+			c.ref = &v
+		} else if value == nil {
+			c.ref = nil
+		} else {
+			return errors.Errorf(`invalid type for "ref" (%T)`, value)
+		}
+	case "sub_ids":
+		if v, ok := value.([]SubjectIdentifier); ok {
+			c.subIDs = v
+		} else {
+			return errors.Errorf(`invalid type for "sub_ids" (%T)`, value)
+		}
+	default:
+		if c.extraFields == nil {
+			c.extraFields = make(map[string]interface{})
+		}
+		c.extraFields[key] = value
+	}
+	return nil
+}
+
+func (c *User) AddAssertions(v ...Assertion) *User {
+	c.assertions = append(c.assertions, v...)
+	return c
+}
+
+func (c *User) Assertions() []Assertion {
+	return c.assertions
+}
+
+func (c *User) SetRef(v string) {
+	c.ref = &v
+}
+
+func (c *User) Ref() string {
+	if c.ref == nil {
+		return ""
+	}
+	return *(c.ref)
+}
+
+func (c *User) AddSubIDs(v ...SubjectIdentifier) *User {
+	c.subIDs = append(c.subIDs, v...)
+	return c
+}
+
+func (c *User) SubIDs() []SubjectIdentifier {
+	return c.subIDs
+}
+
+func (c User) MarshalJSON() ([]byte, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	buf.WriteByte('{')
+	var pairs []*mapiter.Pair
+	for iter := c.Iterate(ctx); iter.Next(ctx); {
+		pairs = append(pairs, iter.Pair())
+	}
+	if len(pairs) == 1 && pairs[0].Key.(string) == "ref" {
+		return []byte(strconv.Quote(pairs[0].Value.(string))), nil
+	}
+	if c.ref != nil {
+		return nil, errors.Errorf(`field "ref" must not be combined with other fields`)
+	}
+	for i, pair := range pairs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(strconv.Quote(pair.Key.(string)))
+		buf.WriteByte(':')
+		if err := enc.Encode(pair.Value); err != nil {
+			return nil, errors.Wrapf(err, `failed to encode %s`, pair.Key.(string))
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (c *User) UnmarshalJSON(data []byte) error {
+	c.assertions = nil
+	c.ref = nil
+	c.subIDs = nil
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return errors.Wrap(err, `error reading token`)
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok != '{' {
+			return errors.Errorf(`expected '{', but got '%c'`, tok)
+		}
+	case string:
+		c.ref = &tok
+		return nil
+	default:
+		return errors.Errorf(`expected '{' or string, but got '%c'`, tok)
+	}
+LOOP:
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return errors.Wrap(err, `error reading token`)
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			if tok == '}' { // End of object
+				break LOOP
+			}
+			return errors.Errorf(`unexpected delimiter '%c'`, tok)
+		case string:
+			switch tok {
+			case "assertions":
+				if err := dec.Decode(&(c.assertions)); err != nil {
+					return errors.Wrap(err, `error reading assertions`)
+				}
+			case "ref":
+				return errors.Errorf(`field "ref" is only allowed in the string form`)
+			case "sub_ids":
+				if err := dec.Decode(&(c.subIDs)); err != nil {
+					return errors.Wrap(err, `error reading sub_ids`)
+				}
+			default:
+				var tmp interface{}
+				if err := dec.Decode(&tmp); err != nil {
+					return errors.Wrapf(err, `error reading %s`, tok)
+				}
+				if c.extraFields == nil {
+					c.extraFields = map[string]interface{}{}
+				}
+				c.extraFields[tok] = tmp
+			}
+		}
+	}
+	return nil
+}
+
+func (c *User) makePairs() []*mapiter.Pair {
+	var pairs []*mapiter.Pair
+	if tmp := c.assertions; len(tmp) > 0 {
+		pairs = append(pairs, &mapiter.Pair{Key: "assertions", Value: tmp})
+	}
+	if tmp := c.ref; tmp != nil {
+		pairs = append(pairs, &mapiter.Pair{Key: "ref", Value: *tmp})
+	}
+	if tmp := c.subIDs; len(tmp) > 0 {
+		pairs = append(pairs, &mapiter.Pair{Key: "sub_ids", Value: tmp})
+	}
+	var extraKeys []string
+	for k := range c.extraFields {
+		extraKeys = append(extraKeys, k)
+	}
+	for _, k := range extraKeys {
+		pairs = append(pairs, &mapiter.Pair{Key: k, Value: c.extraFields[k]})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.(string) < pairs[j].Key.(string)
+	})
+	return pairs
+}
+
+func (c *User) Iterate(ctx context.Context) mapiter.Iterator {
+	pairs := c.makePairs()
+	ch := make(chan *mapiter.Pair, len(pairs))
+	go func(ctx context.Context, ch chan *mapiter.Pair, pairs []*mapiter.Pair) {
+		defer close(ch)
+		for _, pair := range pairs {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- pair:
+			}
+		}
+	}(ctx, ch, pairs)
+	return mapiter.New(ch)
+}